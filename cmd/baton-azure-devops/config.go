@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/spf13/viper"
 )
@@ -9,32 +11,102 @@ var (
 	bearerTokenField = field.StringField(
 		"personal-access-token",
 		field.WithDescription("The bearer token used to authenticate the request for Azure Dev Ops"),
-		field.WithRequired(true),
+		field.WithIsSecret(true),
+	)
+	tenantIDField = field.StringField(
+		"entra-tenant-id",
+		field.WithDescription("The Microsoft Entra tenant ID of the service principal used to authenticate instead of a personal access token"),
+	)
+	clientIDField = field.StringField(
+		"entra-client-id",
+		field.WithDescription("The application (client) ID of the service principal used to authenticate instead of a personal access token"),
+	)
+	clientSecretField = field.StringField(
+		"entra-client-secret",
+		field.WithDescription("The client secret of the service principal"),
+		field.WithIsSecret(true),
+	)
+	clientCertificatePathField = field.StringField(
+		"entra-client-certificate-path",
+		field.WithDescription("Path to a PEM file holding the certificate of the service principal and its unencrypted private key"),
+	)
+	federatedTokenFileField = field.StringField(
+		"entra-federated-token-file",
+		field.WithDescription("Path to a token of another identity provider trusted by a federated credential of the service principal, such as a projected Kubernetes service account token. The file is read again when the token rotates"),
 	)
 	organizationUrlField = field.StringField(
 		"organization-url",
-		field.WithDescription("The organization url used to sync data for Azure Dev Ops"),
-		field.WithRequired(true),
+		field.WithDescription("The organization url used to sync data for Azure Dev Ops, or the collection url of an Azure DevOps Server"),
+	)
+	organizationUrlsField = field.StringSliceField(
+		"organization-urls",
+		field.WithDescription("The urls of several organizations to sync data for Azure Dev Ops from a single connector (e.g. https://dev.azure.com/contoso,https://dev.azure.com/fabrikam)"),
+	)
+	discoverOrganizationsField = field.BoolField(
+		"discover-organizations",
+		field.WithDefaultValue(false),
+		field.WithDescription("Sync every Azure DevOps Services organization the authenticated identity is a member of, discovered through the accounts API, instead of the configured organization urls."),
+	)
+	serverField = field.BoolField(
+		"azure-devops-server",
+		field.WithDefaultValue(false),
+		field.WithDescription("Connect to an Azure DevOps Server (on-premises) collection, e.g. https://tfs.example.com/tfs/DefaultCollection, instead of an Azure DevOps Services organization. Users are synced through the graph API and access levels are not synced or provisioned."),
 	)
 	syncGrantSourcesField = field.BoolField(
 		"sync-grant-sources",
 		field.WithDefaultValue(false),
 		field.WithDescription("Sync grant sources. If this is not set, grant sources will not be synced."),
 	)
+	syncPermissionActionsField = field.BoolField(
+		"sync-permission-actions",
+		field.WithDefaultValue(false),
+		field.WithDescription("Sync one entitlement per security namespace action (e.g. force push, manage permissions) instead of only read and write permissions."),
+	)
+	securityNamespacesField = field.StringSliceField(
+		"security-namespaces",
+		field.WithDescription("Security namespaces, by name or ID, to sync at the project level (e.g. Project, Git Repositories, CSS, Library). Defaults to Project, Tagging, VersionControlItems, AnalyticsViews, Build, Git Repositories, MetaTask and ReleaseManagement."),
+	)
+	excludedSecurityNamespacesField = field.StringSliceField(
+		"excluded-security-namespaces",
+		field.WithDescription("Security namespaces, by name or ID, to skip at the project level (e.g. Tagging)."),
+	)
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
 	ConfigurationFields = []field.SchemaField{
 		bearerTokenField,
+		tenantIDField,
+		clientIDField,
+		clientSecretField,
+		clientCertificatePathField,
+		federatedTokenFileField,
 		organizationUrlField,
+		organizationUrlsField,
+		discoverOrganizationsField,
+		serverField,
 		syncGrantSourcesField,
+		syncPermissionActionsField,
+		securityNamespacesField,
+		excludedSecurityNamespacesField,
 	}
 
 	// FieldRelationships defines relationships between the fields listed in
 	// ConfigurationFields that can be automatically validated. For example, a
 	// username and password can be required together, or an access token can be
 	// marked as mutually exclusive from the username password pair.
-	FieldRelationships = []field.SchemaFieldRelationship{}
+	FieldRelationships = []field.SchemaFieldRelationship{
+		field.FieldsAtLeastOneUsed(bearerTokenField, clientIDField),
+		field.FieldsMutuallyExclusive(bearerTokenField, clientIDField),
+		field.FieldsMutuallyExclusive(bearerTokenField, tenantIDField),
+		field.FieldsMutuallyExclusive(clientSecretField, clientCertificatePathField, federatedTokenFileField),
+		field.FieldsRequiredTogether(tenantIDField, clientIDField),
+		field.FieldsDependentOn(
+			[]field.SchemaField{clientSecretField, clientCertificatePathField, federatedTokenFileField},
+			[]field.SchemaField{tenantIDField, clientIDField},
+		),
+		field.FieldsAtLeastOneUsed(organizationUrlField, organizationUrlsField, discoverOrganizationsField),
+		field.FieldsMutuallyExclusive(organizationUrlField, organizationUrlsField, discoverOrganizationsField),
+	}
 )
 
 // ValidateConfig is run after the configuration is loaded, and should return an
@@ -42,5 +114,20 @@ var (
 // needs to perform extra validations that cannot be encoded with configuration
 // parameters.
 func ValidateConfig(v *viper.Viper) error {
+	if v.GetString(clientIDField.FieldName) != "" &&
+		v.GetString(clientSecretField.FieldName) == "" &&
+		v.GetString(clientCertificatePathField.FieldName) == "" &&
+		v.GetString(federatedTokenFileField.FieldName) == "" {
+		return errors.New("one of entra-client-secret, entra-client-certificate-path or entra-federated-token-file is required to authenticate as a service principal")
+	}
+
+	if v.GetBool(serverField.FieldName) && v.GetString(clientIDField.FieldName) != "" {
+		return errors.New("azure devops server only supports personal access tokens")
+	}
+
+	if v.GetBool(serverField.FieldName) && v.GetBool(discoverOrganizationsField.FieldName) {
+		return errors.New("organizations can only be discovered on azure devops services, configure the collection urls of an azure devops server instead")
+	}
+
 	return nil
 }
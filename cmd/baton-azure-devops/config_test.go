@@ -14,7 +14,154 @@ func TestConfigs(t *testing.T) {
 	)
 
 	testCases := []test.TestCase{
-		// Add test cases here.
+		{
+			Configs: map[string]string{
+				"organization-url":      "https://dev.azure.com/org",
+				"personal-access-token": "token",
+			},
+			IsValid: true,
+			Message: "personal access token",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":    "https://dev.azure.com/org",
+				"entra-tenant-id":     "tenant",
+				"entra-client-id":     "client",
+				"entra-client-secret": "secret",
+			},
+			IsValid: true,
+			Message: "service principal with a client secret",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":              "https://dev.azure.com/org",
+				"entra-tenant-id":               "tenant",
+				"entra-client-id":               "client",
+				"entra-client-certificate-path": "/etc/baton/certificate.pem",
+			},
+			IsValid: true,
+			Message: "service principal with a certificate",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":           "https://dev.azure.com/org",
+				"entra-tenant-id":            "tenant",
+				"entra-client-id":            "client",
+				"entra-federated-token-file": "/var/run/secrets/azure/tokens/azure-identity-token",
+			},
+			IsValid: true,
+			Message: "workload identity federation",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":           "https://dev.azure.com/org",
+				"entra-tenant-id":            "tenant",
+				"entra-client-id":            "client",
+				"entra-client-secret":        "secret",
+				"entra-federated-token-file": "/var/run/secrets/azure/tokens/azure-identity-token",
+			},
+			IsValid: false,
+			Message: "client secret and federated token file",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":      "https://tfs.example.com/tfs/DefaultCollection",
+				"personal-access-token": "token",
+				"azure-devops-server":   "true",
+			},
+			IsValid: true,
+			Message: "azure devops server",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":    "https://tfs.example.com/tfs/DefaultCollection",
+				"azure-devops-server": "true",
+				"entra-tenant-id":     "tenant",
+				"entra-client-id":     "client",
+				"entra-client-secret": "secret",
+			},
+			IsValid: false,
+			Message: "azure devops server with a service principal",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url": "https://dev.azure.com/org",
+			},
+			IsValid: false,
+			Message: "missing credentials",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":      "https://dev.azure.com/org",
+				"personal-access-token": "token",
+				"entra-tenant-id":       "tenant",
+				"entra-client-id":       "client",
+				"entra-client-secret":   "secret",
+			},
+			IsValid: false,
+			Message: "personal access token and service principal",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":    "https://dev.azure.com/org",
+				"entra-client-id":     "client",
+				"entra-client-secret": "secret",
+			},
+			IsValid: false,
+			Message: "service principal without a tenant",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url": "https://dev.azure.com/org",
+				"entra-tenant-id":  "tenant",
+				"entra-client-id":  "client",
+			},
+			IsValid: false,
+			Message: "service principal without a secret or certificate",
+		},
+		{
+			Configs: map[string]string{
+				"organization-urls":     "https://dev.azure.com/contoso,https://dev.azure.com/fabrikam",
+				"personal-access-token": "token",
+			},
+			IsValid: true,
+			Message: "several organizations",
+		},
+		{
+			Configs: map[string]string{
+				"discover-organizations": "true",
+				"entra-tenant-id":        "tenant",
+				"entra-client-id":        "client",
+				"entra-client-secret":    "secret",
+			},
+			IsValid: true,
+			Message: "discovered organizations",
+		},
+		{
+			Configs: map[string]string{
+				"personal-access-token": "token",
+			},
+			IsValid: false,
+			Message: "missing organization url",
+		},
+		{
+			Configs: map[string]string{
+				"organization-url":       "https://dev.azure.com/contoso",
+				"discover-organizations": "true",
+				"personal-access-token":  "token",
+			},
+			IsValid: false,
+			Message: "organization url and discovered organizations",
+		},
+		{
+			Configs: map[string]string{
+				"discover-organizations": "true",
+				"azure-devops-server":    "true",
+				"personal-access-token":  "token",
+			},
+			IsValid: false,
+			Message: "discovered organizations of an azure devops server",
+		},
 	}
 
 	test.ExerciseTestCases(t, configurationSchema, ValidateConfig, testCases)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/conductorone/baton-azure-devops/pkg/client"
 	connectorSchema "github.com/conductorone/baton-azure-devops/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
@@ -25,7 +26,8 @@ func main() {
 		"baton-azure-devops",
 		getConnector,
 		field.Configuration{
-			Fields: ConfigurationFields,
+			Fields:      ConfigurationFields,
+			Constraints: FieldRelationships,
 		},
 	)
 	if err != nil {
@@ -45,15 +47,38 @@ func main() {
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	personalAccessToken := v.GetString(bearerTokenField.FieldName)
-	organizationUrl := v.GetString(organizationUrlField.FieldName)
+	server := v.GetBool(serverField.FieldName)
 	syncGrantSources := v.GetBool(syncGrantSourcesField.FieldName)
+	syncPermissionActions := v.GetBool(syncPermissionActionsField.FieldName)
+	securityNamespaces := v.GetStringSlice(securityNamespacesField.FieldName)
+	excludedSecurityNamespaces := v.GetStringSlice(excludedSecurityNamespacesField.FieldName)
 
 	if err := ValidateConfig(v); err != nil {
 		return nil, err
 	}
 
-	connectorBuilder, err := connectorSchema.New(ctx, personalAccessToken, organizationUrl, syncGrantSources)
+	credentials, err := getCredentials(v)
+	if err != nil {
+		l.Error("error reading credentials", zap.Error(err))
+		return nil, err
+	}
+
+	organizationUrls, err := getOrganizationUrls(ctx, v, credentials)
+	if err != nil {
+		l.Error("error reading organization urls", zap.Error(err))
+		return nil, err
+	}
+
+	connectorBuilder, err := connectorSchema.New(
+		ctx,
+		credentials,
+		organizationUrls,
+		server,
+		syncGrantSources,
+		syncPermissionActions,
+		securityNamespaces,
+		excludedSecurityNamespaces,
+	)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -68,3 +93,41 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 	}
 	return connector, nil
 }
+
+// getCredentials returns the credentials of the service principal when a client id is configured, and the personal
+// access token otherwise.
+func getCredentials(v *viper.Viper) (client.Credentials, error) {
+	tenantID := v.GetString(tenantIDField.FieldName)
+	clientID := v.GetString(clientIDField.FieldName)
+	if clientID == "" {
+		return client.NewPersonalAccessTokenCredentials(v.GetString(bearerTokenField.FieldName)), nil
+	}
+
+	if certificatePath := v.GetString(clientCertificatePathField.FieldName); certificatePath != "" {
+		certificate, err := os.ReadFile(certificatePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading client certificate: %w", err)
+		}
+		return client.NewClientCertificateCredentials(tenantID, clientID, certificate)
+	}
+
+	if tokenFilePath := v.GetString(federatedTokenFileField.FieldName); tokenFilePath != "" {
+		return client.NewWorkloadIdentityCredentials(tenantID, clientID, tokenFilePath)
+	}
+
+	return client.NewClientSecretCredentials(tenantID, clientID, v.GetString(clientSecretField.FieldName))
+}
+
+// getOrganizationUrls returns the configured organization urls, or the urls of the organizations the authenticated
+// identity is a member of when organizations are discovered.
+func getOrganizationUrls(ctx context.Context, v *viper.Viper, credentials client.Credentials) ([]string, error) {
+	if v.GetBool(discoverOrganizationsField.FieldName) {
+		return client.DiscoverOrganizations(ctx, credentials)
+	}
+
+	if organizationUrl := v.GetString(organizationUrlField.FieldName); organizationUrl != "" {
+		return []string{organizationUrl}, nil
+	}
+
+	return v.GetStringSlice(organizationUrlsField.FieldName), nil
+}
@@ -3,10 +3,18 @@ package client
 import (
 	"context"
 
+	"github.com/conductorone/baton-azure-devops/pkg/client/securityroles"
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
 	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
 )
 
 // AzureDevOpsClient defines the interface for interacting with Azure DevOps services.
@@ -14,6 +22,28 @@ type AzureDevOpsClientInterface interface {
 	GetDescriptor(ctx context.Context, resource uuid.UUID) (string, error)
 	CreateMembership(ctx context.Context, teamDescriptor, principalDescriptor string) (*graph.GraphMembership, error)
 	RevokeMembership(ctx context.Context, teamDescriptor, principalDescriptor string) error
-	ListTeams(ctx context.Context) ([]core.WebApiTeam, error)
-	ListTeamMembers(ctx context.Context, projectId, teamId string) ([]webapi.TeamMember, error)
+	GetMembership(ctx context.Context, containerDescriptor, memberDescriptor string) (*graph.GraphMembership, error)
+	ListTeams(ctx context.Context, nextPageToken string) ([]core.WebApiTeam, string, error)
+	ListProjectTeams(ctx context.Context, projectId, nextPageToken string) ([]core.WebApiTeam, string, error)
+	ListTeamMembers(ctx context.Context, projectId, teamId, nextPageToken string) ([]webapi.TeamMember, string, error)
+	GetIdentityDescriptor(ctx context.Context, subjectDescriptor string) (string, error)
+	GetAccessControlEntry(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string) (*security.AccessControlEntry, error)
+	SetAccessControlEntry(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string, allow, deny int) error
+	RemovePermission(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string, permissions int) error
+	GetStorageKey(ctx context.Context, descriptor string) (uuid.UUID, error)
+	ListRoleAssignments(ctx context.Context, scopeID, resourceID string) ([]securityroles.RoleAssignment, error)
+	SetRoleAssignment(ctx context.Context, scopeID, resourceID, identityID, roleName string) error
+	RemoveRoleAssignment(ctx context.Context, scopeID, resourceID, identityID string) error
+	ListTeamIDs(ctx context.Context) (map[string]bool, error)
+	ReadIdentitiesByDescriptors(ctx context.Context, descriptors []string) ([]identity.Identity, error)
+	ReadIdentitiesByIDs(ctx context.Context, identityIDs []string) ([]identity.Identity, error)
+	GetPipelinePermissions(ctx context.Context, projectID, resourceType, resourceID string) (*pipelinepermissions.ResourcePipelinePermissions, error)
+	ListIdentities(ctx context.Context, identityIDs string, descriptors string) ([]identity.Identity, error)
+	GetRootClassificationNodeID(ctx context.Context, projectID string, structureGroup workitemtracking.TreeStructureGroup) (uuid.UUID, error)
+	GetBuildDefinition(ctx context.Context, projectID string, definitionID int) (*build.BuildDefinitionReference, error)
+	ListAccessLevels(ctx context.Context) ([]licensing.AccessLevel, error)
+	ListUsersByAccessLevel(ctx context.Context, licenseID, nextContinuationToken string) ([]userentitlement.UserEntitlement, string, error)
+	GetUserEntitlement(ctx context.Context, userID uuid.UUID) (*userentitlement.UserEntitlement, error)
+	UpdateUserEntitlement(ctx context.Context, userID uuid.UUID, document []webapi.JsonPatchOperation) (*userentitlement.UserEntitlement, error)
+	UpdatePipelinePermissions(ctx context.Context, projectID, resourceType, resourceID string, permissions *pipelinepermissions.ResourcePipelinePermissions) error
 }
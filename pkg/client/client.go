@@ -7,92 +7,239 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/conductorone/baton-azure-devops/pkg/client/securefiles"
+	"github.com/conductorone/baton-azure-devops/pkg/client/securityroles"
 	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensingrule"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/serviceendpoint"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
 	"go.uber.org/zap"
 )
 
 type AzureDevOpsClient struct {
-	SyncGrantSources      bool
-	coreClient            core.Client
-	graphClient           graph.Client
-	securityClient        security.Client
-	identityClient        identity.Client
-	userEntitlementClient userentitlement.Client
-	gitClient             git.Client
+	// Server is set when the client is connected to an Azure DevOps Server (on-premises) collection.
+	Server                    bool
+	SyncGrantSources          bool
+	SyncPermissionActions     bool
+	coreClient                core.Client
+	graphClient               graph.Client
+	securityClient            security.Client
+	identityClient            identity.Client
+	userEntitlementClient     userentitlement.Client
+	gitClient                 git.Client
+	workItemClient            workitemtracking.Client
+	buildClient               build.Client
+	serviceEndpointClient     serviceendpoint.Client
+	securityRolesClient       securityroles.Client
+	taskAgentClient           taskagent.Client
+	secureFilesClient         securefiles.Client
+	pipelinePermissionsClient pipelinepermissions.Client
+	rateLimit                 *rateLimitTransport
 }
 
-func New(ctx context.Context, personalAccessToken, organization string, syncGrantSources bool) (*AzureDevOpsClient, error) {
+func New(ctx context.Context, credentials Credentials, organization string, server, syncGrantSources, syncPermissionActions bool) (*AzureDevOpsClient, error) {
 	l := ctxzap.Extract(ctx)
-	connection := azuredevops.NewPatConnection(organization, personalAccessToken)
+	organizationUrl, err := parseOrganizationUrl(organization)
+	if err != nil {
+		return nil, err
+	}
+	connection := newConnection(organizationUrl, credentials)
 
 	// Create a client to interact with the Core area
-	coreClient, err := core.NewClient(ctx, connection)
+	coreClient, err := connection.clientByResourceAreaId(ctx, core.ResourceAreaId)
 	if err != nil {
 		l.Error("baton-azure-devops: error creating core client", zap.Error(err))
-		return nil, fmt.Errorf("error creating core client: %w", err)
+		// The resource areas are the first request, so invalid or expired credentials are reported here.
+		if errors.Is(err, ErrUnauthorized) {
+			return nil, describeAPIError(err, "resource areas", "")
+		}
+		return nil, fmt.Errorf("error creating core client: %w", wrapError(err))
 	}
 
-	graphClient, err := graph.NewClient(ctx, connection)
+	graphClient, err := connection.clientByResourceAreaId(ctx, graph.ResourceAreaId)
 	if err != nil {
 		l.Error("baton-azure-devops: error creating graph client", zap.Error(err))
-		return nil, fmt.Errorf("error creating graph client: %w", err)
+		return nil, fmt.Errorf("error creating graph client: %w", wrapError(err))
 	}
 
-	securityClient := security.NewClient(ctx, connection)
+	securityClient := connection.clientByUrl(connection.BaseUrl)
 
-	identityClient, err := identity.NewClient(ctx, connection)
+	identityClient, err := connection.clientByResourceAreaId(ctx, identity.ResourceAreaId)
 	if err != nil {
 		l.Error("baton-azure-devops: error creating identity client", zap.Error(err))
-		return nil, fmt.Errorf("error creating identity client: %w", err)
+		return nil, fmt.Errorf("error creating identity client: %w", wrapError(err))
 	}
 
-	userEntitlementClient, err := userentitlement.NewClient(ctx, connection)
-	if err != nil {
-		l.Error("baton-azure-devops: error creating member entitlement management client", zap.Error(err))
-		return nil, fmt.Errorf("error creating member entitlement management client: %w", err)
+	// Azure DevOps Server has no member entitlement management API.
+	var userEntitlementClient userentitlement.Client
+	if !server {
+		userEntitlementBaseClient, err := connection.clientByResourceAreaId(ctx, userentitlement.ResourceAreaId)
+		if err != nil {
+			l.Error("baton-azure-devops: error creating member entitlement management client", zap.Error(err))
+			return nil, fmt.Errorf("error creating member entitlement management client: %w", wrapError(err))
+		}
+		userEntitlementClient = &userentitlement.ClientImpl{Client: *userEntitlementBaseClient}
 	}
 
-	gitClient, err := git.NewClient(ctx, connection)
+	gitClient, err := connection.clientByResourceAreaId(ctx, git.ResourceAreaId)
 	if err != nil {
 		l.Error("baton-azure-devops: error creating git client", zap.Error(err))
-		return nil, fmt.Errorf("error creating git client: %w", err)
+		return nil, fmt.Errorf("error creating git client: %w", wrapError(err))
+	}
+
+	workItemClient, err := connection.clientByResourceAreaId(ctx, workitemtracking.ResourceAreaId)
+	if err != nil {
+		l.Error("baton-azure-devops: error creating work item tracking client", zap.Error(err))
+		return nil, fmt.Errorf("error creating work item tracking client: %w", wrapError(err))
+	}
+
+	buildClient, err := connection.clientByResourceAreaId(ctx, build.ResourceAreaId)
+	if err != nil {
+		l.Error("baton-azure-devops: error creating build client", zap.Error(err))
+		return nil, fmt.Errorf("error creating build client: %w", wrapError(err))
+	}
+
+	serviceEndpointClient, err := connection.clientByResourceAreaId(ctx, serviceendpoint.ResourceAreaId)
+	if err != nil {
+		l.Error("baton-azure-devops: error creating service endpoint client", zap.Error(err))
+		return nil, fmt.Errorf("error creating service endpoint client: %w", wrapError(err))
+	}
+
+	securityRolesClient, err := securityroles.NewClient(ctx, connection.Connection, azuredevops.WithHTTPClient(connection.httpClient))
+	if err != nil {
+		l.Error("baton-azure-devops: error creating security roles client", zap.Error(err))
+		return nil, fmt.Errorf("error creating security roles client: %w", wrapError(err))
+	}
+
+	taskAgentClient, err := connection.clientByResourceAreaId(ctx, taskagent.ResourceAreaId)
+	if err != nil {
+		l.Error("baton-azure-devops: error creating task agent client", zap.Error(err))
+		return nil, fmt.Errorf("error creating task agent client: %w", wrapError(err))
+	}
+
+	secureFilesClient, err := securefiles.NewClient(ctx, connection.Connection, azuredevops.WithHTTPClient(connection.httpClient))
+	if err != nil {
+		l.Error("baton-azure-devops: error creating secure files client", zap.Error(err))
+		return nil, fmt.Errorf("error creating secure files client: %w", wrapError(err))
+	}
+
+	pipelinePermissionsClient, err := connection.clientByResourceAreaId(ctx, pipelinepermissions.ResourceAreaId)
+	if err != nil {
+		l.Error("baton-azure-devops: error creating pipeline permissions client", zap.Error(err))
+		return nil, fmt.Errorf("error creating pipeline permissions client: %w", wrapError(err))
 	}
 
 	client := AzureDevOpsClient{
-		coreClient:            coreClient,
-		graphClient:           graphClient,
-		securityClient:        securityClient,
-		identityClient:        identityClient,
-		userEntitlementClient: userEntitlementClient,
-		gitClient:             gitClient,
-		SyncGrantSources:      syncGrantSources,
+		coreClient:                &core.ClientImpl{Client: *coreClient},
+		graphClient:               &graph.ClientImpl{Client: *graphClient},
+		securityClient:            &security.ClientImpl{Client: *securityClient},
+		identityClient:            &identity.ClientImpl{Client: *identityClient},
+		userEntitlementClient:     userEntitlementClient,
+		gitClient:                 &git.ClientImpl{Client: *gitClient},
+		workItemClient:            &workitemtracking.ClientImpl{Client: *workItemClient},
+		buildClient:               &build.ClientImpl{Client: *buildClient},
+		serviceEndpointClient:     &serviceendpoint.ClientImpl{Client: *serviceEndpointClient},
+		securityRolesClient:       securityRolesClient,
+		taskAgentClient:           &taskagent.ClientImpl{Client: *taskAgentClient},
+		secureFilesClient:         secureFilesClient,
+		pipelinePermissionsClient: &pipelinepermissions.ClientImpl{Client: *pipelinePermissionsClient},
+		Server:                    server,
+		SyncGrantSources:          syncGrantSources,
+		SyncPermissionActions:     syncPermissionActions,
+		rateLimit:                 connection.rateLimit,
 	}
 
 	return &client, nil
 }
 
+// RateLimitDescription returns the rate limit state Azure DevOps reported on the last response, or nil when it
+// reported none.
+func (c *AzureDevOpsClient) RateLimitDescription() *v2.RateLimitDescription {
+	if c.rateLimit == nil {
+		return nil
+	}
+
+	return c.rateLimit.description()
+}
+
 func (c *AzureDevOpsClient) ListUsers(ctx context.Context, nextContinuationToken string) ([]userentitlement.UserEntitlement, string, error) {
+	if c.Server {
+		return c.listServerUsers(ctx, nextContinuationToken)
+	}
+
+	return c.searchUsers(ctx, nextContinuationToken, "")
+}
+
+// ListServicePrincipals returns the Microsoft Entra service principals that were added to the organization. They are
+// not returned by the member entitlement search of ListUsers. Azure DevOps Server has no service principals.
+func (c *AzureDevOpsClient) ListServicePrincipals(ctx context.Context, nextContinuationToken string) ([]graph.GraphServicePrincipal, string, error) {
 	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return nil, "", nil
+	}
+
+	args := graph.ListServicePrincipalsArgs{}
+	if nextContinuationToken != "" {
+		args.ContinuationToken = &nextContinuationToken
+	}
+
+	servicePrincipals, err := c.graphClient.ListServicePrincipals(ctx, args)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, "", wrapError(err)
+	}
+
 	nextPageToken := ""
+	if servicePrincipals.ContinuationToken != nil && len(*servicePrincipals.ContinuationToken) > 0 {
+		nextPageToken = (*servicePrincipals.ContinuationToken)[0]
+	}
+	if servicePrincipals.GraphServicePrincipals == nil {
+		return nil, nextPageToken, nil
+	}
+
+	return *servicePrincipals.GraphServicePrincipals, nextPageToken, nil
+}
+
+// ListUsersByAccessLevel returns the users holding the given access level, where the access level is
+// identified by its license id (e.g. Account-Express, Account-Stakeholder, Msdn-Eligible).
+func (c *AzureDevOpsClient) ListUsersByAccessLevel(ctx context.Context, licenseID, nextContinuationToken string) ([]userentitlement.UserEntitlement, string, error) {
+	filter := fmt.Sprintf("licenseId eq '%s'", licenseID)
+	return c.searchUsers(ctx, nextContinuationToken, filter)
+}
+
+func (c *AzureDevOpsClient) searchUsers(ctx context.Context, nextContinuationToken, filter string) ([]userentitlement.UserEntitlement, string, error) {
+	l := ctxzap.Extract(ctx)
+	nextPageToken := ""
+	if c.Server {
+		return nil, "", errServerUnsupported
+	}
 
 	userArgs := userentitlement.SearchUserEntitlementsArgs{}
 	if nextContinuationToken != "" {
 		userArgs.ContinuationToken = &nextContinuationToken
 	}
+	if filter != "" {
+		userArgs.Filter = &filter
+	}
 
 	users, err := c.userEntitlementClient.SearchUserEntitlements(ctx, userArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, "", err
+		return nil, "", wrapError(err)
 	}
 
 	if users.ContinuationToken != nil && *users.ContinuationToken != "" {
@@ -102,34 +249,283 @@ func (c *AzureDevOpsClient) ListUsers(ctx context.Context, nextContinuationToken
 	return *users.Members, nextPageToken, nil
 }
 
-func (c *AzureDevOpsClient) CreateUserAccount(ctx context.Context, ue *userentitlement.UserEntitlement) (*userentitlement.UserEntitlement, error) {
+// CreateUserAccount adds a user entitlement to the organization. The user may be added even though the operation
+// failed for some of the requested project entitlements, in which case the response is returned along with its
+// operation result so that the failed projects can be reported.
+func (c *AzureDevOpsClient) CreateUserAccount(ctx context.Context, ue *userentitlement.UserEntitlement) (*userentitlement.UserEntitlementsPostResponse, error) {
+	if c.Server {
+		return nil, errServerUnsupported
+	}
+
 	args := userentitlement.AddUserEntitlementArgs{
 		UserEntitlement: ue,
 	}
 	resp, err := c.userEntitlementClient.AddUserEntitlement(ctx, args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add user entitlement: %w", err)
+		return nil, fmt.Errorf("failed to add user entitlement: %w", wrapError(err))
+	}
+	// If the operation result exists and indicates failure without adding the user, handle the error
+	if resp.OperationResult != nil && resp.OperationResult.IsSuccess != nil && !*resp.OperationResult.IsSuccess &&
+		(resp.UserEntitlement == nil || resp.UserEntitlement.User == nil || resp.UserEntitlement.User.Descriptor == nil) {
+		return nil, fmt.Errorf("failed to add user entitlement: %w", operationErrors(resp.OperationResult.Errors))
 	}
-	// If the operation result exists and indicates failure, handle the error
-	if resp.OperationResult != nil && resp.OperationResult.IsSuccess != nil && !*resp.OperationResult.IsSuccess {
-		var errorMessages []string
-		if resp.OperationResult.Errors != nil {
-			for _, kv := range *resp.OperationResult.Errors {
-				if kv.Value != nil {
-					errorMessages = append(errorMessages, fmt.Sprintf("%v", *kv.Value))
+
+	return resp, nil
+}
+
+func (c *AzureDevOpsClient) GetUserEntitlement(ctx context.Context, userID uuid.UUID) (*userentitlement.UserEntitlement, error) {
+	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return nil, errServerUnsupported
+	}
+
+	userEntitlement, err := c.userEntitlementClient.GetUserEntitlement(ctx, userentitlement.GetUserEntitlementArgs{UserId: &userID})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting user entitlement: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return userEntitlement, nil
+}
+
+// UpdateUserEntitlement applies a JSON patch document (e.g. a replacement of /accessLevel) to the entitlement of a user.
+func (c *AzureDevOpsClient) UpdateUserEntitlement(ctx context.Context, userID uuid.UUID, document []webapi.JsonPatchOperation) (*userentitlement.UserEntitlement, error) {
+	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return nil, errServerUnsupported
+	}
+
+	resp, err := c.userEntitlementClient.UpdateUserEntitlement(ctx, userentitlement.UpdateUserEntitlementArgs{
+		UserId:   &userID,
+		Document: &document,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error updating user entitlement: %s", err))
+		return nil, fmt.Errorf("failed to update user entitlement: %w", wrapError(err))
+	}
+
+	if resp.IsSuccess != nil && !*resp.IsSuccess {
+		var errs []azuredevops.KeyValuePair
+		if resp.OperationResults != nil {
+			for _, result := range *resp.OperationResults {
+				if result.Errors != nil {
+					errs = append(errs, *result.Errors...)
 				}
 			}
 		}
-		if len(errorMessages) > 0 {
-			err := errors.New(strings.Join(errorMessages, "; "))
-			return nil, fmt.Errorf("failed to add user entitlement: %w", err)
-		}
-		return nil, fmt.Errorf("failed to add user entitlement: unknown reason")
+		return nil, fmt.Errorf("failed to update user entitlement: %w", operationErrors(&errs))
 	}
 
 	return resp.UserEntitlement, nil
 }
 
+// DeleteUserEntitlement removes a user from the organization, along with its access level and memberships.
+func (c *AzureDevOpsClient) DeleteUserEntitlement(ctx context.Context, userID uuid.UUID) error {
+	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return errServerUnsupported
+	}
+
+	err := c.userEntitlementClient.DeleteUserEntitlement(ctx, userentitlement.DeleteUserEntitlementArgs{UserId: &userID})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error deleting user entitlement: %s", err))
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// ListGroupEntitlements returns the group entitlements of the organization, which are the group rules that assign
+// licenses, extensions and project access to the members of a group.
+func (c *AzureDevOpsClient) ListGroupEntitlements(ctx context.Context) ([]userentitlement.GroupEntitlement, error) {
+	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return nil, errServerUnsupported
+	}
+
+	groupEntitlements, err := c.userEntitlementClient.GetGroupEntitlements(ctx, userentitlement.GetGroupEntitlementsArgs{})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting group entitlements: %s", err))
+		return nil, wrapError(err)
+	}
+	if groupEntitlements == nil {
+		return nil, nil
+	}
+
+	return *groupEntitlements, nil
+}
+
+// groupEntitlementMembersPageSize is the number of group entitlement members listed per page.
+const groupEntitlementMembersPageSize = 100
+
+// ListGroupEntitlementMembers returns a page of the direct members of the group of a group entitlement. The page token
+// is the paging token of the member entitlement management API.
+func (c *AzureDevOpsClient) ListGroupEntitlementMembers(ctx context.Context, groupID uuid.UUID, nextPageToken string) ([]userentitlement.UserEntitlement, string, error) {
+	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return nil, "", errServerUnsupported
+	}
+
+	maxResults := groupEntitlementMembersPageSize
+	args := userentitlement.GetGroupMembersArgs{GroupId: &groupID, MaxResults: &maxResults}
+	if nextPageToken != "" {
+		args.PagingToken = &nextPageToken
+	}
+
+	members, err := c.userEntitlementClient.GetGroupMembers(ctx, args)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting group entitlement members: %s", err))
+		return nil, "", wrapError(err)
+	}
+
+	var nextToken string
+	if members.ContinuationToken != nil {
+		nextToken = *members.ContinuationToken
+	}
+	if members.Members == nil {
+		return nil, nextToken, nil
+	}
+
+	return *members.Members, nextToken, nil
+}
+
+// AddGroupEntitlement creates the group entitlement of a group and applies its rules to the members of the group. It
+// returns the id of the group entitlement.
+func (c *AzureDevOpsClient) AddGroupEntitlement(ctx context.Context, groupEntitlement *userentitlement.GroupEntitlement) (uuid.UUID, error) {
+	if c.Server {
+		return uuid.Nil, errServerUnsupported
+	}
+
+	operation, err := c.userEntitlementClient.AddGroupEntitlement(ctx, userentitlement.AddGroupEntitlementArgs{
+		GroupEntitlement: groupEntitlement,
+		RuleOption:       &licensingrule.RuleOptionValues.ApplyGroupRule,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to add group entitlement: %w", wrapError(err))
+	}
+	if err := groupOperationErrors(operation); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to add group entitlement: %w", err)
+	}
+
+	if operation.Results != nil {
+		for _, result := range *operation.Results {
+			if result.GroupId != nil {
+				return *result.GroupId, nil
+			}
+			if result.Result != nil && result.Result.Id != nil {
+				return *result.Result.Id, nil
+			}
+		}
+	}
+
+	return uuid.Nil, nil
+}
+
+// UpdateGroupEntitlement applies a JSON patch document (e.g. a replacement of /licenseRule) to a group entitlement and
+// its rules to the members of the group.
+func (c *AzureDevOpsClient) UpdateGroupEntitlement(ctx context.Context, groupID uuid.UUID, document []webapi.JsonPatchOperation) error {
+	if c.Server {
+		return errServerUnsupported
+	}
+
+	operation, err := c.userEntitlementClient.UpdateGroupEntitlement(ctx, userentitlement.UpdateGroupEntitlementArgs{
+		GroupId:    &groupID,
+		Document:   &document,
+		RuleOption: &licensingrule.RuleOptionValues.ApplyGroupRule,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update group entitlement: %w", wrapError(err))
+	}
+	if err := groupOperationErrors(operation); err != nil {
+		return fmt.Errorf("failed to update group entitlement: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteGroupEntitlement removes a group entitlement from the organization, along with the licenses, extensions and
+// project access its members got through its rules.
+func (c *AzureDevOpsClient) DeleteGroupEntitlement(ctx context.Context, groupID uuid.UUID) error {
+	if c.Server {
+		return errServerUnsupported
+	}
+
+	operation, err := c.userEntitlementClient.DeleteGroupEntitlement(ctx, userentitlement.DeleteGroupEntitlementArgs{
+		GroupId:    &groupID,
+		RuleOption: &licensingrule.RuleOptionValues.ApplyGroupRule,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete group entitlement: %w", wrapError(err))
+	}
+	if err := groupOperationErrors(operation); err != nil {
+		return fmt.Errorf("failed to delete group entitlement: %w", err)
+	}
+
+	return nil
+}
+
+// groupOperationErrors returns an error out of the failed results of a group entitlement operation, which may still
+// be in progress.
+func groupOperationErrors(operation *userentitlement.GroupEntitlementOperationReference) error {
+	if operation == nil || operation.HaveResultsSucceeded == nil || *operation.HaveResultsSucceeded {
+		return nil
+	}
+
+	var errs []azuredevops.KeyValuePair
+	if operation.Results != nil {
+		for _, result := range *operation.Results {
+			if result.IsSuccess != nil && !*result.IsSuccess && result.Errors != nil {
+				errs = append(errs, *result.Errors...)
+			}
+		}
+	}
+
+	return operationErrors(&errs)
+}
+
+// ListAccessLevels returns the access levels (licenses) available in the organization.
+func (c *AzureDevOpsClient) ListAccessLevels(ctx context.Context) ([]licensing.AccessLevel, error) {
+	l := ctxzap.Extract(ctx)
+	if c.Server {
+		return nil, errServerUnsupported
+	}
+
+	selectAccessLevels := string(userentitlement.SummaryPropertyNameValues.AccessLevels)
+	summary, err := c.userEntitlementClient.GetUsersSummary(ctx, userentitlement.GetUsersSummaryArgs{Select: &selectAccessLevels})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	if summary.AvailableAccessLevels == nil {
+		return nil, nil
+	}
+
+	return *summary.AvailableAccessLevels, nil
+}
+
+// OperationErrors returns the error messages of an operation result of the member entitlement management API.
+func OperationErrors(kvs *[]azuredevops.KeyValuePair) []string {
+	var errorMessages []string
+	if kvs != nil {
+		for _, kv := range *kvs {
+			if kv.Value != nil {
+				errorMessages = append(errorMessages, fmt.Sprintf("%v", *kv.Value))
+			}
+		}
+	}
+	return errorMessages
+}
+
+// operationErrors builds an error out of the error messages returned by the member entitlement management API.
+func operationErrors(kvs *[]azuredevops.KeyValuePair) error {
+	errorMessages := OperationErrors(kvs)
+	if len(errorMessages) > 0 {
+		return errors.New(strings.Join(errorMessages, "; "))
+	}
+	return errors.New("unknown reason")
+}
+
 func (c *AzureDevOpsClient) ListProjects(ctx context.Context, nextContinuationToken string) ([]core.TeamProjectReference, string, error) {
 	l := ctxzap.Extract(ctx)
 
@@ -144,38 +540,162 @@ func (c *AzureDevOpsClient) ListProjects(ctx context.Context, nextContinuationTo
 	projects, err := c.coreClient.GetProjects(ctx, projectArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, "", err
+		return nil, "", wrapError(err)
 	}
 
 	return projects.Value, projects.ContinuationToken, nil
 }
 
-func (c *AzureDevOpsClient) ListTeams(ctx context.Context) ([]core.WebApiTeam, error) {
+// GetProjectID returns the id of a project, by name or id.
+func (c *AzureDevOpsClient) GetProjectID(ctx context.Context, project string) (uuid.UUID, error) {
 	l := ctxzap.Extract(ctx)
 
-	// Teams client query is not supporting pagination
-	teams, err := c.coreClient.GetAllTeams(ctx, core.GetAllTeamsArgs{})
+	teamProject, err := c.coreClient.GetProject(ctx, core.GetProjectArgs{ProjectId: &project})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting project: %s", err))
+		return uuid.Nil, wrapError(err)
+	}
+	if teamProject == nil || teamProject.Id == nil {
+		return uuid.Nil, fmt.Errorf("project %s: %w", project, ErrNotFound)
+	}
+
+	return *teamProject.Id, nil
+}
+
+const (
+	// teamsPageSize is the number of teams listed per page, which is also the default of the teams API.
+	teamsPageSize = 100
+	// teamMembersPageSize is the number of team members listed per page.
+	teamMembersPageSize = 100
+)
+
+// ListTeams returns a page of the teams of the organization. The page token is the number of teams already listed.
+// Azure DevOps Server collections that predate the organization wide teams API return ErrNotFound, in which case
+// the teams are listed per project with ListProjectTeams.
+func (c *AzureDevOpsClient) ListTeams(ctx context.Context, nextPageToken string) ([]core.WebApiTeam, string, error) {
+	l := ctxzap.Extract(ctx)
+
+	top, skip := teamsPageSize, pageOffset(nextPageToken)
+	teams, err := c.coreClient.GetAllTeams(ctx, core.GetAllTeamsArgs{Top: &top, Skip: &skip})
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, err
+		return nil, "", wrapError(err)
+	}
+
+	return *teams, nextPageOffset(skip, len(*teams), top), nil
+}
+
+// ListProjectTeams returns a page of the teams of a project. The page token is the number of teams already listed.
+func (c *AzureDevOpsClient) ListProjectTeams(ctx context.Context, projectId, nextPageToken string) ([]core.WebApiTeam, string, error) {
+	l := ctxzap.Extract(ctx)
+
+	top, skip := teamsPageSize, pageOffset(nextPageToken)
+	teams, err := c.coreClient.GetTeams(ctx, core.GetTeamsArgs{ProjectId: &projectId, Top: &top, Skip: &skip})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s;; for project %s", err, projectId))
+		return nil, "", wrapError(err)
 	}
 
-	return *teams, nil
+	return *teams, nextPageOffset(skip, len(*teams), top), nil
 }
 
-func (c *AzureDevOpsClient) ListTeamMembers(ctx context.Context, projectId, teamId string) ([]webapi.TeamMember, error) {
+// ListTeamMembers returns a page of the members of a team. The page token is the number of members already listed.
+func (c *AzureDevOpsClient) ListTeamMembers(ctx context.Context, projectId, teamId, nextPageToken string) ([]webapi.TeamMember, string, error) {
 	l := ctxzap.Extract(ctx)
 
+	top, skip := teamMembersPageSize, pageOffset(nextPageToken)
 	teamMembersArgs := core.GetTeamMembersWithExtendedPropertiesArgs{
 		ProjectId: &projectId,
 		TeamId:    &teamId,
+		Top:       &top,
+		Skip:      &skip,
 	}
 	teamMembers, err := c.coreClient.GetTeamMembersWithExtendedProperties(ctx, teamMembersArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, err
+		return nil, "", wrapError(err)
+	}
+
+	return *teamMembers, nextPageOffset(skip, len(*teamMembers), top), nil
+}
+
+// listAllTeams returns every team of the organization, listing the teams of each project when the organization wide
+// teams API is not available.
+func (c *AzureDevOpsClient) listAllTeams(ctx context.Context) ([]core.WebApiTeam, error) {
+	var teams []core.WebApiTeam
+
+	nextPageToken := ""
+	for {
+		page, next, err := c.ListTeams(ctx, nextPageToken)
+		if errors.Is(err, ErrNotFound) {
+			return c.listAllProjectTeams(ctx)
+		}
+		if err != nil {
+			return nil, err
+		}
+		teams = append(teams, page...)
+
+		if next == "" {
+			return teams, nil
+		}
+		nextPageToken = next
+	}
+}
+
+func (c *AzureDevOpsClient) listAllProjectTeams(ctx context.Context) ([]core.WebApiTeam, error) {
+	var teams []core.WebApiTeam
+
+	nextProjectsToken := ""
+	for {
+		projects, nextProjects, err := c.ListProjects(ctx, nextProjectsToken)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, project := range projects {
+			if project.Id == nil {
+				continue
+			}
+			nextPageToken := ""
+			for {
+				page, next, err := c.ListProjectTeams(ctx, project.Id.String(), nextPageToken)
+				if err != nil {
+					return nil, err
+				}
+				teams = append(teams, page...)
+
+				if next == "" {
+					break
+				}
+				nextPageToken = next
+			}
+		}
+
+		if nextProjects == "" {
+			return teams, nil
+		}
+		nextProjectsToken = nextProjects
+	}
+}
+
+// pageOffset parses a page token holding the number of items already listed.
+func pageOffset(pageToken string) int {
+	offset, err := strconv.Atoi(pageToken)
+	if err != nil || offset < 0 {
+		return 0
+	}
+
+	return offset
+}
+
+// nextPageOffset returns the page token of the page after a page of an offset paginated API, or an empty token when
+// the page was the last one.
+func nextPageOffset(offset, count, pageSize int) string {
+	if count < pageSize {
+		return ""
 	}
-	return *teamMembers, nil
+
+	return strconv.Itoa(offset + count)
 }
 
 func (c *AzureDevOpsClient) ListGroups(ctx context.Context, nextContinuationToken string) ([]graph.GraphGroup, string, error) {
@@ -190,7 +710,7 @@ func (c *AzureDevOpsClient) ListGroups(ctx context.Context, nextContinuationToke
 	groups, err := c.graphClient.ListGroups(ctx, groupArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, "", err
+		return nil, "", wrapError(err)
 	}
 
 	if *groups.ContinuationToken != nil && len(*groups.ContinuationToken) > 0 {
@@ -229,7 +749,7 @@ func (c *AzureDevOpsClient) ListTeamIDs(ctx context.Context) (map[string]bool, e
 	l := ctxzap.Extract(ctx)
 
 	teamsMap := make(map[string]bool)
-	teams, err := c.ListTeams(ctx)
+	teams, err := c.listAllTeams(ctx)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting team resources: %s", err))
 		return nil, err
@@ -258,12 +778,99 @@ func (c *AzureDevOpsClient) ListIdentities(ctx context.Context, identityIDs stri
 
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return *identities, nil
 }
 
+// ListDirectMemberIDs returns the identity ids of the direct members of a group, identified by its subject descriptor.
+// Nested members are not expanded.
+func (c *AzureDevOpsClient) ListDirectMemberIDs(ctx context.Context, subjectDescriptor string) ([]uuid.UUID, error) {
+	l := ctxzap.Extract(ctx)
+
+	identities, err := c.identityClient.ReadIdentities(ctx, identity.ReadIdentitiesArgs{
+		SubjectDescriptors: &subjectDescriptor,
+		QueryMembership:    &identity.QueryMembershipValues.Direct,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting members of %s: %s", subjectDescriptor, err))
+		return nil, wrapError(err)
+	}
+
+	if identities == nil || len(*identities) == 0 || (*identities)[0].MemberIds == nil {
+		return nil, nil
+	}
+
+	return *(*identities)[0].MemberIds, nil
+}
+
+// ListExpandedMemberIDs returns the identity ids of the direct and nested members of a group, by identity descriptor.
+func (c *AzureDevOpsClient) ListExpandedMemberIDs(ctx context.Context, descriptor string) ([]uuid.UUID, error) {
+	l := ctxzap.Extract(ctx)
+
+	identities, err := c.identityClient.ReadIdentities(ctx, identity.ReadIdentitiesArgs{
+		Descriptors:     &descriptor,
+		QueryMembership: &identity.QueryMembershipValues.Expanded,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting expanded members of %s: %s", descriptor, err))
+		return nil, wrapError(err)
+	}
+
+	if identities == nil || len(*identities) == 0 || (*identities)[0].MemberIds == nil {
+		return nil, nil
+	}
+
+	return *(*identities)[0].MemberIds, nil
+}
+
+// readIdentitiesBatchSize is the number of descriptors or identity ids read per ReadIdentities request, which passes
+// them in the query string.
+const readIdentitiesBatchSize = 50
+
+// ReadIdentitiesByDescriptors reads the identities of identity descriptors, without their memberships. Descriptors
+// that do not resolve to an identity are omitted.
+func (c *AzureDevOpsClient) ReadIdentitiesByDescriptors(ctx context.Context, descriptors []string) ([]identity.Identity, error) {
+	return c.readIdentities(ctx, descriptors, func(batch string) identity.ReadIdentitiesArgs {
+		return identity.ReadIdentitiesArgs{Descriptors: &batch}
+	})
+}
+
+// ReadIdentitiesByIDs reads the identities of identity ids, without their memberships. Ids that do not resolve to an
+// identity are omitted.
+func (c *AzureDevOpsClient) ReadIdentitiesByIDs(ctx context.Context, identityIDs []string) ([]identity.Identity, error) {
+	return c.readIdentities(ctx, identityIDs, func(batch string) identity.ReadIdentitiesArgs {
+		return identity.ReadIdentitiesArgs{IdentityIds: &batch}
+	})
+}
+
+// readIdentities reads identities in batches of comma separated keys.
+func (c *AzureDevOpsClient) readIdentities(ctx context.Context, keys []string, args func(batch string) identity.ReadIdentitiesArgs) ([]identity.Identity, error) {
+	l := ctxzap.Extract(ctx)
+
+	var identities []identity.Identity
+	for start := 0; start < len(keys); start += readIdentitiesBatchSize {
+		end := min(start+readIdentitiesBatchSize, len(keys))
+		batch, err := c.identityClient.ReadIdentities(ctx, args(strings.Join(keys[start:end], ",")))
+		if err != nil {
+			l.Error(fmt.Sprintf("Error reading identities: %s", err))
+			return nil, wrapError(err)
+		}
+		if batch == nil {
+			continue
+		}
+		// Keys that do not resolve are returned as null identities.
+		for _, batchIdentity := range *batch {
+			if batchIdentity.Id != nil {
+				identities = append(identities, batchIdentity)
+			}
+		}
+	}
+
+	return identities, nil
+}
+
 func (c *AzureDevOpsClient) ListSecurityNamespaces(ctx context.Context, securityNamespaces []string) ([]security.SecurityNamespaceDescription, error) {
 	l := ctxzap.Extract(ctx)
 
@@ -284,7 +891,7 @@ func (c *AzureDevOpsClient) ListSecurityNamespaces(ctx context.Context, security
 		namespaces, err := c.securityClient.QuerySecurityNamespaces(ctx, arguments)
 		if err != nil {
 			l.Error(fmt.Sprintf("Error getting resources: %s", err))
-			return nil, err
+			return nil, wrapError(err)
 		}
 		finalNamespaces = append(finalNamespaces, *namespaces...)
 	}
@@ -292,13 +899,26 @@ func (c *AzureDevOpsClient) ListSecurityNamespaces(ctx context.Context, security
 	return finalNamespaces, nil
 }
 
+// ListAllSecurityNamespaces returns every security namespace of the organization.
+func (c *AzureDevOpsClient) ListAllSecurityNamespaces(ctx context.Context) ([]security.SecurityNamespaceDescription, error) {
+	l := ctxzap.Extract(ctx)
+
+	namespaces, err := c.securityClient.QuerySecurityNamespaces(ctx, security.QuerySecurityNamespacesArgs{})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *namespaces, nil
+}
+
 func (c *AzureDevOpsClient) ListActionsBySecurityNamespace(ctx context.Context, securityNamespaceId uuid.UUID) ([]security.ActionDefinition, error) {
 	l := ctxzap.Extract(ctx)
 
 	securityNamespace, err := c.securityClient.QuerySecurityNamespaces(ctx, security.QuerySecurityNamespacesArgs{SecurityNamespaceId: &securityNamespaceId})
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	if *securityNamespace != nil && len(*securityNamespace) > 0 {
@@ -320,32 +940,120 @@ func (c *AzureDevOpsClient) ListAccessControlsBySecurityNamespace(ctx context.Co
 	})
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s", err))
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return *lists, nil
 }
 
-func (c *AzureDevOpsClient) GetUsersMap(ctx context.Context) (map[string]string, error) {
-	userMap := make(map[string]string)
-	nextPageToken := ""
+// GetAccessControlEntry returns the ACE of an identity descriptor on a security token, or nil when there is none.
+func (c *AzureDevOpsClient) GetAccessControlEntry(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string) (*security.AccessControlEntry, error) {
+	l := ctxzap.Extract(ctx)
 
-	for {
-		users, nextPageToken, err := c.ListUsers(ctx, nextPageToken)
-		if err != nil {
-			return nil, err
-		}
+	includeExtendedInfo := true
+	lists, err := c.securityClient.QueryAccessControlLists(ctx, security.QueryAccessControlListsArgs{
+		SecurityNamespaceId: &securityNamespaceId,
+		Token:               &token,
+		Descriptors:         &descriptor,
+		IncludeExtendedInfo: &includeExtendedInfo,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
 
-		for _, user := range users {
-			userMap[*user.User.PrincipalName] = *user.User.Descriptor
+	for _, acl := range *lists {
+		if acl.AcesDictionary == nil {
+			continue
+		}
+		for _, ace := range *acl.AcesDictionary {
+			if ace.Descriptor != nil && strings.EqualFold(*ace.Descriptor, descriptor) {
+				return &ace, nil
+			}
 		}
+	}
+
+	return nil, nil
+}
+
+// ListAccessControlEntries returns the ACEs of the given identity descriptors on a security token, including the
+// permissions they inherit from parent tokens and group memberships.
+func (c *AzureDevOpsClient) ListAccessControlEntries(ctx context.Context, securityNamespaceId uuid.UUID, token string, descriptors []string) ([]security.AccessControlEntry, error) {
+	l := ctxzap.Extract(ctx)
+
+	if len(descriptors) == 0 {
+		return nil, nil
+	}
+
+	includeExtendedInfo := true
+	descriptorList := strings.Join(descriptors, ",")
+	lists, err := c.securityClient.QueryAccessControlLists(ctx, security.QueryAccessControlListsArgs{
+		SecurityNamespaceId: &securityNamespaceId,
+		Token:               &token,
+		Descriptors:         &descriptorList,
+		IncludeExtendedInfo: &includeExtendedInfo,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
 
-		if nextPageToken == "" {
-			break
+	var aces []security.AccessControlEntry
+	for _, acl := range *lists {
+		if acl.AcesDictionary == nil {
+			continue
+		}
+		for _, ace := range *acl.AcesDictionary {
+			aces = append(aces, ace)
 		}
 	}
 
-	return userMap, nil
+	return aces, nil
+}
+
+// SetAccessControlEntry merges the allow and deny bits into the ACE of an identity descriptor on a security token.
+func (c *AzureDevOpsClient) SetAccessControlEntry(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string, allow, deny int) error {
+	l := ctxzap.Extract(ctx)
+
+	container := map[string]interface{}{
+		"token": token,
+		"merge": true,
+		"accessControlEntries": []security.AccessControlEntry{
+			{
+				Descriptor: &descriptor,
+				Allow:      &allow,
+				Deny:       &deny,
+			},
+		},
+	}
+	_, err := c.securityClient.SetAccessControlEntries(ctx, security.SetAccessControlEntriesArgs{
+		SecurityNamespaceId: &securityNamespaceId,
+		Container:           container,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error setting access control entry: %s", err))
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// RemovePermission clears the given permission bits (both allow and deny) from the ACE of an identity descriptor on a security token.
+func (c *AzureDevOpsClient) RemovePermission(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string, permissions int) error {
+	l := ctxzap.Extract(ctx)
+
+	_, err := c.securityClient.RemovePermission(ctx, security.RemovePermissionArgs{
+		SecurityNamespaceId: &securityNamespaceId,
+		Descriptor:          &descriptor,
+		Permissions:         &permissions,
+		Token:               &token,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error removing permission: %s", err))
+		return wrapError(err)
+	}
+
+	return nil
 }
 
 func (c *AzureDevOpsClient) GetIdentity(ctx context.Context, identityID *string) (string, error) {
@@ -354,7 +1062,7 @@ func (c *AzureDevOpsClient) GetIdentity(ctx context.Context, identityID *string)
 	identities, err := c.identityClient.ReadIdentities(ctx, identity.ReadIdentitiesArgs{Descriptors: identityID})
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s;; for identity %s", err, *identityID))
-		return "", err
+		return "", wrapError(err)
 	}
 
 	if identities != nil && len(*identities) > 0 {
@@ -367,13 +1075,30 @@ func (c *AzureDevOpsClient) GetIdentity(ctx context.Context, identityID *string)
 	return "", nil
 }
 
+// GetIdentityDescriptor returns the identity descriptor, as used in access control entries, of a subject descriptor.
+func (c *AzureDevOpsClient) GetIdentityDescriptor(ctx context.Context, subjectDescriptor string) (string, error) {
+	l := ctxzap.Extract(ctx)
+
+	identities, err := c.identityClient.ReadIdentities(ctx, identity.ReadIdentitiesArgs{SubjectDescriptors: &subjectDescriptor})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting identity of %s: %s", subjectDescriptor, err))
+		return "", wrapError(err)
+	}
+
+	if identities == nil || len(*identities) == 0 || (*identities)[0].Descriptor == nil {
+		return "", fmt.Errorf("%w: no identity found for %s", ErrNotFound, subjectDescriptor)
+	}
+
+	return *(*identities)[0].Descriptor, nil
+}
+
 func (c *AzureDevOpsClient) ListRepositories(ctx context.Context, projectName string) ([]git.GitRepository, error) {
 	l := ctxzap.Extract(ctx)
 
 	repositories, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{Project: &projectName})
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting resources: %s;; for project %s", err, projectName))
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	if repositories != nil && len(*repositories) > 0 {
@@ -382,6 +1107,250 @@ func (c *AzureDevOpsClient) ListRepositories(ctx context.Context, projectName st
 	return nil, nil
 }
 
+// GetRootClassificationNodeID returns the identifier of the root area or iteration node of a project.
+func (c *AzureDevOpsClient) GetRootClassificationNodeID(ctx context.Context, projectID string, structureGroup workitemtracking.TreeStructureGroup) (uuid.UUID, error) {
+	l := ctxzap.Extract(ctx)
+
+	node, err := c.workItemClient.GetClassificationNode(ctx, workitemtracking.GetClassificationNodeArgs{
+		Project:        &projectID,
+		StructureGroup: &structureGroup,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return uuid.Nil, wrapError(err)
+	}
+	if node == nil || node.Identifier == nil {
+		return uuid.Nil, fmt.Errorf("no root %s node found for project %s", structureGroup, projectID)
+	}
+
+	return *node.Identifier, nil
+}
+
+// ListBuildDefinitions returns the YAML and classic build pipelines of a project.
+func (c *AzureDevOpsClient) ListBuildDefinitions(ctx context.Context, projectID, nextContinuationToken string) ([]build.BuildDefinitionReference, string, error) {
+	l := ctxzap.Extract(ctx)
+
+	args := build.GetDefinitionsArgs{
+		Project:    &projectID,
+		QueryOrder: &build.DefinitionQueryOrderValues.DefinitionNameAscending,
+	}
+	if nextContinuationToken != "" {
+		args.ContinuationToken = &nextContinuationToken
+	}
+
+	definitions, err := c.buildClient.GetDefinitions(ctx, args)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, "", wrapError(err)
+	}
+
+	return definitions.Value, definitions.ContinuationToken, nil
+}
+
+// GetBuildDefinition returns a build pipeline of a project, or an error when it does not exist.
+func (c *AzureDevOpsClient) GetBuildDefinition(ctx context.Context, projectID string, definitionID int) (*build.BuildDefinitionReference, error) {
+	l := ctxzap.Extract(ctx)
+
+	definitions, err := c.buildClient.GetDefinitions(ctx, build.GetDefinitionsArgs{
+		Project:       &projectID,
+		DefinitionIds: &[]int{definitionID},
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+	if len(definitions.Value) == 0 {
+		return nil, fmt.Errorf("build definition %d not found in project %s", definitionID, projectID)
+	}
+
+	return &definitions.Value[0], nil
+}
+
+// ListServiceEndpoints returns the service connections of a project, including the ones shared with it.
+func (c *AzureDevOpsClient) ListServiceEndpoints(ctx context.Context, projectID string) ([]serviceendpoint.ServiceEndpoint, error) {
+	l := ctxzap.Extract(ctx)
+
+	endpoints, err := c.serviceEndpointClient.GetServiceEndpoints(ctx, serviceendpoint.GetServiceEndpointsArgs{
+		Project: &projectID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *endpoints, nil
+}
+
+// ListAgentPools returns the agent pools and deployment pools of the organization.
+func (c *AzureDevOpsClient) ListAgentPools(ctx context.Context) ([]taskagent.TaskAgentPool, error) {
+	l := ctxzap.Extract(ctx)
+
+	pools, err := c.taskAgentClient.GetAgentPools(ctx, taskagent.GetAgentPoolsArgs{})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *pools, nil
+}
+
+// ListAgentQueues returns the agent queues of a project, which link the project to the agent pools it can use.
+func (c *AzureDevOpsClient) ListAgentQueues(ctx context.Context, projectID string) ([]taskagent.TaskAgentQueue, error) {
+	l := ctxzap.Extract(ctx)
+
+	queues, err := c.taskAgentClient.GetAgentQueues(ctx, taskagent.GetAgentQueuesArgs{
+		Project: &projectID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *queues, nil
+}
+
+// ListEnvironments returns the pipeline environments of a project.
+func (c *AzureDevOpsClient) ListEnvironments(ctx context.Context, projectID, nextContinuationToken string) ([]taskagent.EnvironmentInstance, string, error) {
+	l := ctxzap.Extract(ctx)
+
+	args := taskagent.GetEnvironmentsArgs{
+		Project: &projectID,
+	}
+	if nextContinuationToken != "" {
+		args.ContinuationToken = &nextContinuationToken
+	}
+
+	environments, err := c.taskAgentClient.GetEnvironments(ctx, args)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, "", wrapError(err)
+	}
+
+	return environments.Value, environments.ContinuationToken, nil
+}
+
+// ListVariableGroups returns the variable groups of a project's pipeline Library.
+func (c *AzureDevOpsClient) ListVariableGroups(ctx context.Context, projectID string) ([]taskagent.VariableGroup, error) {
+	l := ctxzap.Extract(ctx)
+
+	variableGroups, err := c.taskAgentClient.GetVariableGroups(ctx, taskagent.GetVariableGroupsArgs{
+		Project: &projectID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *variableGroups, nil
+}
+
+// ListSecureFiles returns the secure files of a project's pipeline Library.
+func (c *AzureDevOpsClient) ListSecureFiles(ctx context.Context, projectID string) ([]securefiles.SecureFile, error) {
+	l := ctxzap.Extract(ctx)
+
+	files, err := c.secureFilesClient.GetSecureFiles(ctx, securefiles.GetSecureFilesArgs{
+		Project: &projectID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *files, nil
+}
+
+// GetPipelinePermissions returns the pipelines authorized to consume a protected resource of a project, such as a
+// variable group (resource type variablegroup) or a secure file (resource type securefile).
+func (c *AzureDevOpsClient) GetPipelinePermissions(ctx context.Context, projectID, resourceType, resourceID string) (*pipelinepermissions.ResourcePipelinePermissions, error) {
+	l := ctxzap.Extract(ctx)
+
+	permissions, err := c.pipelinePermissionsClient.GetPipelinePermissionsForResource(ctx, pipelinepermissions.GetPipelinePermissionsForResourceArgs{
+		Project:      &projectID,
+		ResourceType: &resourceType,
+		ResourceId:   &resourceID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return permissions, nil
+}
+
+// UpdatePipelinePermissions authorizes or unauthorizes pipelines, or every pipeline of the project, to consume a
+// protected resource of a project. Pipelines left out of the permissions keep their authorization.
+func (c *AzureDevOpsClient) UpdatePipelinePermissions(ctx context.Context, projectID, resourceType, resourceID string, permissions *pipelinepermissions.ResourcePipelinePermissions) error {
+	l := ctxzap.Extract(ctx)
+
+	_, err := c.pipelinePermissionsClient.UpdatePipelinePermisionsForResource(ctx, pipelinepermissions.UpdatePipelinePermisionsForResourceArgs{
+		ResourceAuthorization: permissions,
+		Project:               &projectID,
+		ResourceType:          &resourceType,
+		ResourceId:            &resourceID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error updating pipeline permissions: %s", err))
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// ListRoleAssignments returns the role assignments, both direct and inherited, of a resource in a security role scope.
+func (c *AzureDevOpsClient) ListRoleAssignments(ctx context.Context, scopeID, resourceID string) ([]securityroles.RoleAssignment, error) {
+	l := ctxzap.Extract(ctx)
+
+	roleAssignments, err := c.securityRolesClient.GetRoleAssignments(ctx, securityroles.GetRoleAssignmentsArgs{
+		ScopeId:    &scopeID,
+		ResourceId: &resourceID,
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, wrapError(err)
+	}
+
+	return *roleAssignments, nil
+}
+
+// SetRoleAssignment assigns a role on a resource to an identity, replacing the role it was directly assigned.
+func (c *AzureDevOpsClient) SetRoleAssignment(ctx context.Context, scopeID, resourceID, identityID, roleName string) error {
+	l := ctxzap.Extract(ctx)
+
+	_, err := c.securityRolesClient.SetRoleAssignments(ctx, securityroles.SetRoleAssignmentsArgs{
+		ScopeId:    &scopeID,
+		ResourceId: &resourceID,
+		RoleAssignments: &[]securityroles.UserRoleAssignmentRef{
+			{
+				RoleName: &roleName,
+				UserId:   &identityID,
+			},
+		},
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error setting role assignment: %s", err))
+		return wrapError(err)
+	}
+
+	return nil
+}
+
+// RemoveRoleAssignment removes the role directly assigned to an identity on a resource.
+func (c *AzureDevOpsClient) RemoveRoleAssignment(ctx context.Context, scopeID, resourceID, identityID string) error {
+	l := ctxzap.Extract(ctx)
+
+	err := c.securityRolesClient.RemoveRoleAssignments(ctx, securityroles.RemoveRoleAssignmentsArgs{
+		ScopeId:     &scopeID,
+		ResourceId:  &resourceID,
+		IdentityIds: &[]string{identityID},
+	})
+	if err != nil {
+		l.Error(fmt.Sprintf("Error removing role assignment: %s", err))
+		return wrapError(err)
+	}
+
+	return nil
+}
+
 // This function creates a membership, the container can be a team or a group.
 func (c *AzureDevOpsClient) CreateMembership(ctx context.Context, containerDescriptor string, memberDescriptor string) (*graph.GraphMembership, error) {
 	l := ctxzap.Extract(ctx)
@@ -392,7 +1361,7 @@ func (c *AzureDevOpsClient) CreateMembership(ctx context.Context, containerDescr
 	newMembership, err := c.graphClient.AddMembership(ctx, addMembershipArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error creating membership: %s", err))
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return newMembership, nil
@@ -408,7 +1377,7 @@ func (c *AzureDevOpsClient) RevokeMembership(ctx context.Context, containerDescr
 	err := c.graphClient.RemoveMembership(ctx, removeMembershipArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error removing membership: %s", err))
-		return err
+		return wrapError(err)
 	}
 
 	return nil
@@ -424,12 +1393,28 @@ func (c *AzureDevOpsClient) GetMembership(ctx context.Context, containerDescript
 	membership, err := c.graphClient.GetMembership(ctx, membershipArgs)
 	if err != nil {
 		l.Error(fmt.Sprintf("Error getting membership: %s", err))
-		return nil, err
+		return nil, wrapError(err)
 	}
 
 	return membership, nil
 }
 
+// GetStorageKey resolves a subject descriptor into its storage key, which is the id used by the identity and
+// member entitlement management APIs.
+func (c *AzureDevOpsClient) GetStorageKey(ctx context.Context, descriptor string) (uuid.UUID, error) {
+	l := ctxzap.Extract(ctx)
+
+	response, err := c.graphClient.GetStorageKey(ctx, graph.GetStorageKeyArgs{
+		SubjectDescriptor: &descriptor,
+	})
+	if err != nil {
+		l.Error("Error getting storage key", zap.Error(err))
+		return uuid.Nil, wrapError(err)
+	}
+
+	return *response.Value, nil
+}
+
 func (c *AzureDevOpsClient) GetDescriptor(ctx context.Context, storageKey uuid.UUID) (string, error) {
 	l := ctxzap.Extract(ctx)
 
@@ -438,7 +1423,7 @@ func (c *AzureDevOpsClient) GetDescriptor(ctx context.Context, storageKey uuid.U
 	})
 	if err != nil {
 		l.Error("Error getting descriptor", zap.Error(err))
-		return "", err
+		return "", wrapError(err)
 	}
 
 	return *response.Value, nil
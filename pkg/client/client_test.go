@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIdentityClient resolves every descriptor but the unknown one, returning a null identity for it like Azure DevOps.
+type fakeIdentityClient struct {
+	identity.Client
+	batches []int
+}
+
+func (f *fakeIdentityClient) ReadIdentities(_ context.Context, args identity.ReadIdentitiesArgs) (*[]identity.Identity, error) {
+	descriptors := strings.Split(*args.Descriptors, ",")
+	f.batches = append(f.batches, len(descriptors))
+
+	identities := make([]identity.Identity, 0, len(descriptors))
+	for _, descriptor := range descriptors {
+		if descriptor == "unknown" {
+			identities = append(identities, identity.Identity{})
+			continue
+		}
+		id := uuid.New()
+		identities = append(identities, identity.Identity{Id: &id, Descriptor: &descriptor})
+	}
+
+	return &identities, nil
+}
+
+func TestReadIdentitiesByDescriptors(t *testing.T) {
+	identityClient := &fakeIdentityClient{}
+	client := &AzureDevOpsClient{identityClient: identityClient}
+
+	descriptors := []string{"unknown"}
+	for i := 0; i < 2*readIdentitiesBatchSize; i++ {
+		descriptors = append(descriptors, fmt.Sprintf("Microsoft.TeamFoundation.Identity;S-1-9-%d", i))
+	}
+
+	identities, err := client.ReadIdentitiesByDescriptors(context.Background(), descriptors)
+	require.NoError(t, err)
+	assert.Len(t, identities, 2*readIdentitiesBatchSize)
+	assert.Equal(t, []int{readIdentitiesBatchSize, readIdentitiesBatchSize, 1}, identityClient.batches)
+}
+
+func TestPageOffset(t *testing.T) {
+	assert.Equal(t, 0, pageOffset(""))
+	assert.Equal(t, 0, pageOffset("invalid"))
+	assert.Equal(t, 200, pageOffset("200"))
+
+	assert.Equal(t, "200", nextPageOffset(100, 100, 100))
+	assert.Equal(t, "", nextPageOffset(100, 42, 100))
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+// authorizingTransport sets the Authorization header of every request from the credentials, so that short-lived
+// access tokens are refreshed without recreating the clients.
+type authorizingTransport struct {
+	base        http.RoundTripper
+	credentials Credentials
+}
+
+func (t *authorizingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	authorization, err := t.credentials.Authorization(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests with invalid or expired credentials are answered with a sign-in page, either as 401 Unauthorized or as
+	// 203 Non-Authoritative Information, which the sdk fails to parse as an error of the API.
+	if (resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusNonAuthoritativeInfo) && !isJSONResponse(resp) {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{
+			StatusCode: resp.StatusCode,
+			kind:       ErrUnauthorized,
+			err:        fmt.Errorf("baton-azure-devops: %s was answered with a sign-in page (%s)", req.URL.Path, resp.Status),
+		}
+	}
+
+	return resp, nil
+}
+
+// isJSONResponse returns whether the body of a response is JSON, as the responses of the API are.
+func isJSONResponse(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// connection creates the clients of the azure devops sdk. The sdk clients create their own http client when they are
+// created from an azuredevops.Connection, so they are created here instead to send their requests through the
+// authorizing and rate limiting transports.
+type connection struct {
+	*azuredevops.Connection
+	httpClient *http.Client
+	rateLimit  *rateLimitTransport
+
+	resourceAreasMutex sync.Mutex
+	resourceAreas      map[uuid.UUID]string
+}
+
+func newConnection(organizationUrl string, credentials Credentials) *connection {
+	// Throttled requests are retried through the authorizing transport, so that they are sent with a fresh token.
+	rateLimit := newRateLimitTransport(&authorizingTransport{
+		base:        &apiVersionTransport{base: http.DefaultTransport},
+		credentials: credentials,
+	})
+
+	return &connection{
+		Connection: azuredevops.NewAnonymousConnection(organizationUrl),
+		httpClient: &http.Client{Transport: rateLimit},
+		rateLimit:  rateLimit,
+	}
+}
+
+// clientByUrl returns a client for the given base url.
+func (c *connection) clientByUrl(baseUrl string) *azuredevops.Client {
+	return azuredevops.NewClientWithOptions(
+		c.Connection,
+		strings.ToLower(strings.TrimRight(baseUrl, "/")),
+		azuredevops.WithHTTPClient(c.httpClient),
+	)
+}
+
+// clientByResourceAreaId returns a client for the location of a resource area, such as vsaex.dev.azure.com for member
+// entitlement management. Servers that publish no resource areas serve every area from the organization url.
+func (c *connection) clientByResourceAreaId(ctx context.Context, resourceAreaID uuid.UUID) (*azuredevops.Client, error) {
+	c.resourceAreasMutex.Lock()
+	defer c.resourceAreasMutex.Unlock()
+
+	if c.resourceAreas == nil {
+		resourceAreas, err := c.clientByUrl(c.BaseUrl).GetResourceAreas(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.resourceAreas = make(map[uuid.UUID]string)
+		for _, resourceArea := range *resourceAreas {
+			if resourceArea.Id != nil && resourceArea.LocationUrl != nil {
+				c.resourceAreas[*resourceArea.Id] = *resourceArea.LocationUrl
+			}
+		}
+	}
+
+	if len(c.resourceAreas) == 0 {
+		return c.clientByUrl(c.BaseUrl), nil
+	}
+
+	locationUrl, ok := c.resourceAreas[resourceAreaID]
+	if !ok {
+		return nil, &azuredevops.ResourceAreaIdNotRegisteredError{ResourceAreaId: resourceAreaID, Url: c.BaseUrl}
+	}
+
+	return c.clientByUrl(locationUrl), nil
+}
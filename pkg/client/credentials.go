@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1" //nolint:gosec // the x5t header of a client assertion is the SHA-1 thumbprint of the certificate.
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+const (
+	// DefaultAuthorityHost is the Microsoft Entra authority of the Azure public cloud.
+	DefaultAuthorityHost = "https://login.microsoftonline.com"
+	// azureDevOpsScope requests a token for the Azure DevOps resource, whose application id is fixed for every tenant.
+	azureDevOpsScope = "499b84ac-1321-427f-aa17-267ca6975798/.default"
+	// tokenRefreshWindow is how long before its expiry an access token is replaced.
+	tokenRefreshWindow = 5 * time.Minute
+	// clientAssertionType is the client_assertion_type of signed JWT client assertions.
+	clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+)
+
+// Credentials authorize the requests sent to Azure DevOps.
+type Credentials interface {
+	// Authorization returns the value of the Authorization header of a request.
+	Authorization(ctx context.Context) (string, error)
+}
+
+type personalAccessTokenCredentials struct {
+	authorization string
+}
+
+// NewPersonalAccessTokenCredentials authorizes requests with a Personal Access Token.
+func NewPersonalAccessTokenCredentials(personalAccessToken string) Credentials {
+	return &personalAccessTokenCredentials{
+		authorization: azuredevops.CreateBasicAuthHeaderValue("", personalAccessToken),
+	}
+}
+
+func (c *personalAccessTokenCredentials) Authorization(_ context.Context) (string, error) {
+	return c.authorization, nil
+}
+
+// EntraOption customizes how Microsoft Entra access tokens are acquired.
+type EntraOption func(*entraCredentials)
+
+// WithAuthorityHost sets the Microsoft Entra authority that issues the access tokens, e.g. for sovereign clouds.
+func WithAuthorityHost(authorityHost string) EntraOption {
+	return func(c *entraCredentials) {
+		c.authorityHost = strings.TrimSuffix(authorityHost, "/")
+	}
+}
+
+// WithTokenHTTPClient sets the http client used to request access tokens.
+func WithTokenHTTPClient(httpClient *http.Client) EntraOption {
+	return func(c *entraCredentials) {
+		c.httpClient = httpClient
+	}
+}
+
+// entraCredentials authorize requests with Microsoft Entra access tokens obtained through the client credentials
+// flow of a service principal. The service principal proves its identity with a client secret, with a client
+// assertion signed by its certificate or with a token federated from another identity provider. Tokens are cached and
+// replaced shortly before they expire.
+type entraCredentials struct {
+	tenantID      string
+	clientID      string
+	clientSecret  string
+	assertion     func(tokenUrl string) (string, error)
+	authorityHost string
+	httpClient    *http.Client
+	now           func() time.Time
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresOn   time.Time
+}
+
+// NewClientSecretCredentials authorizes requests as a service principal that authenticates with a client secret.
+func NewClientSecretCredentials(tenantID, clientID, clientSecret string, opts ...EntraOption) (Credentials, error) {
+	if clientSecret == "" {
+		return nil, errors.New("baton-azure-devops: client secret is required")
+	}
+
+	return newEntraCredentials(tenantID, clientID, func(c *entraCredentials) {
+		c.clientSecret = clientSecret
+	}, opts...)
+}
+
+// NewClientCertificateCredentials authorizes requests as a service principal that authenticates with a certificate.
+// The PEM data must hold the certificate and its unencrypted RSA private key.
+func NewClientCertificateCredentials(tenantID, clientID string, certificatePEM []byte, opts ...EntraOption) (Credentials, error) {
+	certificate, privateKey, err := parseCertificate(certificatePEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEntraCredentials(tenantID, clientID, func(c *entraCredentials) {
+		c.assertion = func(tokenUrl string) (string, error) {
+			return signClientAssertion(certificate, privateKey, clientID, tokenUrl, c.now())
+		}
+	}, opts...)
+}
+
+// NewWorkloadIdentityCredentials authorizes requests as a service principal that trusts the tokens of another identity
+// provider through a federated identity credential, such as a Kubernetes service account token projected into a file.
+// The file is read again every time an access token is requested, so that rotated tokens are picked up.
+func NewWorkloadIdentityCredentials(tenantID, clientID, tokenFilePath string, opts ...EntraOption) (Credentials, error) {
+	if tokenFilePath == "" {
+		return nil, errors.New("baton-azure-devops: federated token file is required")
+	}
+
+	return newEntraCredentials(tenantID, clientID, func(c *entraCredentials) {
+		c.assertion = func(_ string) (string, error) {
+			return readFederatedToken(tokenFilePath)
+		}
+	}, opts...)
+}
+
+func newEntraCredentials(tenantID, clientID string, secret EntraOption, opts ...EntraOption) (*entraCredentials, error) {
+	if tenantID == "" {
+		return nil, errors.New("baton-azure-devops: tenant id is required")
+	}
+	if clientID == "" {
+		return nil, errors.New("baton-azure-devops: client id is required")
+	}
+
+	credentials := &entraCredentials{
+		tenantID:      tenantID,
+		clientID:      clientID,
+		authorityHost: DefaultAuthorityHost,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		now:           time.Now,
+	}
+	secret(credentials)
+	for _, opt := range opts {
+		opt(credentials)
+	}
+
+	return credentials, nil
+}
+
+func (c *entraCredentials) Authorization(ctx context.Context) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.accessToken == "" || !c.now().Before(c.expiresOn.Add(-tokenRefreshWindow)) {
+		accessToken, expiresOn, err := c.requestToken(ctx)
+		if err != nil {
+			return "", err
+		}
+		c.accessToken = accessToken
+		c.expiresOn = expiresOn
+	}
+
+	return "Bearer " + c.accessToken, nil
+}
+
+type tokenResponse struct {
+	AccessToken      string      `json:"access_token"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// requestToken requests a new access token for the Azure DevOps resource from the token endpoint of the tenant.
+func (c *entraCredentials) requestToken(ctx context.Context) (string, time.Time, error) {
+	tokenUrl := fmt.Sprintf("%s/%s/oauth2/v2.0/token", c.authorityHost, url.PathEscape(c.tenantID))
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", c.clientID)
+	form.Set("scope", azureDevOpsScope)
+	if c.assertion != nil {
+		assertion, err := c.assertion(tokenUrl)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	} else {
+		form.Set("client_secret", c.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	requestedOn := c.now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("baton-azure-devops: error requesting entra access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("baton-azure-devops: error reading entra token response: %w", err)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil && resp.StatusCode == http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("baton-azure-devops: error parsing entra token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if token.Error != "" {
+			return "", time.Time{}, fmt.Errorf("baton-azure-devops: error requesting entra access token: %s: %s", token.Error, token.ErrorDescription)
+		}
+		return "", time.Time{}, fmt.Errorf("baton-azure-devops: error requesting entra access token: unexpected status %s", resp.Status)
+	}
+	if token.AccessToken == "" {
+		return "", time.Time{}, errors.New("baton-azure-devops: entra token response has no access token")
+	}
+
+	expiresIn, err := strconv.ParseInt(token.ExpiresIn.String(), 10, 64)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("baton-azure-devops: invalid expires_in in entra token response: %w", err)
+	}
+
+	return token.AccessToken, requestedOn.Add(time.Duration(expiresIn) * time.Second), nil
+}
+
+// readFederatedToken reads the token of the federated identity provider, which is presented as the client assertion.
+func readFederatedToken(tokenFilePath string) (string, error) {
+	token, err := os.ReadFile(tokenFilePath)
+	if err != nil {
+		return "", fmt.Errorf("baton-azure-devops: error reading federated token file: %w", err)
+	}
+
+	assertion := strings.TrimSpace(string(token))
+	if assertion == "" {
+		return "", fmt.Errorf("baton-azure-devops: federated token file %s is empty", tokenFilePath)
+	}
+
+	return assertion, nil
+}
+
+// parseCertificate reads the certificate and the RSA private key of a PEM bundle.
+func parseCertificate(certificatePEM []byte) (*x509.Certificate, *rsa.PrivateKey, error) {
+	var certificate *x509.Certificate
+	var privateKey *rsa.PrivateKey
+
+	for {
+		var block *pem.Block
+		block, certificatePEM = pem.Decode(certificatePEM)
+		if block == nil {
+			break
+		}
+
+		switch block.Type {
+		case "CERTIFICATE":
+			if certificate != nil {
+				continue
+			}
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("baton-azure-devops: error parsing client certificate: %w", err)
+			}
+			certificate = parsed
+		case "RSA PRIVATE KEY":
+			parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("baton-azure-devops: error parsing client certificate private key: %w", err)
+			}
+			privateKey = parsed
+		case "PRIVATE KEY":
+			parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("baton-azure-devops: error parsing client certificate private key: %w", err)
+			}
+			rsaKey, ok := parsed.(*rsa.PrivateKey)
+			if !ok {
+				return nil, nil, errors.New("baton-azure-devops: client certificate private key must be an RSA key")
+			}
+			privateKey = rsaKey
+		}
+	}
+
+	if certificate == nil {
+		return nil, nil, errors.New("baton-azure-devops: no certificate found in the client certificate file")
+	}
+	if privateKey == nil {
+		return nil, nil, errors.New("baton-azure-devops: no unencrypted private key found in the client certificate file")
+	}
+
+	return certificate, privateKey, nil
+}
+
+// signClientAssertion builds the JWT a service principal presents instead of a client secret, signed with the private
+// key of its certificate.
+func signClientAssertion(certificate *x509.Certificate, privateKey *rsa.PrivateKey, clientID, tokenUrl string, now time.Time) (string, error) {
+	thumbprint := sha1.Sum(certificate.Raw) //nolint:gosec // required by the x5t header.
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"x5t": base64.RawURLEncoding.EncodeToString(thumbprint[:]),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": tokenUrl,
+		"iss": clientID,
+		"sub": clientID,
+		"jti": uuid.NewString(),
+		"nbf": now.Unix(),
+		"iat": now.Unix(),
+		"exp": now.Add(10 * time.Minute).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(nil, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("baton-azure-devops: error signing client assertion: %w", err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
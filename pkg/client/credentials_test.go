@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeTokenEndpoint serves access tokens the way the Microsoft Entra token endpoint does, numbering every token it
+// issues. The form of every token request is passed to check.
+func newFakeTokenEndpoint(t *testing.T, check func(form map[string]string)) (*httptest.Server, *int32) {
+	var issued int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/tenant/oauth2/v2.0/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+
+		form := map[string]string{}
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+		check(form)
+
+		if form["client_secret"] == "wrong" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"AADSTS7000215: Invalid client secret provided."}`))
+			return
+		}
+
+		n := atomic.AddInt32(&issued, 1)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"access_token": fmt.Sprintf("token-%d", n),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &issued
+}
+
+func TestClientSecretCredentials(t *testing.T) {
+	ctx := context.Background()
+	server, issued := newFakeTokenEndpoint(t, func(form map[string]string) {
+		assert.Equal(t, "client_credentials", form["grant_type"])
+		assert.Equal(t, "client", form["client_id"])
+		assert.Equal(t, azureDevOpsScope, form["scope"])
+	})
+
+	now := time.Now()
+	credentials, err := NewClientSecretCredentials("tenant", "client", "secret", WithAuthorityHost(server.URL))
+	require.NoError(t, err)
+	credentials.(*entraCredentials).now = func() time.Time { return now }
+
+	authorization, err := credentials.Authorization(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", authorization)
+
+	t.Run("cached until shortly before expiry", func(t *testing.T) {
+		now = now.Add(50 * time.Minute)
+		authorization, err := credentials.Authorization(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token-1", authorization)
+		assert.Equal(t, int32(1), atomic.LoadInt32(issued))
+	})
+
+	t.Run("refreshed before expiry", func(t *testing.T) {
+		now = now.Add(6 * time.Minute)
+		authorization, err := credentials.Authorization(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer token-2", authorization)
+		assert.Equal(t, int32(2), atomic.LoadInt32(issued))
+	})
+
+	t.Run("rejected secret", func(t *testing.T) {
+		credentials, err := NewClientSecretCredentials("tenant", "client", "wrong", WithAuthorityHost(server.URL))
+		require.NoError(t, err)
+		_, err = credentials.Authorization(ctx)
+		assert.ErrorContains(t, err, "invalid_client")
+	})
+}
+
+func TestClientCertificateCredentials(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "baton-azure-devops"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	require.NoError(t, err)
+	certificatePEM := append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})...,
+	)
+
+	var server *httptest.Server
+	server, _ = newFakeTokenEndpoint(t, func(form map[string]string) {
+		assert.Empty(t, form["client_secret"])
+		assert.Equal(t, clientAssertionType, form["client_assertion_type"])
+
+		parts := strings.Split(form["client_assertion"], ".")
+		require.Len(t, parts, 3)
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		require.NoError(t, err)
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		assert.NoError(t, rsa.VerifyPKCS1v15(&privateKey.PublicKey, crypto.SHA256, digest[:], signature))
+
+		rawClaims, err := base64.RawURLEncoding.DecodeString(parts[1])
+		require.NoError(t, err)
+		var claims map[string]interface{}
+		require.NoError(t, json.Unmarshal(rawClaims, &claims))
+		assert.Equal(t, "client", claims["iss"])
+		assert.Equal(t, "client", claims["sub"])
+		assert.Equal(t, server.URL+"/tenant/oauth2/v2.0/token", claims["aud"])
+	})
+
+	credentials, err := NewClientCertificateCredentials("tenant", "client", certificatePEM, WithAuthorityHost(server.URL))
+	require.NoError(t, err)
+
+	authorization, err := credentials.Authorization(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", authorization)
+
+	_, err = NewClientCertificateCredentials("tenant", "client", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	assert.ErrorContains(t, err, "no unencrypted private key")
+}
+
+func TestWorkloadIdentityCredentials(t *testing.T) {
+	ctx := context.Background()
+	tokenFilePath := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFilePath, []byte("service-account-token-1\n"), 0o600))
+
+	var assertions []string
+	server, _ := newFakeTokenEndpoint(t, func(form map[string]string) {
+		assert.Empty(t, form["client_secret"])
+		assert.Equal(t, clientAssertionType, form["client_assertion_type"])
+		assertions = append(assertions, form["client_assertion"])
+	})
+
+	now := time.Now()
+	credentials, err := NewWorkloadIdentityCredentials("tenant", "client", tokenFilePath, WithAuthorityHost(server.URL))
+	require.NoError(t, err)
+	credentials.(*entraCredentials).now = func() time.Time { return now }
+
+	authorization, err := credentials.Authorization(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", authorization)
+
+	// The projected token is rotated before the access token expires.
+	require.NoError(t, os.WriteFile(tokenFilePath, []byte("service-account-token-2"), 0o600))
+	now = now.Add(time.Hour)
+
+	authorization, err = credentials.Authorization(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token-2", authorization)
+	assert.Equal(t, []string{"service-account-token-1", "service-account-token-2"}, assertions)
+
+	t.Run("missing token file", func(t *testing.T) {
+		credentials, err := NewWorkloadIdentityCredentials("tenant", "client", filepath.Join(t.TempDir(), "missing"), WithAuthorityHost(server.URL))
+		require.NoError(t, err)
+		_, err = credentials.Authorization(ctx)
+		assert.ErrorContains(t, err, "error reading federated token file")
+	})
+}
+
+func TestAuthorizingTransport(t *testing.T) {
+	tokenServer, _ := newFakeTokenEndpoint(t, func(map[string]string) {})
+	credentials, err := NewClientSecretCredentials("tenant", "client", "secret", WithAuthorityHost(tokenServer.URL))
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	connection := newConnection(server.URL, credentials)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	resp, err := connection.httpClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
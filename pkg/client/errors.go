@@ -0,0 +1,145 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors of failed Azure DevOps API requests. They are classified by the status code and the exception type of the
+// response rather than by its message, which Microsoft may reword or localize.
+var (
+	ErrNotFound      = errors.New("baton-azure-devops: not found")
+	ErrAlreadyExists = errors.New("baton-azure-devops: already exists")
+	ErrUnauthorized  = errors.New("baton-azure-devops: unauthorized")
+	ErrForbidden     = errors.New("baton-azure-devops: forbidden")
+	ErrThrottled     = errors.New("baton-azure-devops: throttled")
+	ErrConflict      = errors.New("baton-azure-devops: conflict")
+)
+
+// APIError is a failed Azure DevOps API request. It matches the error it was classified as with errors.Is, and carries
+// the matching gRPC status code for the baton-sdk.
+type APIError struct {
+	StatusCode int
+	// TypeKey is the exception type of the response, or of its innermost exception that has one, e.g.
+	// GraphMembershipNotFoundException.
+	TypeKey string
+
+	kind error
+	err  error
+}
+
+func (e *APIError) Error() string {
+	return e.err.Error()
+}
+
+func (e *APIError) Unwrap() []error {
+	if e.kind == nil {
+		return []error{e.err}
+	}
+
+	return []error{e.kind, e.err}
+}
+
+// GRPCStatus returns the gRPC status of the error, which the baton-sdk reports to decide whether to retry.
+func (e *APIError) GRPCStatus() *status.Status {
+	code := codes.Unknown
+	switch e.kind {
+	case ErrNotFound:
+		code = codes.NotFound
+	case ErrAlreadyExists:
+		code = codes.AlreadyExists
+	case ErrUnauthorized:
+		code = codes.Unauthenticated
+	case ErrForbidden:
+		code = codes.PermissionDenied
+	case ErrThrottled:
+		code = codes.Unavailable
+	case ErrConflict:
+		code = codes.Aborted
+	}
+
+	return status.New(code, e.Error())
+}
+
+// wrapError classifies an error returned by the Azure DevOps sdk. Other errors are returned as is.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return err
+	}
+
+	wrappedError, ok := asWrappedError(err)
+	if !ok {
+		return err
+	}
+
+	apiError = &APIError{err: err}
+	if wrappedError.StatusCode != nil {
+		apiError.StatusCode = *wrappedError.StatusCode
+	}
+
+	var typeKeys []string
+	for exception := wrappedError; exception != nil; exception = exception.InnerError {
+		if exception.TypeKey != nil && *exception.TypeKey != "" {
+			typeKeys = append(typeKeys, *exception.TypeKey)
+		}
+	}
+	if len(typeKeys) > 0 {
+		apiError.TypeKey = typeKeys[len(typeKeys)-1]
+	}
+	apiError.kind = classifyError(apiError.StatusCode, typeKeys)
+
+	return apiError
+}
+
+// classifyError classifies a failed request by its exception types, which are more specific than its status code:
+// a missing identity is reported with 400 Bad Request and an existing group with 409 Conflict.
+func classifyError(statusCode int, typeKeys []string) error {
+	for _, typeKey := range typeKeys {
+		switch {
+		case strings.HasSuffix(typeKey, "NotFoundException"), strings.Contains(typeKey, "DoesNotExist"):
+			return ErrNotFound
+		case strings.Contains(typeKey, "AlreadyExists"):
+			return ErrAlreadyExists
+		}
+	}
+
+	switch statusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusTooManyRequests:
+		return ErrThrottled
+	case http.StatusConflict:
+		return ErrConflict
+	default:
+		return nil
+	}
+}
+
+// asWrappedError returns the WrappedError of an error. The sdk returns it both by value and by pointer.
+func asWrappedError(err error) (*azuredevops.WrappedError, bool) {
+	var wrappedError azuredevops.WrappedError
+	if errors.As(err, &wrappedError) {
+		return &wrappedError, true
+	}
+
+	var wrappedErrorPtr *azuredevops.WrappedError
+	if errors.As(err, &wrappedErrorPtr) && wrappedErrorPtr != nil {
+		return wrappedErrorPtr, true
+	}
+
+	return nil, false
+}
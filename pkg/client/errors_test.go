@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapError(t *testing.T) {
+	wrappedError := func(statusCode int, typeKey string) azuredevops.WrappedError {
+		message := "localized message"
+		return azuredevops.WrappedError{Message: &message, StatusCode: &statusCode, TypeKey: &typeKey}
+	}
+	withInnerError := func(outer, inner azuredevops.WrappedError) *azuredevops.WrappedError {
+		outer.InnerError = &inner
+		return &outer
+	}
+
+	testCases := []struct {
+		name string
+		err  error
+		kind error
+		code codes.Code
+	}{
+		{
+			name: "missing membership",
+			err:  wrappedError(http.StatusNotFound, "GraphMembershipNotFoundException"),
+			kind: ErrNotFound,
+			code: codes.NotFound,
+		},
+		{
+			name: "missing identity reported as a bad request",
+			err:  wrappedError(http.StatusBadRequest, "IdentityNotFoundException"),
+			kind: ErrNotFound,
+			code: codes.NotFound,
+		},
+		{
+			name: "missing project",
+			err:  wrappedError(http.StatusNotFound, "ProjectDoesNotExistWithNameException"),
+			kind: ErrNotFound,
+			code: codes.NotFound,
+		},
+		{
+			name: "existing group reported as a conflict",
+			err:  wrappedError(http.StatusConflict, "GroupAlreadyExistsException"),
+			kind: ErrAlreadyExists,
+			code: codes.AlreadyExists,
+		},
+		{
+			name: "inner exception",
+			err:  fmt.Errorf("wrapped: %w", withInnerError(wrappedError(http.StatusBadRequest, "VssServiceException"), wrappedError(0, "TeamNotFoundException"))),
+			kind: ErrNotFound,
+			code: codes.NotFound,
+		},
+		{
+			name: "conflict",
+			err:  wrappedError(http.StatusConflict, ""),
+			kind: ErrConflict,
+			code: codes.Aborted,
+		},
+		{
+			name: "unauthorized",
+			err:  wrappedError(http.StatusUnauthorized, ""),
+			kind: ErrUnauthorized,
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "forbidden",
+			err:  wrappedError(http.StatusForbidden, "UnauthorizedRequestException"),
+			kind: ErrForbidden,
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "throttled",
+			err:  wrappedError(http.StatusTooManyRequests, ""),
+			kind: ErrThrottled,
+			code: codes.Unavailable,
+		},
+		{
+			name: "server error",
+			err:  wrappedError(http.StatusInternalServerError, ""),
+			code: codes.Unknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := wrapError(tc.err)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.err.Error(), err.Error())
+			if tc.kind != nil {
+				assert.ErrorIs(t, err, tc.kind)
+			}
+			for _, kind := range []error{ErrNotFound, ErrAlreadyExists, ErrUnauthorized, ErrForbidden, ErrThrottled, ErrConflict} {
+				if kind != tc.kind {
+					assert.NotErrorIs(t, err, kind)
+				}
+			}
+			assert.Equal(t, tc.code, status.Code(fmt.Errorf("baton-azure-devops: %w", err)))
+		})
+	}
+
+	t.Run("other errors are returned as is", func(t *testing.T) {
+		err := errors.New("connection refused")
+		assert.Equal(t, err, wrapError(err))
+		assert.NoError(t, wrapError(nil))
+	})
+
+	t.Run("errors are classified once", func(t *testing.T) {
+		err := fmt.Errorf("wrapped: %w", wrapError(wrappedError(http.StatusNotFound, "")))
+		assert.Equal(t, err, wrapError(err))
+	})
+}
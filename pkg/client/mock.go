@@ -3,10 +3,18 @@ package client
 import (
 	"context"
 
+	"github.com/conductorone/baton-azure-devops/pkg/client/securityroles"
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
 	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -29,12 +37,122 @@ func (m *MockAzureClient) CreateMembership(ctx context.Context, teamDescriptor,
 	return args.Get(0).(*graph.GraphMembership), args.Error(1)
 }
 
-func (m *MockAzureClient) ListTeams(ctx context.Context) ([]core.WebApiTeam, error) {
+func (m *MockAzureClient) GetMembership(ctx context.Context, containerDescriptor, memberDescriptor string) (*graph.GraphMembership, error) {
+	args := m.Called(ctx, containerDescriptor, memberDescriptor)
+	return args.Get(0).(*graph.GraphMembership), args.Error(1)
+}
+
+func (m *MockAzureClient) ListTeams(ctx context.Context, nextPageToken string) ([]core.WebApiTeam, string, error) {
+	args := m.Called(ctx, nextPageToken)
+	return args.Get(0).([]core.WebApiTeam), args.String(1), args.Error(2)
+}
+
+func (m *MockAzureClient) ListProjectTeams(ctx context.Context, projectId, nextPageToken string) ([]core.WebApiTeam, string, error) {
+	args := m.Called(ctx, projectId, nextPageToken)
+	return args.Get(0).([]core.WebApiTeam), args.String(1), args.Error(2)
+}
+
+func (m *MockAzureClient) ListTeamMembers(ctx context.Context, projectId, teamId, nextPageToken string) ([]webapi.TeamMember, string, error) {
+	args := m.Called(ctx, projectId, teamId, nextPageToken)
+	return args.Get(0).([]webapi.TeamMember), args.String(1), args.Error(2)
+}
+
+func (m *MockAzureClient) GetIdentityDescriptor(ctx context.Context, subjectDescriptor string) (string, error) {
+	args := m.Called(ctx, subjectDescriptor)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAzureClient) GetAccessControlEntry(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string) (*security.AccessControlEntry, error) {
+	args := m.Called(ctx, securityNamespaceId, token, descriptor)
+	return args.Get(0).(*security.AccessControlEntry), args.Error(1)
+}
+
+func (m *MockAzureClient) SetAccessControlEntry(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string, allow, deny int) error {
+	args := m.Called(ctx, securityNamespaceId, token, descriptor, allow, deny)
+	return args.Error(0)
+}
+
+func (m *MockAzureClient) RemovePermission(ctx context.Context, securityNamespaceId uuid.UUID, token, descriptor string, permissions int) error {
+	args := m.Called(ctx, securityNamespaceId, token, descriptor, permissions)
+	return args.Error(0)
+}
+
+func (m *MockAzureClient) GetStorageKey(ctx context.Context, descriptor string) (uuid.UUID, error) {
+	args := m.Called(ctx, descriptor)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockAzureClient) ListRoleAssignments(ctx context.Context, scopeID, resourceID string) ([]securityroles.RoleAssignment, error) {
+	args := m.Called(ctx, scopeID, resourceID)
+	return args.Get(0).([]securityroles.RoleAssignment), args.Error(1)
+}
+
+func (m *MockAzureClient) SetRoleAssignment(ctx context.Context, scopeID, resourceID, identityID, roleName string) error {
+	args := m.Called(ctx, scopeID, resourceID, identityID, roleName)
+	return args.Error(0)
+}
+
+func (m *MockAzureClient) RemoveRoleAssignment(ctx context.Context, scopeID, resourceID, identityID string) error {
+	args := m.Called(ctx, scopeID, resourceID, identityID)
+	return args.Error(0)
+}
+
+func (m *MockAzureClient) ListTeamIDs(ctx context.Context) (map[string]bool, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *MockAzureClient) ReadIdentitiesByDescriptors(ctx context.Context, descriptors []string) ([]identity.Identity, error) {
+	args := m.Called(ctx, descriptors)
+	return args.Get(0).([]identity.Identity), args.Error(1)
+}
+
+func (m *MockAzureClient) ReadIdentitiesByIDs(ctx context.Context, identityIDs []string) ([]identity.Identity, error) {
+	args := m.Called(ctx, identityIDs)
+	return args.Get(0).([]identity.Identity), args.Error(1)
+}
+
+func (m *MockAzureClient) GetPipelinePermissions(ctx context.Context, projectID, resourceType, resourceID string) (*pipelinepermissions.ResourcePipelinePermissions, error) {
+	args := m.Called(ctx, projectID, resourceType, resourceID)
+	return args.Get(0).(*pipelinepermissions.ResourcePipelinePermissions), args.Error(1)
+}
+
+func (m *MockAzureClient) UpdatePipelinePermissions(ctx context.Context, projectID, resourceType, resourceID string, permissions *pipelinepermissions.ResourcePipelinePermissions) error {
+	args := m.Called(ctx, projectID, resourceType, resourceID, permissions)
+	return args.Error(0)
+}
+
+func (m *MockAzureClient) ListAccessLevels(ctx context.Context) ([]licensing.AccessLevel, error) {
 	args := m.Called(ctx)
-	return args.Get(0).([]core.WebApiTeam), args.Error(1)
+	return args.Get(0).([]licensing.AccessLevel), args.Error(1)
+}
+
+func (m *MockAzureClient) ListUsersByAccessLevel(ctx context.Context, licenseID, nextContinuationToken string) ([]userentitlement.UserEntitlement, string, error) {
+	args := m.Called(ctx, licenseID, nextContinuationToken)
+	return args.Get(0).([]userentitlement.UserEntitlement), args.String(1), args.Error(2)
+}
+
+func (m *MockAzureClient) GetUserEntitlement(ctx context.Context, userID uuid.UUID) (*userentitlement.UserEntitlement, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(*userentitlement.UserEntitlement), args.Error(1)
+}
+
+func (m *MockAzureClient) UpdateUserEntitlement(ctx context.Context, userID uuid.UUID, document []webapi.JsonPatchOperation) (*userentitlement.UserEntitlement, error) {
+	args := m.Called(ctx, userID, document)
+	return args.Get(0).(*userentitlement.UserEntitlement), args.Error(1)
+}
+
+func (m *MockAzureClient) ListIdentities(ctx context.Context, identityIDs string, descriptors string) ([]identity.Identity, error) {
+	args := m.Called(ctx, identityIDs, descriptors)
+	return args.Get(0).([]identity.Identity), args.Error(1)
+}
+
+func (m *MockAzureClient) GetRootClassificationNodeID(ctx context.Context, projectID string, structureGroup workitemtracking.TreeStructureGroup) (uuid.UUID, error) {
+	args := m.Called(ctx, projectID, structureGroup)
+	return args.Get(0).(uuid.UUID), args.Error(1)
 }
 
-func (m *MockAzureClient) ListTeamMembers(ctx context.Context, projectId, teamId string) ([]webapi.TeamMember, error) {
-	args := m.Called(ctx, projectId, teamId)
-	return args.Get(0).([]webapi.TeamMember), args.Error(1)
+func (m *MockAzureClient) GetBuildDefinition(ctx context.Context, projectID string, definitionID int) (*build.BuildDefinitionReference, error) {
+	args := m.Called(ctx, projectID, definitionID)
+	return args.Get(0).(*build.BuildDefinitionReference), args.Error(1)
 }
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/accounts"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/profile"
+)
+
+// accountsUrl serves the profile and accounts APIs of Azure DevOps Services, which are not bound to an organization.
+const accountsUrl = "https://app.vssps.visualstudio.com"
+
+var errNoOrganizations = errors.New("baton-azure-devops: the authenticated identity is not a member of any organization")
+
+// OrganizationName returns the name of an organization from its url: the first path segment of a dev.azure.com url,
+// the subdomain of a visualstudio.com url, or the collection name of an Azure DevOps Server collection url.
+func OrganizationName(organizationUrl string) (string, error) {
+	parsedUrl, err := parseOrganizationUrl(organizationUrl)
+	if err != nil {
+		return "", err
+	}
+
+	parsed, err := url.Parse(parsedUrl)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidOrganizationUrl, err)
+	}
+
+	if name := path.Base(parsed.Path); name != "." && name != "/" {
+		return name, nil
+	}
+
+	name, _, _ := strings.Cut(parsed.Hostname(), ".")
+	return name, nil
+}
+
+// DiscoverOrganizations returns the urls of the Azure DevOps Services organizations the authenticated identity is a
+// member of, through the profile and accounts APIs.
+func DiscoverOrganizations(ctx context.Context, credentials Credentials) ([]string, error) {
+	return discoverOrganizations(ctx, credentials, accountsUrl)
+}
+
+func discoverOrganizations(ctx context.Context, credentials Credentials, baseUrl string) ([]string, error) {
+	connection := newConnection(baseUrl, credentials)
+	baseClient := connection.clientByUrl(baseUrl)
+
+	me := "me"
+	self, err := (&profile.ClientImpl{Client: *baseClient}).GetProfile(ctx, profile.GetProfileArgs{Id: &me})
+	if err != nil {
+		return nil, fmt.Errorf("baton-azure-devops: error reading the profile of the authenticated identity: %w", err)
+	}
+	if self.Id == nil {
+		return nil, errors.New("baton-azure-devops: the profile of the authenticated identity has no id")
+	}
+
+	members, err := (&accounts.ClientImpl{Client: *baseClient}).GetAccounts(ctx, accounts.GetAccountsArgs{MemberId: self.Id})
+	if err != nil {
+		return nil, fmt.Errorf("baton-azure-devops: error listing the organizations of the authenticated identity: %w", err)
+	}
+
+	if members == nil {
+		return nil, errNoOrganizations
+	}
+
+	var organizationUrls []string
+	for _, account := range *members {
+		if account.AccountName == nil || *account.AccountName == "" {
+			continue
+		}
+		organizationUrls = append(organizationUrls, "https://dev.azure.com/"+*account.AccountName)
+	}
+
+	if len(organizationUrls) == 0 {
+		return nil, errNoOrganizations
+	}
+
+	return organizationUrls, nil
+}
@@ -0,0 +1,30 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationName(t *testing.T) {
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{url: "https://dev.azure.com/contoso/", expected: "contoso"},
+		{url: "https://contoso.visualstudio.com", expected: "contoso"},
+		{url: "https://tfs.example.com/tfs/DefaultCollection", expected: "DefaultCollection"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.url, func(t *testing.T) {
+			name, err := OrganizationName(tc.url)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, name)
+		})
+	}
+
+	_, err := OrganizationName("contoso")
+	assert.ErrorIs(t, err, errInvalidOrganizationUrl)
+}
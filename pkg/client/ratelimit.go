@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// maxThrottledRetries is the number of times a throttled request is retried before its response is returned.
+	maxThrottledRetries = 5
+	// initialRetryDelay is the delay before the first retry of a throttled request without a Retry-After header.
+	initialRetryDelay = time.Second
+	// maxRetryDelay caps the delay before a retry.
+	maxRetryDelay = time.Minute
+	// lowRateLimitBudget is the share of the TSTU budget under which requests are slowed down.
+	lowRateLimitBudget = 0.1
+	// maxSlowDownDelay caps the delay requests are slowed down by while the budget is low.
+	maxSlowDownDelay = 10 * time.Second
+)
+
+// rateLimitTransport honors the rate limits of Azure DevOps, which throttles the identities that consume too many
+// throughput units (TSTUs). Throttled requests are delayed and retried with backoff, and requests are slowed down
+// while the remaining budget is low or after Azure DevOps delayed a request, before they get throttled. See
+// https://learn.microsoft.com/en-us/azure/devops/integrate/concepts/rate-limits.
+type rateLimitTransport struct {
+	base  http.RoundTripper
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mutex     sync.Mutex
+	limit     float64
+	remaining float64
+	resetAt   time.Time
+	notBefore time.Time
+	throttled bool
+}
+
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:  base,
+		now:   time.Now,
+		sleep: sleepContext,
+	}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.sleep(ctx, t.slowDownDelay()); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		retryAfter := t.observe(resp)
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= maxThrottledRetries {
+			return resp, nil
+		}
+
+		retry := req
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			retry = req.Clone(ctx)
+			retry.Body, err = req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		delay := retryAfter
+		if delay <= 0 {
+			delay = initialRetryDelay << attempt
+		}
+		delay = min(delay, maxRetryDelay)
+
+		ctxzap.Extract(ctx).Debug(fmt.Sprintf("baton-azure-devops: %s was throttled, retrying in %s", req.URL.Path, delay))
+		if err := t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		req = retry
+	}
+}
+
+// observe records the rate limit headers of a response, and returns how long Azure DevOps asked to wait before the
+// next request.
+func (t *rateLimitTransport) observe(resp *http.Response) time.Duration {
+	now := t.now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.throttled = resp.StatusCode == http.StatusTooManyRequests
+	if limit, ok := parseFloatHeader(resp.Header, "X-RateLimit-Limit"); ok {
+		t.limit = limit
+	}
+	if remaining, ok := parseFloatHeader(resp.Header, "X-RateLimit-Remaining"); ok {
+		t.remaining = remaining
+	}
+	if reset, ok := parseFloatHeader(resp.Header, "X-RateLimit-Reset"); ok {
+		t.resetAt = time.Unix(int64(reset), 0)
+	}
+
+	var wait time.Duration
+	if delay, ok := parseFloatHeader(resp.Header, "X-RateLimit-Delay"); ok && delay > 0 {
+		wait = time.Duration(delay * float64(time.Second))
+	}
+	if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"), now); retryAfter > wait {
+		wait = retryAfter
+	}
+	if wait > 0 {
+		t.notBefore = now.Add(min(wait, maxRetryDelay))
+	}
+
+	return wait
+}
+
+// slowDownDelay returns how long to wait before sending a request: until the time Azure DevOps asked to wait for, or
+// a share of the time left until the budget resets while the remaining budget is low.
+func (t *rateLimitTransport) slowDownDelay() time.Duration {
+	now := t.now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if now.Before(t.notBefore) {
+		return t.notBefore.Sub(now)
+	}
+
+	if t.limit <= 0 || t.remaining/t.limit >= lowRateLimitBudget || !now.Before(t.resetAt) {
+		return 0
+	}
+
+	return min(time.Duration(float64(t.resetAt.Sub(now))/(max(t.remaining, 0)+1)), maxSlowDownDelay)
+}
+
+// description returns the rate limit state of the last response, or nil when Azure DevOps reported none.
+func (t *rateLimitTransport) description() *v2.RateLimitDescription {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.limit <= 0 && !t.throttled {
+		return nil
+	}
+
+	status := v2.RateLimitDescription_STATUS_OK
+	if t.throttled || (t.limit > 0 && t.remaining <= 0) {
+		status = v2.RateLimitDescription_STATUS_OVERLIMIT
+	}
+
+	description := &v2.RateLimitDescription{
+		Status:    status,
+		Limit:     int64(t.limit),
+		Remaining: int64(t.remaining),
+	}
+	if resetAt := t.resetAt; !resetAt.IsZero() || !t.notBefore.IsZero() {
+		if t.notBefore.After(resetAt) {
+			resetAt = t.notBefore
+		}
+		description.ResetAt = timestamppb.New(resetAt)
+	}
+
+	return description
+}
+
+func parseFloatHeader(header http.Header, key string) (float64, bool) {
+	value := header.Get(key)
+	if value == "" {
+		return 0, false
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return parsed, true
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now)
+	}
+
+	return 0
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeRateLimitTransport returns a rate limit transport on a fake clock, which sleeping advances, and the delays it
+// slept for.
+func newFakeRateLimitTransport() (*rateLimitTransport, *[]time.Duration) {
+	now := time.Unix(1700000000, 0)
+	var sleeps []time.Duration
+
+	transport := newRateLimitTransport(http.DefaultTransport)
+	transport.now = func() time.Time { return now }
+	transport.sleep = func(_ context.Context, d time.Duration) error {
+		if d > 0 {
+			sleeps = append(sleeps, d)
+			now = now.Add(d)
+		}
+		return nil
+	}
+
+	return transport, &sleeps
+}
+
+func TestRateLimitTransport(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("throttled requests are retried after Retry-After", func(t *testing.T) {
+		var bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			bodies = append(bodies, string(body))
+
+			if len(bodies) < 3 {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		transport, sleeps := newFakeRateLimitTransport()
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL, strings.NewReader("body"))
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+		assert.Equal(t, []string{"body", "body", "body"}, bodies)
+		assert.Equal(t, []time.Duration{7 * time.Second, 7 * time.Second}, *sleeps)
+		assert.Nil(t, transport.description())
+	})
+
+	t.Run("throttled requests without Retry-After back off", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		transport, sleeps := newFakeRateLimitTransport()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+		assert.Equal(t, maxThrottledRetries+1, requests)
+		assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second}, *sleeps)
+
+		description := transport.description()
+		require.NotNil(t, description)
+		assert.Equal(t, v2.RateLimitDescription_STATUS_OVERLIMIT, description.Status)
+	})
+
+	t.Run("requests slow down while the budget is low", func(t *testing.T) {
+		transport, sleeps := newFakeRateLimitTransport()
+		reset := transport.now().Add(40 * time.Second)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Limit", "200")
+			w.Header().Set("X-RateLimit-Remaining", "4")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		for range 2 {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		// The time left until the reset is shared by the remaining budget.
+		assert.Equal(t, []time.Duration{8 * time.Second}, *sleeps)
+
+		description := transport.description()
+		require.NotNil(t, description)
+		assert.Equal(t, v2.RateLimitDescription_STATUS_OK, description.Status)
+		assert.Equal(t, int64(200), description.Limit)
+		assert.Equal(t, int64(4), description.Remaining)
+		assert.Equal(t, reset.Unix(), description.ResetAt.AsTime().Unix())
+	})
+
+	t.Run("requests wait for X-RateLimit-Delay", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Delay", "2.5")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		transport, sleeps := newFakeRateLimitTransport()
+		for range 2 {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			require.NoError(t, err)
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+		}
+
+		assert.Equal(t, []time.Duration{2500 * time.Millisecond}, *sleeps)
+		assert.Nil(t, transport.description())
+	})
+
+	t.Run("waiting is canceled with the context", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "30")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		transport := newRateLimitTransport(http.DefaultTransport)
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		_, err = transport.RoundTrip(req)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter(now.Add(90*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
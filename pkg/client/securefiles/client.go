@@ -0,0 +1,66 @@
+package securefiles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+const apiVersion = "7.1-preview.1"
+
+type Client interface {
+	// [Preview API] Get the secure files of a project
+	GetSecureFiles(context.Context, GetSecureFilesArgs) (*[]SecureFile, error)
+}
+
+type ClientImpl struct {
+	Client  azuredevops.Client
+	baseUrl string
+}
+
+func NewClient(_ context.Context, connection *azuredevops.Connection, options ...azuredevops.ClientOptionFunc) (Client, error) {
+	client := connection.GetClientByUrl(connection.BaseUrl)
+	if len(options) > 0 {
+		client = azuredevops.NewClientWithOptions(connection, connection.BaseUrl, options...)
+	}
+	return &ClientImpl{
+		Client:  *client,
+		baseUrl: strings.TrimSuffix(connection.BaseUrl, "/"),
+	}, nil
+}
+
+// [Preview API] Get the secure files of a project
+func (client *ClientImpl) GetSecureFiles(ctx context.Context, args GetSecureFilesArgs) (*[]SecureFile, error) {
+	if args.Project == nil || *args.Project == "" {
+		return nil, &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.Project"}
+	}
+
+	// The secure files routes are not part of the published task agent client, so the request is sent to the fixed
+	// route relative to the organization url.
+	requestUrl := fmt.Sprintf("%s/%s/_apis/distributedtask/securefiles", client.baseUrl, url.PathEscape(*args.Project))
+	req, err := client.Client.CreateRequestMessage(ctx, http.MethodGet, requestUrl, apiVersion, bytes.NewReader(nil), "", "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Client.SendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var responseValue []SecureFile
+	err = client.Client.UnmarshalCollectionBody(resp, &responseValue)
+	return &responseValue, err
+}
+
+// Arguments for the GetSecureFiles function
+type GetSecureFilesArgs struct {
+	// (required) Project ID or project name
+	Project *string
+}
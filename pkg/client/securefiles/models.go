@@ -0,0 +1,25 @@
+package securefiles
+
+import (
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+)
+
+// A file stored in the pipeline Library that pipelines can consume without exposing its content.
+type SecureFile struct {
+	// The identity who created the secure file.
+	CreatedBy *webapi.IdentityRef `json:"createdBy,omitempty"`
+	// The date the secure file was created.
+	CreatedOn *azuredevops.Time `json:"createdOn,omitempty"`
+	// The unique identifier of the secure file.
+	Id *uuid.UUID `json:"id,omitempty"`
+	// The identity who last modified the secure file.
+	ModifiedBy *webapi.IdentityRef `json:"modifiedBy,omitempty"`
+	// The date the secure file was last modified.
+	ModifiedOn *azuredevops.Time `json:"modifiedOn,omitempty"`
+	// The name of the secure file.
+	Name *string `json:"name,omitempty"`
+	// The properties of the secure file.
+	Properties *map[string]string `json:"properties,omitempty"`
+}
@@ -0,0 +1,191 @@
+package securityroles
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+const apiVersion = "7.1-preview.1"
+
+type Client interface {
+	// [Preview API] Get role assignments for the resource
+	GetRoleAssignments(context.Context, GetRoleAssignmentsArgs) (*[]RoleAssignment, error)
+	// [Preview API] Get role definitions of a scope
+	GetRoleDefinitions(context.Context, GetRoleDefinitionsArgs) (*[]SecurityRole, error)
+	// [Preview API] Remove role assignments of the resource
+	RemoveRoleAssignments(context.Context, RemoveRoleAssignmentsArgs) error
+	// [Preview API] Set role assignments on the resource
+	SetRoleAssignments(context.Context, SetRoleAssignmentsArgs) (*[]RoleAssignment, error)
+}
+
+type ClientImpl struct {
+	Client  azuredevops.Client
+	baseUrl string
+}
+
+func NewClient(_ context.Context, connection *azuredevops.Connection, options ...azuredevops.ClientOptionFunc) (Client, error) {
+	client := connection.GetClientByUrl(connection.BaseUrl)
+	if len(options) > 0 {
+		client = azuredevops.NewClientWithOptions(connection, connection.BaseUrl, options...)
+	}
+	return &ClientImpl{
+		Client:  *client,
+		baseUrl: strings.TrimSuffix(connection.BaseUrl, "/"),
+	}, nil
+}
+
+// The security roles area is not published in the resource locations of the organization, so requests are sent to
+// fixed routes relative to the organization url.
+func (client *ClientImpl) roleAssignmentsUrl(scopeId, resourceId string) string {
+	return fmt.Sprintf(
+		"%s/_apis/securityroles/scopes/%s/roleassignments/resources/%s",
+		client.baseUrl,
+		url.PathEscape(scopeId),
+		url.PathEscape(resourceId),
+	)
+}
+
+func (client *ClientImpl) send(ctx context.Context, httpMethod, requestUrl string, body interface{}) (*http.Response, error) {
+	var reader *bytes.Reader
+	mediaType := ""
+	if body != nil {
+		rawBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(rawBody)
+		mediaType = "application/json"
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := client.Client.CreateRequestMessage(ctx, httpMethod, requestUrl, apiVersion, reader, mediaType, "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Client.SendRequest(req)
+}
+
+// [Preview API] Get role assignments for the resource
+func (client *ClientImpl) GetRoleAssignments(ctx context.Context, args GetRoleAssignmentsArgs) (*[]RoleAssignment, error) {
+	if args.ScopeId == nil || *args.ScopeId == "" {
+		return nil, &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ScopeId"}
+	}
+	if args.ResourceId == nil || *args.ResourceId == "" {
+		return nil, &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ResourceId"}
+	}
+
+	resp, err := client.send(ctx, http.MethodGet, client.roleAssignmentsUrl(*args.ScopeId, *args.ResourceId), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var responseValue []RoleAssignment
+	err = client.Client.UnmarshalCollectionBody(resp, &responseValue)
+	return &responseValue, err
+}
+
+// Arguments for the GetRoleAssignments function
+type GetRoleAssignmentsArgs struct {
+	// (required) Id of the assigned scope
+	ScopeId *string
+	// (required) Id of the resource that is assigned the scope
+	ResourceId *string
+}
+
+// [Preview API] Get role definitions of a scope
+func (client *ClientImpl) GetRoleDefinitions(ctx context.Context, args GetRoleDefinitionsArgs) (*[]SecurityRole, error) {
+	if args.ScopeId == nil || *args.ScopeId == "" {
+		return nil, &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ScopeId"}
+	}
+
+	requestUrl := fmt.Sprintf("%s/_apis/securityroles/scopes/%s/roledefinitions", client.baseUrl, url.PathEscape(*args.ScopeId))
+	resp, err := client.send(ctx, http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var responseValue []SecurityRole
+	err = client.Client.UnmarshalCollectionBody(resp, &responseValue)
+	return &responseValue, err
+}
+
+// Arguments for the GetRoleDefinitions function
+type GetRoleDefinitionsArgs struct {
+	// (required) Id of the scope
+	ScopeId *string
+}
+
+// [Preview API] Remove role assignments of the resource
+func (client *ClientImpl) RemoveRoleAssignments(ctx context.Context, args RemoveRoleAssignmentsArgs) error {
+	if args.ScopeId == nil || *args.ScopeId == "" {
+		return &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ScopeId"}
+	}
+	if args.ResourceId == nil || *args.ResourceId == "" {
+		return &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ResourceId"}
+	}
+	if args.IdentityIds == nil {
+		return &azuredevops.ArgumentNilError{ArgumentName: "args.IdentityIds"}
+	}
+
+	resp, err := client.send(ctx, http.MethodPatch, client.roleAssignmentsUrl(*args.ScopeId, *args.ResourceId), *args.IdentityIds)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// Arguments for the RemoveRoleAssignments function
+type RemoveRoleAssignmentsArgs struct {
+	// (required) Id of the assigned scope
+	ScopeId *string
+	// (required) Id of the resource that is assigned the scope
+	ResourceId *string
+	// (required) Ids of the identities to remove the role assignments from
+	IdentityIds *[]string
+}
+
+// [Preview API] Set role assignments on the resource
+func (client *ClientImpl) SetRoleAssignments(ctx context.Context, args SetRoleAssignmentsArgs) (*[]RoleAssignment, error) {
+	if args.ScopeId == nil || *args.ScopeId == "" {
+		return nil, &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ScopeId"}
+	}
+	if args.ResourceId == nil || *args.ResourceId == "" {
+		return nil, &azuredevops.ArgumentNilOrEmptyError{ArgumentName: "args.ResourceId"}
+	}
+	if args.RoleAssignments == nil {
+		return nil, &azuredevops.ArgumentNilError{ArgumentName: "args.RoleAssignments"}
+	}
+
+	resp, err := client.send(ctx, http.MethodPut, client.roleAssignmentsUrl(*args.ScopeId, *args.ResourceId), *args.RoleAssignments)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var responseValue []RoleAssignment
+	err = client.Client.UnmarshalCollectionBody(resp, &responseValue)
+	return &responseValue, err
+}
+
+// Arguments for the SetRoleAssignments function
+type SetRoleAssignmentsArgs struct {
+	// (required) Id of the assigned scope
+	ScopeId *string
+	// (required) Id of the resource that is assigned the scope
+	ResourceId *string
+	// (required) Roles to be assigned
+	RoleAssignments *[]UserRoleAssignmentRef
+}
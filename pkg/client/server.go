@@ -0,0 +1,167 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+)
+
+// errServerUnsupported is returned by the member entitlement management API calls, which Azure DevOps Server does not
+// provide: its users come from Active Directory and their access levels are managed on the server.
+var errServerUnsupported = errors.New("baton-azure-devops: member entitlement management is not available on Azure DevOps Server")
+
+var (
+	apiVersionPattern         = regexp.MustCompile(`api-version=(\d+\.\d+)`)
+	supportedVersionPattern   = regexp.MustCompile(`latest REST API version this server supports is (\d+\.\d+)`)
+	versionOutOfRangeTypeKey  = []byte("VssVersionOutOfRangeException")
+	errInvalidOrganizationUrl = errors.New("baton-azure-devops: the organization url must be an absolute http or https url")
+)
+
+// parseOrganizationUrl validates the url of an Azure DevOps Services organization or of an Azure DevOps Server
+// collection. Collection urls keep their virtual directory, e.g. https://tfs.example.com/tfs/DefaultCollection.
+func parseOrganizationUrl(organizationUrl string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(organizationUrl))
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errInvalidOrganizationUrl, err)
+	}
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return "", fmt.Errorf("%w: %s", errInvalidOrganizationUrl, organizationUrl)
+	}
+
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	parsed.Path = strings.TrimRight(parsed.Path, "/")
+	parsed.RawPath = strings.TrimRight(parsed.RawPath, "/")
+
+	return parsed.String(), nil
+}
+
+// apiVersionTransport lowers the api-version of the requests an Azure DevOps Server rejects as too recent. The sdk
+// negotiates the version of the routes the server publishes in its resource locations, but the routes the connector
+// sends to fixed urls, such as the security roles, are requested with the version of Azure DevOps Services. Once the
+// server reported the latest version it supports, later requests are sent with that version right away.
+type apiVersionTransport struct {
+	base http.RoundTripper
+
+	mutex      sync.RWMutex
+	maxVersion *azuredevops.Version
+}
+
+func (t *apiVersionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = t.withSupportedVersion(req)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	matches := supportedVersionPattern.FindSubmatch(body)
+	if !bytes.Contains(body, versionOutOfRangeTypeKey) || matches == nil {
+		return resp, nil
+	}
+	maxVersion, err := azuredevops.NewVersion(string(matches[1]))
+	if err != nil {
+		return resp, nil //nolint:nilerr // the original response is returned when the supported version is unreadable.
+	}
+	t.mutex.Lock()
+	t.maxVersion = maxVersion
+	t.mutex.Unlock()
+
+	retry := t.withSupportedVersion(req)
+	if retry == req {
+		return resp, nil
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return resp, nil
+		}
+		retry.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctxzap.Extract(req.Context()).Debug(fmt.Sprintf("baton-azure-devops: retrying %s with api-version %s", req.URL.Path, maxVersion))
+
+	return t.base.RoundTrip(retry)
+}
+
+// withSupportedVersion returns a copy of the request with its api-version lowered to the latest version the server
+// supports, or the request itself when its version is supported.
+func (t *apiVersionTransport) withSupportedVersion(req *http.Request) *http.Request {
+	t.mutex.RLock()
+	maxVersion := t.maxVersion
+	t.mutex.RUnlock()
+	if maxVersion == nil {
+		return req
+	}
+
+	accept := req.Header.Get("Accept")
+	matches := apiVersionPattern.FindStringSubmatch(accept)
+	if matches == nil {
+		return req
+	}
+	requestedVersion, err := azuredevops.NewVersion(matches[1])
+	if err != nil || requestedVersion.CompareTo(*maxVersion) <= 0 {
+		return req
+	}
+
+	supported := req.Clone(req.Context())
+	supported.Header.Set("Accept", strings.Replace(accept, matches[0], "api-version="+maxVersion.String(), 1))
+
+	return supported
+}
+
+// listServerUsers lists the users of an Azure DevOps Server collection through the graph API, since the member
+// entitlement management API is only available on Azure DevOps Services. The users hold no access level.
+func (c *AzureDevOpsClient) listServerUsers(ctx context.Context, nextContinuationToken string) ([]userentitlement.UserEntitlement, string, error) {
+	l := ctxzap.Extract(ctx)
+
+	args := graph.ListUsersArgs{}
+	if nextContinuationToken != "" {
+		args.ContinuationToken = &nextContinuationToken
+	}
+
+	users, err := c.graphClient.ListUsers(ctx, args)
+	if err != nil {
+		l.Error(fmt.Sprintf("Error getting resources: %s", err))
+		return nil, "", wrapError(err)
+	}
+
+	var userEntitlements []userentitlement.UserEntitlement
+	if users.GraphUsers != nil {
+		for _, user := range *users.GraphUsers {
+			if user.Descriptor == nil {
+				continue
+			}
+			userCopy := user
+			userEntitlements = append(userEntitlements, userentitlement.UserEntitlement{User: &userCopy})
+		}
+	}
+
+	nextPageToken := ""
+	if users.ContinuationToken != nil && len(*users.ContinuationToken) > 0 {
+		nextPageToken = (*users.ContinuationToken)[0]
+	}
+
+	return userEntitlements, nextPageToken, nil
+}
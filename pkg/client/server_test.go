@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOrganizationUrl(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		expected string
+		invalid  bool
+	}{
+		{
+			name:     "azure devops services organization",
+			url:      "https://dev.azure.com/contoso/",
+			expected: "https://dev.azure.com/contoso",
+		},
+		{
+			name:     "azure devops server collection with a virtual directory",
+			url:      " https://tfs.example.com:8443/tfs/DefaultCollection/?view=1 ",
+			expected: "https://tfs.example.com:8443/tfs/DefaultCollection",
+		},
+		{
+			name:     "collection name with a space",
+			url:      "http://tfs/tfs/Default%20Collection",
+			expected: "http://tfs/tfs/Default%20Collection",
+		},
+		{
+			name:    "missing scheme",
+			url:     "dev.azure.com/contoso",
+			invalid: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			organizationUrl, err := parseOrganizationUrl(tc.url)
+			if tc.invalid {
+				assert.ErrorIs(t, err, errInvalidOrganizationUrl)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, organizationUrl)
+		})
+	}
+}
+
+func TestAPIVersionTransport(t *testing.T) {
+	var versions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept := r.Header.Get("Accept")
+		versions = append(versions, accept)
+		if strings.Contains(accept, "api-version=7.1") {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"The requested REST API version of 7.1 is out of range for this server. The latest REST API version this server supports is 7.0.","typeKey":"VssVersionOutOfRangeException"}`))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	httpClient := &http.Client{Transport: &apiVersionTransport{base: http.DefaultTransport}}
+	send := func(body string) string {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodPut, server.URL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Accept", "application/json;api-version=7.1-preview.1")
+
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		responseBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(responseBody)
+	}
+
+	assert.Equal(t, "first", send("first"))
+	assert.Equal(t, "second", send("second"))
+	assert.Equal(t, []string{
+		"application/json;api-version=7.1-preview.1",
+		"application/json;api-version=7.0-preview.1",
+		"application/json;api-version=7.0-preview.1",
+	}, versions)
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/git"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/serviceendpoint"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+)
+
+// apiProbe is a cheap request against an API area the connector depends on, with the PAT scope the area requires.
+type apiProbe struct {
+	area  string
+	scope string
+	// servicesOnly is set for the areas Azure DevOps Server does not provide.
+	servicesOnly bool
+	probe        func(ctx context.Context, projectID string) error
+}
+
+// Validate checks that the organization url and the credentials are valid, and that every API area the connector
+// depends on can be read. Errors name the PAT scope that is missing.
+func (c *AzureDevOpsClient) Validate(ctx context.Context) error {
+	top := 1
+	projects, err := c.coreClient.GetProjects(ctx, core.GetProjectsArgs{Top: &top})
+	if err != nil {
+		return describeAPIError(err, "projects", "vso.project")
+	}
+
+	projectID := ""
+	if len(projects.Value) > 0 && projects.Value[0].Id != nil {
+		projectID = projects.Value[0].Id.String()
+	}
+
+	for _, probe := range c.apiProbes() {
+		if probe.servicesOnly && c.Server {
+			continue
+		}
+		if err := probe.probe(ctx, projectID); err != nil {
+			return describeAPIError(err, probe.area, probe.scope)
+		}
+	}
+
+	return nil
+}
+
+// apiProbes returns a probe for every API area the connector reads. Probes that need a project are skipped when the
+// organization has none.
+func (c *AzureDevOpsClient) apiProbes() []apiProbe {
+	top := 1
+
+	return []apiProbe{
+		{
+			area:         "member entitlement management",
+			scope:        "vso.memberentitlementmanagement",
+			servicesOnly: true,
+			probe: func(ctx context.Context, _ string) error {
+				_, err := c.ListAccessLevels(ctx)
+				return err
+			},
+		},
+		{
+			area:  "graph",
+			scope: "vso.graph",
+			probe: func(ctx context.Context, _ string) error {
+				_, err := c.graphClient.ListGroups(ctx, graph.ListGroupsArgs{})
+				return err
+			},
+		},
+		{
+			area:  "identity",
+			scope: "vso.identity",
+			probe: func(ctx context.Context, _ string) error {
+				_, err := c.identityClient.GetSelf(ctx, identity.GetSelfArgs{})
+				return err
+			},
+		},
+		{
+			area:  "security",
+			scope: "vso.security",
+			probe: func(ctx context.Context, _ string) error {
+				_, err := c.securityClient.QuerySecurityNamespaces(ctx, security.QuerySecurityNamespacesArgs{})
+				return err
+			},
+		},
+		{
+			area:  "git",
+			scope: "vso.code",
+			probe: func(ctx context.Context, projectID string) error {
+				if projectID == "" {
+					return nil
+				}
+				_, err := c.gitClient.GetRepositories(ctx, git.GetRepositoriesArgs{Project: &projectID})
+				return err
+			},
+		},
+		{
+			area:  "build",
+			scope: "vso.build",
+			probe: func(ctx context.Context, projectID string) error {
+				if projectID == "" {
+					return nil
+				}
+				_, err := c.buildClient.GetDefinitions(ctx, build.GetDefinitionsArgs{Project: &projectID, Top: &top})
+				return err
+			},
+		},
+		{
+			area:  "service endpoint",
+			scope: "vso.serviceendpoint",
+			probe: func(ctx context.Context, projectID string) error {
+				if projectID == "" {
+					return nil
+				}
+				_, err := c.serviceEndpointClient.GetServiceEndpoints(ctx, serviceendpoint.GetServiceEndpointsArgs{Project: &projectID})
+				return err
+			},
+		},
+		{
+			area:  "agent pools",
+			scope: "vso.agentpools",
+			probe: func(ctx context.Context, _ string) error {
+				_, err := c.taskAgentClient.GetAgentPools(ctx, taskagent.GetAgentPoolsArgs{})
+				return err
+			},
+		},
+		{
+			area:  "variable groups",
+			scope: "vso.variablegroups_read",
+			probe: func(ctx context.Context, projectID string) error {
+				if projectID == "" {
+					return nil
+				}
+				_, err := c.taskAgentClient.GetVariableGroups(ctx, taskagent.GetVariableGroupsArgs{Project: &projectID, Top: &top})
+				return err
+			},
+		},
+	}
+}
+
+// describeAPIError turns a failed request into an actionable error message.
+func describeAPIError(err error, area, scope string) error {
+	err = wrapError(err)
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		return fmt.Errorf("baton-azure-devops: authentication failed, the personal access token or service principal credentials are invalid or expired: %w", err)
+	case errors.Is(err, ErrForbidden):
+		return fmt.Errorf("baton-azure-devops: the credentials are missing the %s scope required to read the %s API: %w", scope, area, err)
+	case errors.Is(err, ErrNotFound):
+		return fmt.Errorf("baton-azure-devops: the %s API was not found, check that the organization or collection url is correct: %w", area, err)
+	default:
+		return fmt.Errorf("baton-azure-devops: error reading the %s API: %w", area, err)
+	}
+}
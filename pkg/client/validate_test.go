@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDescribeAPIError(t *testing.T) {
+	wrappedError := func(status int) azuredevops.WrappedError {
+		message := http.StatusText(status)
+		return azuredevops.WrappedError{Message: &message, StatusCode: &status}
+	}
+	forbidden := wrappedError(http.StatusForbidden)
+
+	testCases := []struct {
+		name     string
+		err      error
+		contains string
+	}{
+		{
+			name:     "unauthorized",
+			err:      wrappedError(http.StatusUnauthorized),
+			contains: "invalid or expired",
+		},
+		{
+			name:     "forbidden names the missing scope",
+			err:      fmt.Errorf("wrapped: %w", &forbidden),
+			contains: "missing the vso.graph scope",
+		},
+		{
+			name:     "not found",
+			err:      wrappedError(http.StatusNotFound),
+			contains: "check that the organization or collection url is correct",
+		},
+		{
+			name:     "other errors",
+			err:      errors.New("connection refused"),
+			contains: "error reading the graph API",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := describeAPIError(tc.err, "graph", "vso.graph")
+			assert.ErrorContains(t, err, tc.contains)
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestValidateSignInPage(t *testing.T) {
+	for _, statusCode := range []int{http.StatusUnauthorized, http.StatusNonAuthoritativeInfo} {
+		t.Run(http.StatusText(statusCode), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.WriteHeader(statusCode)
+				_, _ = w.Write([]byte("<html><body>Sign in to your account</body></html>"))
+			}))
+			defer server.Close()
+
+			connection := newConnection(server.URL, NewPersonalAccessTokenCredentials("expired"))
+			c := &AzureDevOpsClient{coreClient: &core.ClientImpl{Client: *connection.clientByUrl(server.URL)}}
+
+			err := c.Validate(context.Background())
+			assert.ErrorIs(t, err, ErrUnauthorized)
+			assert.ErrorContains(t, err, "invalid or expired")
+
+			_, err = New(context.Background(), NewPersonalAccessTokenCredentials("expired"), server.URL, true, false, false)
+			assert.ErrorIs(t, err, ErrUnauthorized)
+			assert.ErrorContains(t, err, "invalid or expired")
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"go.uber.org/zap"
+)
+
+// stakeholderAccessLevelID is the free access level users are downgraded to when another access level is revoked.
+const stakeholderAccessLevelID = "Account-Stakeholder"
+
+var assignedPermission = "assigned"
+
+type accessLevelBuilder struct {
+	resourceType *v2.ResourceType
+	client       client.AzureDevOpsClientInterface
+}
+
+func (o *accessLevelBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return accessLevelResourceType
+}
+
+func (o *accessLevelBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	accessLevels, err := o.client.ListAccessLevels(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, accessLevel := range accessLevels {
+		accessLevelCopy := &accessLevel
+		accessLevelResource, err := parseIntoAccessLevelResource(accessLevelCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, accessLevelResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *accessLevelBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDescription(fmt.Sprintf("Assigned the %s access level", resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, assignedPermission)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(resource, assignedPermission, assigmentOptions...),
+	}, "", nil, nil
+}
+
+func (o *accessLevelBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var grants []*v2.Grant
+
+	users, nextPageToken, err := o.client.ListUsersByAccessLevel(ctx, resource.Id.Resource, pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, user := range users {
+		if user.User == nil || user.User.Descriptor == nil {
+			continue
+		}
+		userResourceId := &v2.ResourceId{
+			ResourceType: userResourceType.Id,
+			Resource:     *user.User.Descriptor,
+		}
+		grants = append(grants, grant.NewGrant(resource, assignedPermission, userResourceId))
+	}
+
+	return grants, nextPageToken, nil, nil
+}
+
+func (o *accessLevelBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	if principal.Id.ResourceType != userResourceType.Id {
+		return nil, fmt.Errorf("access levels can only be granted to users, not %s", principal.Id.ResourceType)
+	}
+
+	accessLevelID := entitlementResource.Resource.Id.Resource
+	accessLevel, err := parseAccessLevelID(accessLevelID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := o.client.GetStorageKey(ctx, principal.Id.Resource)
+	if err != nil {
+		l.Debug("Error getting user storage key", zap.Error(err))
+		return nil, err
+	}
+
+	userEntitlement, err := o.client.GetUserEntitlement(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userEntitlement.AccessLevel != nil && getAccessLevelID(userEntitlement.AccessLevel) == accessLevelID {
+		l.Info("User already has the access level; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	_, err = o.client.UpdateUserEntitlement(ctx, userID, accessLevelPatch(accessLevel))
+	if err != nil {
+		l.Debug("Error updating user access level", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// Revoke downgrades the user to the Stakeholder access level, since a user always holds exactly one access level.
+func (o *accessLevelBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	accessLevelID := grantResource.Entitlement.Resource.Id.Resource
+	if accessLevelID == stakeholderAccessLevelID {
+		return nil, fmt.Errorf("the %s access level cannot be revoked, it is the lowest access level a user can have", accessLevelID)
+	}
+
+	userID, err := o.client.GetStorageKey(ctx, grantResource.Principal.Id.Resource)
+	if err != nil {
+		l.Debug("Error getting user storage key", zap.Error(err))
+		return nil, err
+	}
+
+	userEntitlement, err := o.client.GetUserEntitlement(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userEntitlement.AccessLevel == nil || getAccessLevelID(userEntitlement.AccessLevel) != accessLevelID {
+		l.Info("User no longer has the access level; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	stakeholder, err := parseAccessLevelID(stakeholderAccessLevelID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = o.client.UpdateUserEntitlement(ctx, userID, accessLevelPatch(stakeholder))
+	if err != nil {
+		l.Debug("Error updating user access level", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func accessLevelPatch(accessLevel *licensing.AccessLevel) []webapi.JsonPatchOperation {
+	path := "/accessLevel"
+	return []webapi.JsonPatchOperation{
+		{
+			Op:    &webapi.OperationValues.Replace,
+			Path:  &path,
+			Value: accessLevel,
+		},
+	}
+}
+
+// getAccessLevelID returns the license id Azure DevOps uses to identify an access level,
+// e.g. Account-Express for Basic or Msdn-Eligible for Visual Studio Subscriber.
+func getAccessLevelID(accessLevel *licensing.AccessLevel) string {
+	var source, licenseType string
+	if accessLevel.LicensingSource != nil {
+		source = string(*accessLevel.LicensingSource)
+	}
+	if source == string(licensing.LicensingSourceValues.Msdn) {
+		if accessLevel.MsdnLicenseType != nil {
+			licenseType = string(*accessLevel.MsdnLicenseType)
+		}
+	} else if accessLevel.AccountLicenseType != nil {
+		licenseType = string(*accessLevel.AccountLicenseType)
+	}
+
+	return fmt.Sprintf("%s-%s", upperFirst(source), upperFirst(licenseType))
+}
+
+func parseAccessLevelID(accessLevelID string) (*licensing.AccessLevel, error) {
+	parts := strings.Split(accessLevelID, "-")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid access level id '%s'", accessLevelID)
+	}
+
+	source := licensing.LicensingSource(lowerFirst(parts[0]))
+	licenseType := lowerFirst(parts[1])
+	switch source {
+	case licensing.LicensingSourceValues.Account:
+		accountLicenseType := licensing.AccountLicenseType(licenseType)
+		return &licensing.AccessLevel{
+			LicensingSource:    &source,
+			AccountLicenseType: &accountLicenseType,
+		}, nil
+	case licensing.LicensingSourceValues.Msdn:
+		msdnLicenseType := licensing.MsdnLicenseType(licenseType)
+		return &licensing.AccessLevel{
+			LicensingSource:    &source,
+			AccountLicenseType: &licensing.AccountLicenseTypeValues.None,
+			MsdnLicenseType:    &msdnLicenseType,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported licensing source '%s' in access level id '%s'", source, accessLevelID)
+	}
+}
+
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func parseIntoAccessLevelResource(accessLevel *licensing.AccessLevel) (*v2.Resource, error) {
+	accessLevelID := getAccessLevelID(accessLevel)
+	displayName := accessLevelID
+	if accessLevel.LicenseDisplayName != nil && *accessLevel.LicenseDisplayName != "" {
+		displayName = *accessLevel.LicenseDisplayName
+	}
+
+	ret, err := resource.NewResource(
+		displayName,
+		accessLevelResourceType,
+		accessLevelID,
+		resource.WithDescription(fmt.Sprintf("%s access level (%s)", displayName, accessLevelID)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newAccessLevelBuilder(c *client.AzureDevOpsClient) *accessLevelBuilder {
+	return &accessLevelBuilder{
+		resourceType: accessLevelResourceType,
+		client:       c,
+	}
+}
@@ -0,0 +1,188 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	mockService "github.com/conductorone/baton-azure-devops/pkg/client"
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessLevelID(t *testing.T) {
+	testCases := []struct {
+		accessLevelID      string
+		licensingSource    licensing.LicensingSource
+		accountLicenseType licensing.AccountLicenseType
+		msdnLicenseType    licensing.MsdnLicenseType
+		expectedError      string
+	}{
+		{
+			accessLevelID:      "Account-Express",
+			licensingSource:    licensing.LicensingSourceValues.Account,
+			accountLicenseType: licensing.AccountLicenseTypeValues.Express,
+		},
+		{
+			accessLevelID:      "Account-Stakeholder",
+			licensingSource:    licensing.LicensingSourceValues.Account,
+			accountLicenseType: licensing.AccountLicenseTypeValues.Stakeholder,
+		},
+		{
+			accessLevelID:      "Account-Advanced",
+			licensingSource:    licensing.LicensingSourceValues.Account,
+			accountLicenseType: licensing.AccountLicenseTypeValues.Advanced,
+		},
+		{
+			accessLevelID:      "Msdn-Eligible",
+			licensingSource:    licensing.LicensingSourceValues.Msdn,
+			accountLicenseType: licensing.AccountLicenseTypeValues.None,
+			msdnLicenseType:    licensing.MsdnLicenseTypeValues.Eligible,
+		},
+		{
+			accessLevelID:      "Msdn-Enterprise",
+			licensingSource:    licensing.LicensingSourceValues.Msdn,
+			accountLicenseType: licensing.AccountLicenseTypeValues.None,
+			msdnLicenseType:    licensing.MsdnLicenseTypeValues.Enterprise,
+		},
+		{accessLevelID: "", expectedError: "invalid access level id"},
+		{accessLevelID: "Express", expectedError: "invalid access level id"},
+		{accessLevelID: "Account-", expectedError: "invalid access level id"},
+		{accessLevelID: "-Express", expectedError: "invalid access level id"},
+		{accessLevelID: "Account-Express-Basic", expectedError: "invalid access level id"},
+		{accessLevelID: "Trial-Express", expectedError: "unsupported licensing source"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.accessLevelID, func(t *testing.T) {
+			accessLevel, err := parseAccessLevelID(tc.accessLevelID)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.licensingSource, *accessLevel.LicensingSource)
+			assert.Equal(t, tc.accountLicenseType, *accessLevel.AccountLicenseType)
+			if tc.msdnLicenseType != "" {
+				assert.Equal(t, tc.msdnLicenseType, *accessLevel.MsdnLicenseType)
+			}
+
+			assert.Equal(t, tc.accessLevelID, getAccessLevelID(accessLevel))
+		})
+	}
+}
+
+func TestAccessLevelProvisioning(t *testing.T) {
+	const testUserDescriptor = "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx"
+	testUserID := uuid.MustParse("5d2f6c1b-8e4a-4f3d-9c7b-1a0e2d3c4b5a")
+	ctx := context.Background()
+
+	// The user holds the Basic access level.
+	mockClient := &mockService.MockAzureClient{}
+	mockClient.On("GetStorageKey", ctx, testUserDescriptor).Return(testUserID, nil)
+	mockClient.On("GetUserEntitlement", ctx, testUserID).Return(&userentitlement.UserEntitlement{
+		AccessLevel: &licensing.AccessLevel{
+			LicensingSource:    &licensing.LicensingSourceValues.Account,
+			AccountLicenseType: &licensing.AccountLicenseTypeValues.Express,
+		},
+	}, nil)
+	mockClient.On("UpdateUserEntitlement", ctx, testUserID, accessLevelPatch(&licensing.AccessLevel{
+		LicensingSource:    &licensing.LicensingSourceValues.Msdn,
+		AccountLicenseType: &licensing.AccountLicenseTypeValues.None,
+		MsdnLicenseType:    &licensing.MsdnLicenseTypeValues.Eligible,
+	})).Return(&userentitlement.UserEntitlement{}, nil)
+	mockClient.On("UpdateUserEntitlement", ctx, testUserID, accessLevelPatch(&licensing.AccessLevel{
+		LicensingSource:    &licensing.LicensingSourceValues.Account,
+		AccountLicenseType: &licensing.AccountLicenseTypeValues.Stakeholder,
+	})).Return(&userentitlement.UserEntitlement{}, nil)
+	builder := &accessLevelBuilder{client: mockClient}
+
+	user := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testUserDescriptor}}
+	assignedEntitlement := func(accessLevelID string) *v2.Entitlement {
+		return &v2.Entitlement{
+			Resource: &v2.Resource{Id: &v2.ResourceId{ResourceType: accessLevelResourceType.Id, Resource: accessLevelID}},
+			Slug:     assignedPermission,
+		}
+	}
+
+	testCases := []struct {
+		name           string
+		revoke         bool
+		principal      *v2.Resource
+		accessLevelID  string
+		alreadyExists  bool
+		alreadyRevoked bool
+		expectedError  string
+	}{
+		{
+			name:          "grant the access level the user holds",
+			principal:     user,
+			accessLevelID: "Account-Express",
+			alreadyExists: true,
+		},
+		{
+			name:          "grant another access level",
+			principal:     user,
+			accessLevelID: "Msdn-Eligible",
+		},
+		{
+			name:          "grant to a group",
+			principal:     &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: uuid.NewString()}},
+			accessLevelID: "Account-Express",
+			expectedError: "access levels can only be granted to users",
+		},
+		{
+			name:          "grant a malformed access level",
+			principal:     user,
+			accessLevelID: "Express",
+			expectedError: "invalid access level id",
+		},
+		{
+			name:          "revoke the access level the user holds",
+			revoke:        true,
+			principal:     user,
+			accessLevelID: "Account-Express",
+		},
+		{
+			name:           "revoke an access level the user no longer holds",
+			revoke:         true,
+			principal:      user,
+			accessLevelID:  "Account-Advanced",
+			alreadyRevoked: true,
+		},
+		{
+			name:          "revoke stakeholder",
+			revoke:        true,
+			principal:     user,
+			accessLevelID: stakeholderAccessLevelID,
+			expectedError: "cannot be revoked",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var annos annotations.Annotations
+			var err error
+			if tc.revoke {
+				annos, err = builder.Revoke(ctx, &v2.Grant{Principal: tc.principal, Entitlement: assignedEntitlement(tc.accessLevelID)})
+			} else {
+				annos, err = builder.Grant(ctx, tc.principal, assignedEntitlement(tc.accessLevelID))
+			}
+
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.alreadyExists, annos.Contains(&v2.GrantAlreadyExists{}))
+			assert.Equal(t, tc.alreadyRevoked, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		})
+	}
+
+	// Only granting another access level and revoking the one the user holds update the user.
+	mockClient.AssertNumberOfCalls(t, "UpdateUserEntitlement", 2)
+}
@@ -0,0 +1,340 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	config "github.com/conductorone/baton-sdk/pb/c1/config/v1"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/actions"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	disableUserAction     = "disable_user"
+	enableUserAction      = "enable_user"
+	createGroupRuleAction = "create_group_rule"
+	updateGroupRuleAction = "update_group_rule"
+	deleteGroupRuleAction = "delete_group_rule"
+)
+
+var (
+	userIDArgument = &config.Field{
+		Name:        "user_id",
+		DisplayName: "User ID",
+		Description: "The ID of the user resource.",
+		IsRequired:  true,
+		Field:       &config.Field_StringField{StringField: &config.StringField{}},
+	}
+	groupRuleIDArgument = &config.Field{
+		Name:        "group_rule_id",
+		DisplayName: "Group Rule ID",
+		Description: "The ID of the group rule resource.",
+		IsRequired:  true,
+		Field:       &config.Field_StringField{StringField: &config.StringField{}},
+	}
+	projectsArgument = &config.Field{
+		Name:        "projects",
+		DisplayName: "Projects",
+		Description: "The names or ids of the projects the members of the group are added to.",
+		Field:       &config.Field_StringSliceField{StringSliceField: &config.StringSliceField{}},
+	}
+	projectGroupArgument = &config.Field{
+		Name:        "project_group",
+		DisplayName: "Project Group",
+		Description: "The group of the projects the members of the group are added to. Must be one of: contributors, readers, project administrators. Defaults to contributors.",
+		Placeholder: "contributors",
+		Field:       &config.Field_StringField{StringField: &config.StringField{}},
+	}
+	successReturnType = &config.Field{
+		Name:        "success",
+		DisplayName: "Success",
+		Field:       &config.Field_BoolField{BoolField: &config.BoolField{}},
+	}
+
+	disableUserSchema = &v2.BatonActionSchema{
+		Name:        disableUserAction,
+		DisplayName: "Disable user",
+		Description: "Disable the account of a user and downgrade it to the Stakeholder access level. The user keeps its group and team memberships.",
+		Arguments:   []*config.Field{userIDArgument},
+		ReturnTypes: []*config.Field{successReturnType},
+	}
+	enableUserSchema = &v2.BatonActionSchema{
+		Name:        enableUserAction,
+		DisplayName: "Enable user",
+		Description: "Enable the account of a disabled user with a license type.",
+		Arguments: []*config.Field{
+			userIDArgument,
+			{
+				Name:        "license_type",
+				DisplayName: "License Type",
+				Description: "The type of license to assign to the user. Must be one of: express, stakeholder, Visual Studio Subscriber. Defaults to express.",
+				Placeholder: "express",
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+		},
+		ReturnTypes: []*config.Field{successReturnType},
+	}
+
+	createGroupRuleSchema = &v2.BatonActionSchema{
+		Name:        createGroupRuleAction,
+		DisplayName: "Create group rule",
+		Description: "Create the group rule of a Microsoft Entra group, which assigns a license and project access to its members.",
+		Arguments: []*config.Field{
+			{
+				Name:        "organization",
+				DisplayName: "Organization",
+				Description: "The name of the organization to create the group rule in. Required when the connector syncs more than one organization.",
+				Placeholder: "contoso",
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+			{
+				Name:        "group_id",
+				DisplayName: "Group ID",
+				Description: "The object ID of the Microsoft Entra group.",
+				IsRequired:  true,
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+			{
+				Name:        "license_type",
+				DisplayName: "License Type",
+				Description: "The type of license to assign to the members of the group. Must be one of: express, stakeholder, Visual Studio Subscriber.",
+				Placeholder: "express",
+				IsRequired:  true,
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+			projectsArgument,
+			projectGroupArgument,
+		},
+		ReturnTypes: []*config.Field{
+			successReturnType,
+			{
+				Name:        "group_rule_id",
+				DisplayName: "Group Rule ID",
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+		},
+	}
+	updateGroupRuleSchema = &v2.BatonActionSchema{
+		Name:        updateGroupRuleAction,
+		DisplayName: "Update group rule",
+		Description: "Replace the license of a group rule and add its group to projects.",
+		Arguments: []*config.Field{
+			groupRuleIDArgument,
+			{
+				Name:        "license_type",
+				DisplayName: "License Type",
+				Description: "The type of license to assign to the members of the group. Must be one of: express, stakeholder, Visual Studio Subscriber. Keeps the current license when omitted.",
+				Placeholder: "express",
+				Field:       &config.Field_StringField{StringField: &config.StringField{}},
+			},
+			projectsArgument,
+			projectGroupArgument,
+		},
+		ReturnTypes: []*config.Field{successReturnType},
+	}
+	deleteGroupRuleSchema = &v2.BatonActionSchema{
+		Name:        deleteGroupRuleAction,
+		DisplayName: "Delete group rule",
+		Description: "Delete a group rule, along with the license and project access its members got through it.",
+		Arguments:   []*config.Field{groupRuleIDArgument},
+		ReturnTypes: []*config.Field{successReturnType},
+	}
+)
+
+// RegisterActionManager registers the actions that disable and enable user accounts, which offboarding workflows use
+// to suspend access without removing the user, and the actions that create, update and delete group rules. Azure
+// DevOps Server users come from Active Directory and it has no group rules, so no action is registered for a
+// collection.
+func (d *Connector) RegisterActionManager(ctx context.Context) (connectorbuilder.CustomActionManager, error) {
+	actionManager := actions.NewActionManager(ctx)
+	if d.server {
+		return actionManager, nil
+	}
+
+	err := actionManager.RegisterAction(ctx, disableUserAction, disableUserSchema, d.disableUser)
+	if err != nil {
+		return nil, err
+	}
+	err = actionManager.RegisterAction(ctx, enableUserAction, enableUserSchema, d.enableUser)
+	if err != nil {
+		return nil, err
+	}
+	err = actionManager.RegisterAction(ctx, createGroupRuleAction, createGroupRuleSchema, d.createGroupRule)
+	if err != nil {
+		return nil, err
+	}
+	err = actionManager.RegisterAction(ctx, updateGroupRuleAction, updateGroupRuleSchema, d.updateGroupRule)
+	if err != nil {
+		return nil, err
+	}
+	err = actionManager.RegisterAction(ctx, deleteGroupRuleAction, deleteGroupRuleSchema, d.deleteGroupRule)
+	if err != nil {
+		return nil, err
+	}
+
+	return actionManager, nil
+}
+
+func (d *Connector) disableUser(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	users, userDescriptor, err := d.actionUser(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = users.disableUser(ctx, userDescriptor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return successResult(), nil, nil
+}
+
+func (d *Connector) enableUser(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	users, userDescriptor, err := d.actionUser(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	licenseType := args.GetFields()["license_type"].GetStringValue()
+	if licenseType == "" {
+		licenseType = "express"
+	}
+
+	err = users.enableUser(ctx, userDescriptor, licenseType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return successResult(), nil, nil
+}
+
+// actionUser returns the user builder of the organization of the user an action targets, by its namespaced resource
+// id, and the subject descriptor of the user.
+func (d *Connector) actionUser(args *structpb.Struct) (*userBuilder, string, error) {
+	userID := args.GetFields()["user_id"].GetStringValue()
+	if userID == "" {
+		return nil, "", fmt.Errorf("missing 'user_id' argument")
+	}
+
+	organizationName, localUserID, err := splitOrganizationResourceID(&v2.ResourceId{ResourceType: userResourceType.Id, Resource: userID})
+	if err != nil {
+		return nil, "", err
+	}
+
+	org, err := d.actionOrganization(organizationName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return newUserBuilder(org.client), localUserID.Resource, nil
+}
+
+func (d *Connector) createGroupRule(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	organizationName := args.GetFields()["organization"].GetStringValue()
+	if organizationName == "" {
+		if len(d.organizations) != 1 {
+			return nil, nil, fmt.Errorf("missing 'organization' argument")
+		}
+		organizationName = d.organizations[0].name
+	}
+	org, err := d.actionOrganization(organizationName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entraGroupID := args.GetFields()["group_id"].GetStringValue()
+	if entraGroupID == "" {
+		return nil, nil, fmt.Errorf("missing 'group_id' argument")
+	}
+	licenseType := args.GetFields()["license_type"].GetStringValue()
+	if licenseType == "" {
+		return nil, nil, fmt.Errorf("missing 'license_type' argument")
+	}
+
+	groupRuleID, err := newGroupRuleBuilder(org.client).createGroupRule(ctx, entraGroupID, licenseType, args.AsMap())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := successResult()
+	// The operation creating the group rule may still be in progress, without the id of the group rule.
+	if groupRuleID != uuid.Nil {
+		result.Fields["group_rule_id"] = structpb.NewStringValue(organizationResourceID(org.name, groupRuleID.String()))
+	}
+
+	return result, nil, nil
+}
+
+func (d *Connector) updateGroupRule(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	groupRules, groupRuleID, err := d.actionGroupRule(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = groupRules.updateGroupRule(ctx, groupRuleID, args.GetFields()["license_type"].GetStringValue(), args.AsMap())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return successResult(), nil, nil
+}
+
+func (d *Connector) deleteGroupRule(ctx context.Context, args *structpb.Struct) (*structpb.Struct, annotations.Annotations, error) {
+	groupRules, groupRuleID, err := d.actionGroupRule(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = groupRules.deleteGroupRule(ctx, groupRuleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return successResult(), nil, nil
+}
+
+// actionGroupRule returns the group rule builder of the organization of the group rule an action targets, by its
+// namespaced resource id, and the id of the group rule.
+func (d *Connector) actionGroupRule(args *structpb.Struct) (*groupRuleBuilder, string, error) {
+	groupRuleID := args.GetFields()["group_rule_id"].GetStringValue()
+	if groupRuleID == "" {
+		return nil, "", fmt.Errorf("missing 'group_rule_id' argument")
+	}
+
+	organizationName, localGroupRuleID, err := splitOrganizationResourceID(&v2.ResourceId{ResourceType: groupRuleResourceType.Id, Resource: groupRuleID})
+	if err != nil {
+		return nil, "", err
+	}
+
+	org, err := d.actionOrganization(organizationName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return newGroupRuleBuilder(org.client), localGroupRuleID.Resource, nil
+}
+
+// actionOrganization returns the synced organization an action targets, by name.
+func (d *Connector) actionOrganization(organizationName string) (*organization, error) {
+	// Organization names are case insensitive.
+	for _, org := range d.organizations {
+		if strings.EqualFold(org.name, organizationName) {
+			return org, nil
+		}
+	}
+
+	return nil, fmt.Errorf("baton-azure-devops: organization %s is not synced by the connector", organizationName)
+}
+
+func successResult() *structpb.Struct {
+	return &structpb.Struct{
+		Fields: map[string]*structpb.Value{
+			"success": structpb.NewBoolValue(true),
+		},
+	}
+}
@@ -0,0 +1,72 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestUserActions(t *testing.T) {
+	ctx := context.Background()
+	connector := &Connector{organizations: []*organization{{name: "contoso"}, {name: "fabrikam"}}}
+
+	actionManager, err := connector.RegisterActionManager(ctx)
+	require.NoError(t, err)
+	schemas, _, err := actionManager.ListActionSchemas(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, schema := range schemas {
+		names = append(names, schema.Name)
+	}
+	assert.ElementsMatch(t, []string{disableUserAction, enableUserAction, createGroupRuleAction, updateGroupRuleAction, deleteGroupRuleAction}, names)
+
+	t.Run("the user is resolved in its organization", func(t *testing.T) {
+		args, err := structpb.NewStruct(map[string]interface{}{"user_id": "fabrikam/aad.userDescriptor"})
+		require.NoError(t, err)
+
+		_, userDescriptor, err := connector.actionUser(args)
+		require.NoError(t, err)
+		assert.Equal(t, "aad.userDescriptor", userDescriptor)
+	})
+
+	t.Run("users of other organizations are rejected", func(t *testing.T) {
+		args, err := structpb.NewStruct(map[string]interface{}{"user_id": "northwind/aad.userDescriptor"})
+		require.NoError(t, err)
+
+		_, _, err = connector.actionUser(args)
+		assert.ErrorContains(t, err, "organization northwind is not synced")
+	})
+
+	t.Run("the user is required", func(t *testing.T) {
+		_, _, err := connector.actionUser(&structpb.Struct{})
+		assert.ErrorContains(t, err, "missing 'user_id'")
+	})
+
+	t.Run("the group rule is resolved in its organization", func(t *testing.T) {
+		args, err := structpb.NewStruct(map[string]interface{}{"group_rule_id": "Fabrikam/2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b"})
+		require.NoError(t, err)
+
+		_, groupRuleID, err := connector.actionGroupRule(args)
+		require.NoError(t, err)
+		assert.Equal(t, "2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b", groupRuleID)
+	})
+
+	t.Run("the organization of a new group rule is required with several organizations", func(t *testing.T) {
+		args, err := structpb.NewStruct(map[string]interface{}{"group_id": "8f9e0d1c-2b3a-4c5d-8e7f-6a5b4c3d2e1f", "license_type": "express"})
+		require.NoError(t, err)
+
+		_, _, err = connector.createGroupRule(ctx, args)
+		assert.ErrorContains(t, err, "missing 'organization'")
+	})
+
+	t.Run("no actions are registered for a server collection", func(t *testing.T) {
+		actionManager, err := (&Connector{server: true}).RegisterActionManager(ctx)
+		require.NoError(t, err)
+		schemas, _, err := actionManager.ListActionSchemas(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, schemas)
+	})
+}
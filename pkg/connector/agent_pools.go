@@ -0,0 +1,100 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+)
+
+// The User role of an agent pool is displayed as Service Account in Azure DevOps.
+var agentPoolRoles = []string{administratorRole, userRole, readerRole}
+
+type agentPoolBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *agentPoolBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return agentPoolResourceType
+}
+
+func (o *agentPoolBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	pools, err := o.client.ListAgentPools(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, pool := range pools {
+		poolCopy := &pool
+		poolResource, err := parseIntoAgentPoolResource(poolCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, poolResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *agentPoolBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return getRoleEntitlements(resource, agentPoolRoles), "", nil, nil
+}
+
+func (o *agentPoolBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	grants, err := getRoleGrants(ctx, o.organization, agentPoolRoleScope, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", nil, nil
+}
+
+func (o *agentPoolBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	return grantRole(ctx, o.client, agentPoolRoleScope, agentPoolRoles, principal, entitlementResource)
+}
+
+func (o *agentPoolBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return revokeRole(ctx, o.client, agentPoolRoleScope, agentPoolRoles, grantResource)
+}
+
+func parseIntoAgentPoolResource(pool *taskagent.TaskAgentPool) (*v2.Resource, error) {
+	poolType := "Agent"
+	if pool.PoolType != nil && *pool.PoolType == taskagent.TaskAgentPoolTypeValues.Deployment {
+		poolType = "Deployment"
+	}
+	description := fmt.Sprintf("%s pool", poolType)
+	if pool.IsHosted != nil && *pool.IsHosted {
+		description = fmt.Sprintf("Microsoft-hosted %s pool", strings.ToLower(poolType))
+	}
+
+	poolResource, err := resource.NewResource(
+		*pool.Name,
+		agentPoolResourceType,
+		strconv.Itoa(*pool.Id),
+		resource.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return poolResource, nil
+}
+
+func newAgentPoolBuilder(c *client.AzureDevOpsClient, org *organization) *agentPoolBuilder {
+	return &agentPoolBuilder{
+		resourceType: agentPoolResourceType,
+		client:       c,
+		organization: org,
+	}
+}
@@ -0,0 +1,104 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+)
+
+var agentQueueRoles = []string{administratorRole, userRole, creatorRole, readerRole}
+
+type agentQueueBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *agentQueueBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return agentQueueResourceType
+}
+
+func (o *agentQueueBuilder) List(ctx context.Context, parent *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	if parent == nil {
+		return resources, "", nil, nil
+	}
+
+	queues, err := o.client.ListAgentQueues(ctx, parent.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, queue := range queues {
+		queueCopy := &queue
+		queueResource, err := parseIntoAgentQueueResource(parent.Resource, queueCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, queueResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *agentQueueBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return getRoleEntitlements(resource, agentQueueRoles), "", nil, nil
+}
+
+func (o *agentQueueBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	grants, err := getRoleGrants(ctx, o.organization, agentQueueRoleScope, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", nil, nil
+}
+
+func (o *agentQueueBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	return grantRole(ctx, o.client, agentQueueRoleScope, agentQueueRoles, principal, entitlementResource)
+}
+
+func (o *agentQueueBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return revokeRole(ctx, o.client, agentQueueRoleScope, agentQueueRoles, grantResource)
+}
+
+// parseIntoAgentQueueResource builds an agent queue resource identified by projectId_queueId, the id of the queue in
+// its security role scope.
+func parseIntoAgentQueueResource(projectID string, queue *taskagent.TaskAgentQueue) (*v2.Resource, error) {
+	description := "Agent queue"
+	if queue.Pool != nil && queue.Pool.Name != nil {
+		description = fmt.Sprintf("Agent queue of the %s pool", *queue.Pool.Name)
+	}
+
+	queueResource, err := resource.NewResource(
+		*queue.Name,
+		agentQueueResourceType,
+		fmt.Sprintf("%s_%d", projectID, *queue.Id),
+		resource.WithDescription(description),
+		resource.WithParentResourceID(
+			&v2.ResourceId{
+				ResourceType: projectResourceType.Id,
+				Resource:     projectID,
+			}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return queueResource, nil
+}
+
+func newAgentQueueBuilder(c *client.AzureDevOpsClient, org *organization) *agentQueueBuilder {
+	return &agentQueueBuilder{
+		resourceType: agentQueueResourceType,
+		client:       c,
+		organization: org,
+	}
+}
@@ -2,9 +2,11 @@ package connector
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
-	"time"
 
 	"github.com/conductorone/baton-azure-devops/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -16,42 +18,104 @@ import (
 
 const TTL = 5 // in minutes
 
-type Connector struct {
-	client         *client.AzureDevOpsClient
-	users          map[string]string
-	usersMutex     sync.Mutex
-	usersTimestamp time.Time
+// organization holds the client of an Azure DevOps organization and the state shared by its resource syncers.
+type organization struct {
+	name   string
+	client *client.AzureDevOpsClient
+
+	securityNamespaces         []string
+	excludedSecurityNamespaces []string
+	securityNamespaceIDs       []string
+	securityNamespacesMutex    sync.Mutex
+
+	identities identityCache
 }
 
-func (d *Connector) loadUsers(ctx context.Context) error {
-	d.usersMutex.Lock()
-	defer d.usersMutex.Unlock()
+// loadSecurityNamespaces resolves the configured project security namespaces to their ids once.
+func (o *organization) loadSecurityNamespaces(ctx context.Context) ([]string, error) {
+	o.securityNamespacesMutex.Lock()
+	defer o.securityNamespacesMutex.Unlock()
 
-	if d.users != nil && time.Since(d.usersTimestamp) < TTL*time.Minute {
-		return nil
+	if o.securityNamespaceIDs != nil {
+		return o.securityNamespaceIDs, nil
 	}
 
-	l := ctxzap.Extract(ctx)
-	d.users = make(map[string]string)
+	namespaces, err := o.client.ListAllSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	usersMap, err := d.client.GetUsersMap(ctx)
+	namespaceIDs, err := resolveSecurityNamespaces(namespaces, o.securityNamespaces, o.excludedSecurityNamespaces)
 	if err != nil {
-		l.Error("Unable to make users map", zap.Error(err))
+		return nil, err
 	}
-	d.users = usersMap
+	o.securityNamespaceIDs = namespaceIDs
 
-	return nil
+	return namespaceIDs, nil
+}
+
+// resourceSyncers returns the resource syncers of the organization, which sync ids that are not namespaced.
+func (o *organization) resourceSyncers() []connectorbuilder.ResourceSyncer {
+	syncers := []connectorbuilder.ResourceSyncer{
+		newProjectBuilder(o.client, o),
+		newTeamBuilder(o.client, o),
+		newGroupBuilder(o.client, o),
+		newRepositoryBuilder(o.client, o),
+		newPipelineBuilder(o.client, o),
+		newServiceConnectionBuilder(o.client, o),
+		newAgentPoolBuilder(o.client, o),
+		newAgentQueueBuilder(o.client, o),
+		newEnvironmentBuilder(o.client, o),
+		newVariableGroupBuilder(o.client, o),
+		newSecureFileBuilder(o.client, o),
+	}
+
+	// Azure DevOps Server users come from Active Directory and their access levels are managed on the server, so
+	// accounts, access levels and group rules are not provisioned or synced.
+	if o.client.Server {
+		return append([]connectorbuilder.ResourceSyncer{newServerUserBuilder(o.client)}, syncers...)
+	}
+
+	syncers = append([]connectorbuilder.ResourceSyncer{newUserBuilder(o.client)}, syncers...)
+	return append(syncers, newAccessLevelBuilder(o.client), newGroupRuleBuilder(o.client))
+}
+
+type Connector struct {
+	organizations []*organization
+	server        bool
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
-func (d *Connector) ResourceSyncers(_ context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newProjectBuilder(d.client, d),
-		newTeamBuilder(d.client),
-		newGroupBuilder(d.client),
-		newRepositoryBuilder(d.client, d),
+// Every resource type but the organization is synced across the organizations by an organizationSyncer.
+func (d *Connector) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
+	organizationSyncers := make([][]connectorbuilder.ResourceSyncer, len(d.organizations))
+	organizationNames := make([]string, len(d.organizations))
+	for i, org := range d.organizations {
+		organizationSyncers[i] = org.resourceSyncers()
+		organizationNames[i] = org.name
 	}
+
+	syncers := []connectorbuilder.ResourceSyncer{newOrganizationBuilder(d.organizations)}
+	for j, syncer := range organizationSyncers[0] {
+		syncersByOrganization := make(map[string]connectorbuilder.ResourceSyncer, len(d.organizations))
+		for i, org := range d.organizations {
+			syncersByOrganization[org.name] = organizationSyncers[i][j]
+		}
+		syncers = append(syncers, newOrganizationSyncer(syncer.ResourceType(ctx), organizationNames, syncersByOrganization, d.rateLimit))
+	}
+
+	return syncers
+}
+
+// rateLimit returns the rate limit state of an organization.
+func (d *Connector) rateLimit(organizationName string) *v2.RateLimitDescription {
+	for _, org := range d.organizations {
+		if org.name == organizationName {
+			return org.client.RateLimitDescription()
+		}
+	}
+
+	return nil
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
@@ -62,9 +126,16 @@ func (d *Connector) Asset(_ context.Context, _ *v2.AssetRef) (string, io.ReadClo
 
 // Metadata returns metadata about the connector.
 func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
+	if d.server {
+		return &v2.ConnectorMetadata{
+			DisplayName: "Azure Dev Ops Server Connector",
+			Description: "Connector to sync users, security namespaces, projects, repositories, pipelines, service connections, agent pools, environments, variable groups, secure files, teams and groups of Azure DevOps Server collections",
+		}, nil
+	}
+
 	return &v2.ConnectorMetadata{
 		DisplayName: "Azure Dev Ops Connector",
-		Description: "Connector to sync users, security namespaces, projects, teams and groups",
+		Description: "Connector to sync organizations, users, access levels, group rules, security namespaces, projects, repositories, pipelines, service connections, agent pools, environments, variable groups, secure files, teams and groups",
 		AccountCreationSchema: &v2.ConnectorAccountCreationSchema{
 			FieldMap: map[string]*v2.ConnectorAccountCreationSchema_Field{
 				"principal_name": {
@@ -87,6 +158,36 @@ func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
 					Placeholder: "express",
 					Order:       2,
 				},
+				"organization": {
+					DisplayName: "Organization",
+					Required:    false,
+					Description: "The name of the organization to add the user to. Required when the connector syncs more than one organization.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+					Placeholder: "contoso",
+					Order:       3,
+				},
+				"projects": {
+					DisplayName: "Projects",
+					Required:    false,
+					Description: "The names or ids of the projects to add the user to.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringListField{
+						StringListField: &v2.ConnectorAccountCreationSchema_StringListField{},
+					},
+					Placeholder: "Fabrikam-Fiber",
+					Order:       4,
+				},
+				"project_group": {
+					DisplayName: "Project Group",
+					Required:    false,
+					Description: "The group of the projects to add the user to. Must be one of: contributors, readers, project administrators. Defaults to contributors.",
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+					Placeholder: "contributors",
+					Order:       5,
+				},
 			},
 		},
 	}, nil
@@ -94,21 +195,59 @@ func (d *Connector) Metadata(_ context.Context) (*v2.ConnectorMetadata, error) {
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
-func (d *Connector) Validate(_ context.Context) (annotations.Annotations, error) {
+func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	for _, org := range d.organizations {
+		if err := org.client.Validate(ctx); err != nil {
+			return nil, fmt.Errorf("organization %s: %w", org.name, err)
+		}
+	}
+
 	return nil, nil
 }
 
-// New returns a new instance of the connector.
-func New(ctx context.Context, personalAccessToken, organizationUrl string, syncGrantSources bool) (*Connector, error) {
+// New returns a new instance of the connector, syncing every organization of organizationUrls.
+func New(
+	ctx context.Context,
+	credentials client.Credentials,
+	organizationUrls []string,
+	server,
+	syncGrantSources,
+	syncPermissionActions bool,
+	securityNamespaces,
+	excludedSecurityNamespaces []string,
+) (*Connector, error) {
 	l := ctxzap.Extract(ctx)
 
-	azureDevOpsClient, err := client.New(ctx, personalAccessToken, organizationUrl, syncGrantSources)
-	if err != nil {
-		l.Error("error creating Azure DevOps client", zap.Error(err))
-		return nil, err
+	if len(organizationUrls) == 0 {
+		return nil, errors.New("baton-azure-devops: at least one organization url is required")
 	}
 
-	return &Connector{
-		client: azureDevOpsClient,
-	}, nil
+	connector := &Connector{server: server}
+	organizationNames := make(map[string]bool, len(organizationUrls))
+	for _, organizationUrl := range organizationUrls {
+		name, err := client.OrganizationName(organizationUrl)
+		if err != nil {
+			return nil, err
+		}
+		// Organization names are case insensitive.
+		if organizationNames[strings.ToLower(name)] {
+			return nil, fmt.Errorf("baton-azure-devops: organization %s is configured more than once", name)
+		}
+		organizationNames[strings.ToLower(name)] = true
+
+		azureDevOpsClient, err := client.New(ctx, credentials, organizationUrl, server, syncGrantSources, syncPermissionActions)
+		if err != nil {
+			l.Error("error creating Azure DevOps client", zap.Error(err), zap.String("organization", name))
+			return nil, err
+		}
+
+		connector.organizations = append(connector.organizations, &organization{
+			name:                       name,
+			client:                     azureDevOpsClient,
+			securityNamespaces:         securityNamespaces,
+			excludedSecurityNamespaces: excludedSecurityNamespaces,
+		})
+	}
+
+	return connector, nil
 }
@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+)
+
+var environmentRoles = []string{administratorRole, userRole, creatorRole, readerRole}
+
+type environmentBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *environmentBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return environmentResourceType
+}
+
+func (o *environmentBuilder) List(ctx context.Context, parent *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	if parent == nil {
+		return resources, "", nil, nil
+	}
+
+	environments, nextPageToken, err := o.client.ListEnvironments(ctx, parent.Resource, pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, environment := range environments {
+		environmentCopy := &environment
+		environmentResource, err := parseIntoEnvironmentResource(parent.Resource, environmentCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, environmentResource)
+	}
+
+	return resources, nextPageToken, nil, nil
+}
+
+func (o *environmentBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return getRoleEntitlements(resource, environmentRoles), "", nil, nil
+}
+
+func (o *environmentBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	grants, err := getRoleGrants(ctx, o.organization, environmentRoleScope, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", nil, nil
+}
+
+func (o *environmentBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	return grantRole(ctx, o.client, environmentRoleScope, environmentRoles, principal, entitlementResource)
+}
+
+func (o *environmentBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return revokeRole(ctx, o.client, environmentRoleScope, environmentRoles, grantResource)
+}
+
+// parseIntoEnvironmentResource builds an environment resource identified by projectId_environmentId, the id of the
+// environment in its security role scope.
+func parseIntoEnvironmentResource(projectID string, environment *taskagent.EnvironmentInstance) (*v2.Resource, error) {
+	options := []resource.ResourceOption{
+		resource.WithParentResourceID(
+			&v2.ResourceId{
+				ResourceType: projectResourceType.Id,
+				Resource:     projectID,
+			}),
+	}
+	if environment.Description != nil && *environment.Description != "" {
+		options = append(options, resource.WithDescription(*environment.Description))
+	}
+
+	environmentResource, err := resource.NewResource(
+		*environment.Name,
+		environmentResourceType,
+		fmt.Sprintf("%s_%d", projectID, *environment.Id),
+		options...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return environmentResource, nil
+}
+
+func newEnvironmentBuilder(c *client.AzureDevOpsClient, org *organization) *environmentBuilder {
+	return &environmentBuilder{
+		resourceType: environmentResourceType,
+		client:       c,
+		organization: org,
+	}
+}
@@ -0,0 +1,326 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"go.uber.org/zap"
+)
+
+// groupRuleBuilder syncs the group rules of an organization: the group entitlements that assign a license, extensions
+// and project access to the members of a group, usually a Microsoft Entra group.
+type groupRuleBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+}
+
+func (o *groupRuleBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return groupRuleResourceType
+}
+
+func (o *groupRuleBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	groupEntitlements, err := o.client.ListGroupEntitlements(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, groupEntitlement := range groupEntitlements {
+		if groupEntitlement.Id == nil {
+			continue
+		}
+		groupEntitlementCopy := &groupEntitlement
+		groupRuleResource, err := parseIntoGroupRuleResource(groupEntitlementCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, groupRuleResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *groupRuleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	assigmentOptions := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(userResourceType),
+		entitlement.WithDescription(fmt.Sprintf("Inherits the license and project access of the %s group rule", resource.DisplayName)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, memberPermission)),
+	}
+
+	return []*v2.Entitlement{
+		entitlement.NewPermissionEntitlement(resource, memberPermission, assigmentOptions...),
+	}, "", nil, nil
+}
+
+// Grants returns a grant for each user that inherits access through the group rule, as a member of its group.
+func (o *groupRuleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var grants []*v2.Grant
+
+	groupID, err := uuid.Parse(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("invalid group rule id %s: %w", resource.Id.Resource, err)
+	}
+
+	members, nextPageToken, err := o.client.ListGroupEntitlementMembers(ctx, groupID, pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, member := range members {
+		if member.User == nil || member.User.Descriptor == nil {
+			continue
+		}
+		userResourceId := &v2.ResourceId{
+			ResourceType: userResourceType.Id,
+			Resource:     *member.User.Descriptor,
+		}
+		grants = append(grants, grant.NewGrant(resource, memberPermission, userResourceId))
+	}
+
+	return grants, nextPageToken, nil, nil
+}
+
+// createGroupRule creates the group rule of a Microsoft Entra group, by object id, with a license type as accepted by
+// CreateAccount and the projects of the arguments. It returns the id of the group rule.
+func (o *groupRuleBuilder) createGroupRule(ctx context.Context, entraGroupID, licenseType string, args map[string]interface{}) (uuid.UUID, error) {
+	l := ctxzap.Extract(ctx)
+
+	if _, err := uuid.Parse(entraGroupID); err != nil {
+		return uuid.Nil, fmt.Errorf("invalid group_id '%s': %w", entraGroupID, err)
+	}
+
+	accountLicenseType, licensingSource, err := mapLicenseType(licenseType)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	requestedProjectEntitlements, err := projectEntitlements(ctx, o.client, args)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	origin := "aad"
+	subjectKind := "group"
+	groupEntitlement := &userentitlement.GroupEntitlement{
+		Group: &graph.GraphGroup{
+			Origin:      &origin,
+			OriginId:    &entraGroupID,
+			SubjectKind: &subjectKind,
+		},
+		LicenseRule: &licensing.AccessLevel{
+			LicensingSource:    licensingSource,
+			AccountLicenseType: accountLicenseType,
+		},
+	}
+	if len(requestedProjectEntitlements) > 0 {
+		groupEntitlement.ProjectEntitlements = &requestedProjectEntitlements
+	}
+
+	groupRuleID, err := o.client.AddGroupEntitlement(ctx, groupEntitlement)
+	if err != nil {
+		l.Debug("Error creating group rule", zap.Error(err))
+		return uuid.Nil, err
+	}
+
+	return groupRuleID, nil
+}
+
+// updateGroupRule replaces the license of a group rule, when a license type is given, and adds the group to the
+// projects of the arguments.
+func (o *groupRuleBuilder) updateGroupRule(ctx context.Context, groupRuleID, licenseType string, args map[string]interface{}) error {
+	l := ctxzap.Extract(ctx)
+
+	groupID, err := uuid.Parse(groupRuleID)
+	if err != nil {
+		return fmt.Errorf("invalid group rule id %s: %w", groupRuleID, err)
+	}
+
+	var licenseRule *licensing.AccessLevel
+	if licenseType != "" {
+		accountLicenseType, licensingSource, err := mapLicenseType(licenseType)
+		if err != nil {
+			return err
+		}
+		licenseRule = &licensing.AccessLevel{
+			LicensingSource:    licensingSource,
+			AccountLicenseType: accountLicenseType,
+		}
+	}
+
+	requestedProjectEntitlements, err := projectEntitlements(ctx, o.client, args)
+	if err != nil {
+		return err
+	}
+
+	document := groupRulePatch(licenseRule, requestedProjectEntitlements)
+	if len(document) == 0 {
+		return fmt.Errorf("nothing to update; set license_type or projects")
+	}
+
+	err = o.client.UpdateGroupEntitlement(ctx, groupID, document)
+	if err != nil {
+		l.Debug("Error updating group rule", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// deleteGroupRule removes a group rule, along with the access its members got through it. Group rules that were
+// already removed are deleted successfully.
+func (o *groupRuleBuilder) deleteGroupRule(ctx context.Context, groupRuleID string) error {
+	l := ctxzap.Extract(ctx)
+
+	groupID, err := uuid.Parse(groupRuleID)
+	if err != nil {
+		return fmt.Errorf("invalid group rule id %s: %w", groupRuleID, err)
+	}
+
+	err = o.client.DeleteGroupEntitlement(ctx, groupID)
+	if errors.Is(err, client.ErrNotFound) {
+		l.Info("Group rule to delete not found; treating as successful because the end state is achieved")
+		return nil
+	}
+	if err != nil {
+		l.Debug("Error deleting group rule", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// groupRulePatch returns the JSON patch document that replaces the license rule of a group rule, unless it is nil, and
+// adds the project entitlements.
+func groupRulePatch(licenseRule *licensing.AccessLevel, projectEntitlements []userentitlement.ProjectEntitlement) []webapi.JsonPatchOperation {
+	var document []webapi.JsonPatchOperation
+	if licenseRule != nil {
+		path := "/licenseRule"
+		document = append(document, webapi.JsonPatchOperation{
+			Op:    &webapi.OperationValues.Replace,
+			Path:  &path,
+			Value: licenseRule,
+		})
+	}
+	for _, projectEntitlement := range projectEntitlements {
+		path := fmt.Sprintf("/projectEntitlements/%s", projectEntitlement.ProjectRef.Id)
+		document = append(document, webapi.JsonPatchOperation{
+			Op:    &webapi.OperationValues.Add,
+			Path:  &path,
+			Value: projectEntitlement,
+		})
+	}
+
+	return document
+}
+
+func parseIntoGroupRuleResource(groupEntitlement *userentitlement.GroupEntitlement) (*v2.Resource, error) {
+	groupRuleID := groupEntitlement.Id.String()
+	displayName := groupRuleID
+	profile := map[string]interface{}{
+		"group_rule_id": groupRuleID,
+	}
+
+	if group := groupEntitlement.Group; group != nil {
+		if group.DisplayName != nil && *group.DisplayName != "" {
+			displayName = *group.DisplayName
+		}
+		if group.Origin != nil {
+			profile["origin"] = *group.Origin
+		}
+		if group.OriginId != nil {
+			profile["origin_id"] = *group.OriginId
+		}
+		if group.Descriptor != nil {
+			profile["descriptor"] = *group.Descriptor
+		}
+	}
+	profile["display_name"] = displayName
+
+	description := fmt.Sprintf("Group rule of %s", displayName)
+	if groupEntitlement.LicenseRule != nil {
+		licenseRule := getAccessLevelID(groupEntitlement.LicenseRule)
+		profile["license_rule"] = licenseRule
+		description = fmt.Sprintf("Group rule of %s assigning the %s access level", displayName, licenseRule)
+	}
+
+	// Project entitlements are listed as project: group type, e.g. Fabrikam-Fiber: projectContributor.
+	var projects []interface{}
+	if groupEntitlement.ProjectEntitlements != nil {
+		for _, projectEntitlement := range *groupEntitlement.ProjectEntitlements {
+			if projectEntitlement.ProjectRef == nil {
+				continue
+			}
+			project := ""
+			if projectEntitlement.ProjectRef.Name != nil {
+				project = *projectEntitlement.ProjectRef.Name
+			} else if projectEntitlement.ProjectRef.Id != nil {
+				project = projectEntitlement.ProjectRef.Id.String()
+			}
+			if projectEntitlement.Group != nil && projectEntitlement.Group.GroupType != nil {
+				project = fmt.Sprintf("%s: %s", project, *projectEntitlement.Group.GroupType)
+			}
+			projects = append(projects, project)
+		}
+	}
+	profile["project_entitlements"] = projects
+
+	var extensions []interface{}
+	if groupEntitlement.ExtensionRules != nil {
+		for _, extension := range *groupEntitlement.ExtensionRules {
+			switch {
+			case extension.Name != nil:
+				extensions = append(extensions, *extension.Name)
+			case extension.Id != nil:
+				extensions = append(extensions, *extension.Id)
+			}
+		}
+	}
+	profile["extension_rules"] = extensions
+
+	if groupEntitlement.Status != nil {
+		profile["status"] = string(*groupEntitlement.Status)
+	}
+	if groupEntitlement.LastExecuted != nil {
+		profile["last_executed"] = groupEntitlement.LastExecuted.Time.Format(time.RFC3339)
+	}
+
+	groupTraits := []resource.GroupTraitOption{
+		resource.WithGroupProfile(profile),
+	}
+
+	ret, err := resource.NewGroupResource(
+		displayName,
+		groupRuleResourceType,
+		groupRuleID,
+		groupTraits,
+		resource.WithDescription(description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newGroupRuleBuilder(c *client.AzureDevOpsClient) *groupRuleBuilder {
+	return &groupRuleBuilder{
+		resourceType: groupRuleResourceType,
+		client:       c,
+	}
+}
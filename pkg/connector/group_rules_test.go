@@ -0,0 +1,68 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensingrule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIntoGroupRuleResource(t *testing.T) {
+	groupRuleID := uuid.MustParse("2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b")
+	displayName := "[contoso]\\Engineering"
+	origin := "aad"
+	projectName := "Fabrikam-Fiber"
+	extensionName := "Test Manager"
+	status := licensingrule.GroupLicensingRuleStatusValues.Applied
+
+	groupRuleResource, err := parseIntoGroupRuleResource(&userentitlement.GroupEntitlement{
+		Id:    &groupRuleID,
+		Group: &graph.GraphGroup{DisplayName: &displayName, Origin: &origin},
+		LicenseRule: &licensing.AccessLevel{
+			LicensingSource:    &licensing.LicensingSourceValues.Account,
+			AccountLicenseType: &licensing.AccountLicenseTypeValues.Express,
+		},
+		ProjectEntitlements: &[]userentitlement.ProjectEntitlement{
+			{
+				Group:      &userentitlement.Group{GroupType: &userentitlement.GroupTypeValues.ProjectContributor},
+				ProjectRef: &userentitlement.ProjectRef{Name: &projectName},
+			},
+		},
+		ExtensionRules: &[]userentitlement.Extension{{Name: &extensionName}},
+		Status:         &status,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, groupRuleID.String(), groupRuleResource.Id.Resource)
+	assert.Equal(t, displayName, groupRuleResource.DisplayName)
+
+	groupTrait, err := resource.GetGroupTrait(groupRuleResource)
+	require.NoError(t, err)
+	profile := groupTrait.GetProfile().AsMap()
+	assert.Equal(t, "Account-Express", profile["license_rule"])
+	assert.Equal(t, []interface{}{"Fabrikam-Fiber: projectContributor"}, profile["project_entitlements"])
+	assert.Equal(t, []interface{}{"Test Manager"}, profile["extension_rules"])
+	assert.Equal(t, "applied", profile["status"])
+}
+
+func TestGroupRulePatch(t *testing.T) {
+	projectID := uuid.MustParse("8f9e0d1c-2b3a-4c5d-8e7f-6a5b4c3d2e1f")
+	projectEntitlements := []userentitlement.ProjectEntitlement{
+		{
+			Group:      &userentitlement.Group{GroupType: &userentitlement.GroupTypeValues.ProjectReader},
+			ProjectRef: &userentitlement.ProjectRef{Id: &projectID},
+		},
+	}
+
+	document := groupRulePatch(&licensing.AccessLevel{AccountLicenseType: &licensing.AccountLicenseTypeValues.Stakeholder}, projectEntitlements)
+	require.Len(t, document, 2)
+	assert.Equal(t, "/licenseRule", *document[0].Path)
+	assert.Equal(t, "/projectEntitlements/8f9e0d1c-2b3a-4c5d-8e7f-6a5b4c3d2e1f", *document[1].Path)
+
+	assert.Empty(t, groupRulePatch(nil, nil))
+}
@@ -10,21 +10,26 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
-	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
-	"github.com/google/uuid"
-	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
-	"go.uber.org/zap"
 )
 
 type groupBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.AzureDevOpsClient
+	organization *organization
 }
 
 var memberPermission = "member"
 
+// adminEquivalentGroups are the built-in groups whose members hold every permission of their organization or project,
+// by lowercase display name.
+var adminEquivalentGroups = map[string]bool{
+	"project collection administrators":   true,
+	"project collection service accounts": true,
+	"project administrators":              true,
+}
+
 func (o *groupBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return groupResourceType
 }
@@ -53,7 +58,7 @@ func (o *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _
 	var entitlements []*v2.Entitlement
 
 	assigmentOptions := []entitlement.EntitlementOption{
-		entitlement.WithGrantableTo(userResourceType, groupResourceType),
+		entitlement.WithGrantableTo(userResourceType, groupResourceType, teamResourceType),
 		entitlement.WithDescription(fmt.Sprintf("%s membership type %s", resource.DisplayName, memberPermission)),
 		entitlement.WithDisplayName(memberPermission),
 	}
@@ -63,147 +68,64 @@ func (o *groupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _
 	return entitlements, "", nil, nil
 }
 
+// Grants returns a grant for each direct member of the group: users, service principals, groups, Entra groups and
+// teams. Nested groups and teams are granted with expandable grants so that the baton-sdk computes the transitive
+// membership, which also resolves cyclic memberships instead of following them.
 func (o *groupBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var grants []*v2.Grant
 
-	groupId := resource.Id.Resource
-
-	groupIdentities, err := o.client.ListIdentities(ctx, groupId, "")
+	groupDescriptor, err := getSubjectDescriptor(ctx, o.client, resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, groupIdentity := range groupIdentities {
-		if groupIdentity.MemberIds != nil {
-			if len(*groupIdentity.MemberIds) > 0 {
-				memberIDs := make([]string, len(*groupIdentity.MemberIds))
-				for _, memberID := range *groupIdentity.MemberIds {
-					memberIDs = append(memberIDs, memberID.String())
-				}
-				memberIDsStr := strings.Join(memberIDs, ",")
-				identities, err := o.client.ListIdentities(ctx, memberIDsStr, "")
-				if err != nil {
-					return nil, "", nil, err
-				}
-				for _, member := range identities {
-					properties, err := unmarshalProperties(member.Properties)
-					if err != nil {
-						continue
-					}
-					schema, err := unmarshalProperties(properties["SchemaClassName"])
-					if err != nil {
-						continue
-					}
-					if schema["$value"] == "User" {
-						userResource := &v2.Resource{
-							Id: &v2.ResourceId{
-								ResourceType: userResourceType.Id,
-								Resource:     *member.SubjectDescriptor,
-							},
-						}
-						membershipGrant := grant.NewGrant(resource, memberPermission, userResource.Id)
-						grants = append(grants, membershipGrant)
-					}
-					if schema["$value"] == "Group" {
-						groupResource := &v2.Resource{
-							Id: &v2.ResourceId{
-								ResourceType: groupResourceType.Id,
-								Resource:     member.Id.String(),
-							},
-						}
-						membershipGrant := grant.NewGrant(resource, memberPermission, groupResource.Id)
-						grants = append(grants, membershipGrant)
-					}
-				}
-			}
-		}
-	}
-	return grants, "", nil, nil
-}
-
-func (o *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
-	l := ctxzap.Extract(ctx)
-	resourceId := entitlementResource.Resource.Id.Resource
-	parsedUUID, err := uuid.Parse(resourceId)
-	if err != nil {
-		return nil, err
-	}
-	groupDescriptor, err := o.client.GetDescriptor(ctx, parsedUUID)
+	memberIDs, err := o.client.ListDirectMemberIDs(ctx, groupDescriptor)
 	if err != nil {
-		l.Debug("Error getting group descriptor", zap.Error(err))
-		return nil, err
-	}
-	memberDescriptor := principal.Id.Resource
-	if parsedUUID, err := uuid.Parse(principal.Id.Resource); err == nil {
-		memberDescriptor, err = o.client.GetDescriptor(ctx, parsedUUID)
-		if err != nil {
-			l.Debug("Error fetching principal descriptor", zap.Error(err))
-			return nil, err
-		}
+		return nil, "", nil, err
 	}
-
-	membership, err := o.client.GetMembership(ctx, groupDescriptor, memberDescriptor)
-	if membership != nil {
-		l.Info("Group membership already exists; treating as successful because the end state is achieved")
-		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	if len(memberIDs) == 0 {
+		return nil, "", nil, nil
 	}
 
-	if err != nil && !strings.Contains(err.Error(), "could not be found") {
-		l.Debug("Error getting membership", zap.Error(err))
-		return nil, err
-	}
-
-	_, err = o.client.CreateMembership(ctx, groupDescriptor, memberDescriptor)
-	if err != nil {
-		l.Debug("Error creating membership", zap.Error(err))
-		return nil, err
-	}
-
-	return nil, nil
-}
-
-func (o *groupBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
-	l := ctxzap.Extract(ctx)
-	principal := grantResource.Principal
-	memberDescriptor := principal.Id.Resource
-	if parsedUUID, err := uuid.Parse(principal.Id.Resource); err == nil {
-		memberDescriptor, err = o.client.GetDescriptor(ctx, parsedUUID)
-		if err != nil {
-			l.Debug("Error fetching principal descriptor", zap.Error(err))
-			return nil, err
-		}
+	ids := make([]string, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		ids = append(ids, memberID.String())
 	}
 
-	resourceId := grantResource.Entitlement.Resource.Id.Resource
-	parsedUUID, err := uuid.Parse(resourceId)
+	teamIDs, err := o.organization.identities.loadTeamIDs(ctx, o.client)
 	if err != nil {
-		l.Debug("Error parsing group uuid", zap.Error(err))
-		return nil, err
+		return nil, "", nil, err
 	}
-	groupDescriptor, err := o.client.GetDescriptor(ctx, parsedUUID)
+	// The members are shared by many groups, so they are read through the identity cache.
+	identities, err := o.organization.identities.identitiesByID(ctx, o.client, ids)
 	if err != nil {
-		return nil, err
+		return nil, "", nil, err
 	}
 
-	_, err = o.client.GetMembership(ctx, groupDescriptor, memberDescriptor)
-
-	if err != nil {
-		if strings.Contains(err.Error(), "could not be found") {
-			// Given the client we are using, the error status code is lost when returning the result
-			l.Info("Group membership to revoke not found; treating as successful because the end state is achieved")
-			return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	for _, id := range ids {
+		member, ok := identities[strings.ToLower(id)]
+		if !ok {
+			continue
 		}
-		l.Debug("Error getting membership", zap.Error(err))
-		return nil, err
+		principal := getMemberResourceID(member, teamIDs)
+		if principal == nil {
+			continue
+		}
+		if principal.ResourceType == groupResourceType.Id && strings.EqualFold(principal.Resource, resource.Id.Resource) {
+			continue
+		}
+		grants = append(grants, newMembershipGrant(resource, memberPermission, principal))
 	}
 
-	err = o.client.RevokeMembership(ctx, groupDescriptor, memberDescriptor)
-	if err != nil {
-		l.Debug("Error revoking group membership", zap.Error(err))
-		return nil, err
-	}
+	return grants, "", nil, nil
+}
 
-	return nil, nil
+func (o *groupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	return membershipProvisioner{client: o.client}.grant(ctx, principal, entitlementResource)
+}
+
+func (o *groupBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return membershipProvisioner{client: o.client}.revoke(ctx, grantResource)
 }
 
 func parseIntoGroupResource(group *graph.GraphGroup) (*v2.Resource, error) {
@@ -213,12 +135,12 @@ func parseIntoGroupResource(group *graph.GraphGroup) (*v2.Resource, error) {
 		"description":  *group.Description,
 		"url":          *group.Url,
 		"descriptor":   *group.Descriptor,
+		// Admin equivalent groups are flagged so that access reviews can prioritize them.
+		"admin_equivalent": adminEquivalentGroups[strings.ToLower(*group.DisplayName)],
+		"scope":            "organization",
 	}
 
-	groupTraits := []resource.GroupTraitOption{
-		resource.WithGroupProfile(profile),
-	}
-
+	// Collection level groups have no parent here, they are parented by their organization once namespaced.
 	var parentId *v2.ResourceId = nil
 	if strings.Contains(*group.Domain, "TeamProject") {
 		parts := strings.Split(*group.Domain, "/")
@@ -226,6 +148,11 @@ func parseIntoGroupResource(group *graph.GraphGroup) (*v2.Resource, error) {
 			ResourceType: projectResourceType.Id,
 			Resource:     parts[len(parts)-1],
 		}
+		profile["scope"] = "project"
+	}
+
+	groupTraits := []resource.GroupTraitOption{
+		resource.WithGroupProfile(profile),
 	}
 
 	ret, err := resource.NewGroupResource(
@@ -243,9 +170,10 @@ func parseIntoGroupResource(group *graph.GraphGroup) (*v2.Resource, error) {
 	return ret, nil
 }
 
-func newGroupBuilder(c *client.AzureDevOpsClient) *groupBuilder {
+func newGroupBuilder(c *client.AzureDevOpsClient, org *organization) *groupBuilder {
 	return &groupBuilder{
 		resourceType: groupResourceType,
 		client:       c,
+		organization: org,
 	}
 }
@@ -3,15 +3,22 @@ package connector
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/conductorone/baton-azure-devops/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"go.uber.org/zap"
 )
 
 func unmarshalProperties(properties interface{}) (map[string]interface{}, error) {
@@ -29,76 +36,176 @@ func unmarshalProperties(properties interface{}) (map[string]interface{}, error)
 	return propsMap, nil
 }
 
-func getIdentityResourceByDescriptor(client *client.AzureDevOpsClient, ctx context.Context, descriptor string, userMap map[string]string) (*v2.Resource, error) {
-	// get user email to map with user
-	parts := strings.Split(descriptor, `\`)
+// getIdentityResourcesByDescriptor maps the identity descriptors of access control entries to user, group and team
+// resources, keyed by descriptor. The identities are read in batches through the identity cache of the organization.
+// Descriptors that resolve to no resource are omitted.
+func getIdentityResourcesByDescriptor(ctx context.Context, org *organization, descriptors []string) (map[string]*v2.Resource, error) {
+	if len(descriptors) == 0 {
+		return map[string]*v2.Resource{}, nil
+	}
+
+	identities, err := org.identities.identitiesByDescriptor(ctx, org.client, descriptors)
+	if err != nil {
+		return nil, err
+	}
 
-	if len(parts) == 2 {
-		userPrincipalName := parts[1]
-		if userMap[userPrincipalName] != "" {
-			userResource := &v2.Resource{
-				Id: &v2.ResourceId{
-					ResourceType: userResourceType.Id,
-					Resource:     userMap[userPrincipalName],
-				},
+	return getIdentityResources(ctx, org, descriptors, identities)
+}
+
+// getIdentityResourcesByID maps identity ids, such as the identities of role assignments, to user, group and team
+// resources, keyed by identity id. Identity ids that resolve to no resource are omitted.
+func getIdentityResourcesByID(ctx context.Context, org *organization, identityIDs []string) (map[string]*v2.Resource, error) {
+	if len(identityIDs) == 0 {
+		return map[string]*v2.Resource{}, nil
+	}
+
+	identities, err := org.identities.identitiesByID(ctx, org.client, identityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return getIdentityResources(ctx, org, identityIDs, identities)
+}
+
+// getIdentityResources maps the keys of identities, keyed by lowercase key, to the resources of the identities, the
+// same way group members are.
+func getIdentityResources(ctx context.Context, org *organization, keys []string, identities map[string]*identity.Identity) (map[string]*v2.Resource, error) {
+	resources := make(map[string]*v2.Resource, len(keys))
+
+	teamIDs, err := org.identities.loadTeamIDs(ctx, org.client)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		member, ok := identities[strings.ToLower(key)]
+		if !ok || member == nil {
+			continue
+		}
+		resourceID := getMemberResourceID(member, teamIDs)
+		if resourceID == nil {
+			continue
+		}
+		resources[key] = &v2.Resource{Id: resourceID}
+	}
+
+	return resources, nil
+}
+
+// entraObjectIDProperty is the identity property holding the Microsoft Entra object id of Entra users and groups.
+const entraObjectIDProperty = "http://schemas.microsoft.com/identity/claims/objectidentifier"
+
+// getGroupIdentityID returns the id of the group resource of a group identity. Groups are identified by their graph
+// origin id, which is the identity id of Azure DevOps groups but the object id of Microsoft Entra groups.
+func getGroupIdentityID(groupIdentity *identity.Identity) string {
+	properties, err := unmarshalProperties(groupIdentity.Properties)
+	if err == nil {
+		if objectID, err := unmarshalProperties(properties[entraObjectIDProperty]); err == nil {
+			if value, ok := objectID["$value"].(string); ok && value != "" {
+				return value
 			}
-			return userResource, nil
 		}
-	} else {
-		teamsMap, err := client.ListTeamIDs(ctx)
-		if err != nil {
-			return nil, err
+	}
+
+	return groupIdentity.Id.String()
+}
+
+// getMemberResourceID returns the resource id of a member of a group: a team, a group, or a user for users and
+// service principals, which are identified by their subject descriptor. Members of other kinds return nil.
+func getMemberResourceID(member *identity.Identity, teamIDs map[string]bool) *v2.ResourceId {
+	if member.IsContainer != nil && *member.IsContainer {
+		if teamIDs[member.Id.String()] {
+			return &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: member.Id.String()}
 		}
-		groupIdentities, err := client.ListIdentities(ctx, "", descriptor)
-		if err != nil {
-			return nil, err
+		return &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: getGroupIdentityID(member)}
+	}
+
+	if member.SubjectDescriptor == nil || *member.SubjectDescriptor == "" {
+		return nil
+	}
+
+	return &v2.ResourceId{ResourceType: userResourceType.Id, Resource: *member.SubjectDescriptor}
+}
+
+// namespacePermission is a permission of a security namespace that is modeled as an entitlement.
+type namespacePermission struct {
+	action      string
+	displayName string
+	description string
+	bit         int
+}
+
+// getNamespacePermissions returns the permissions of a security namespace that are modeled as entitlements: either
+// one entitlement per action bit of the namespace, or its read and write permissions.
+func getNamespacePermissions(namespace security.SecurityNamespaceDescription, resource *v2.Resource, syncPermissionActions bool) []namespacePermission {
+	var permissions []namespacePermission
+
+	if syncPermissionActions {
+		if namespace.Actions == nil {
+			return nil
 		}
-		if len(groupIdentities) > 0 {
-			groupIdentity := groupIdentities[0]
-			if groupIdentity.Id != nil {
-				if _, isTeam := teamsMap[groupIdentity.Id.String()]; isTeam {
-					teamResource := &v2.Resource{
-						Id: &v2.ResourceId{
-							ResourceType: teamResourceType.Id,
-							Resource:     groupIdentity.Id.String(),
-						},
-					}
-					return teamResource, nil
-				} else {
-					groupResource := &v2.Resource{
-						Id: &v2.ResourceId{
-							ResourceType: groupResourceType.Id,
-							Resource:     groupIdentity.Id.String(),
-						},
-					}
-					return groupResource, nil
-				}
+		for _, action := range *namespace.Actions {
+			if action.Name == nil || action.Bit == nil || *action.Bit == 0 {
+				continue
+			}
+			displayName := *action.Name
+			if action.DisplayName != nil && *action.DisplayName != "" {
+				displayName = *action.DisplayName
 			}
+			permissions = append(permissions, namespacePermission{
+				action:      *action.Name,
+				displayName: displayName,
+				description: fmt.Sprintf("%s permission in %s security namespace at %s %s level", displayName, *namespace.Name, resource.DisplayName, resource.Id.ResourceType),
+				bit:         *action.Bit,
+			})
 		}
+		return permissions
 	}
-	return nil, errors.New("no identity resource found")
+
+	if namespace.ReadPermission != nil {
+		permissions = append(permissions, namespacePermission{
+			action:      "read",
+			displayName: getPermissionName(resource.DisplayName, *namespace.Name, "read"),
+			description: fmt.Sprintf("Read permission in %s security namespace at %s %s level", *namespace.Name, resource.DisplayName, resource.Id.ResourceType),
+			bit:         *namespace.ReadPermission,
+		})
+	}
+	if namespace.WritePermission != nil {
+		permissions = append(permissions, namespacePermission{
+			action:      "write",
+			displayName: getPermissionName(resource.DisplayName, *namespace.Name, "write"),
+			description: fmt.Sprintf("Write permission in %s security namespace at %s %s level", *namespace.Name, resource.DisplayName, resource.Id.ResourceType),
+			bit:         *namespace.WritePermission,
+		})
+	}
+
+	return permissions
 }
 
-func getEntitlementsFromSecurityNamespaces(namespaces []security.SecurityNamespaceDescription, resource *v2.Resource) []*v2.Entitlement {
+func getEntitlementsFromSecurityNamespaces(namespaces []security.SecurityNamespaceDescription, resource *v2.Resource, syncPermissionActions bool) []*v2.Entitlement {
 	var entitlements []*v2.Entitlement
 
 	for _, namespace := range namespaces {
-		readPermissionName := getPermissionName(resource.DisplayName, *namespace.Name, "read")
-		readOptions := []entitlement.EntitlementOption{
-			entitlement.WithGrantableTo(userResourceType, groupResourceType, teamResourceType),
-			entitlement.WithDescription(fmt.Sprintf("Read permission in %s security namespace at %s %s level", *namespace.Name, resource.DisplayName, resource.Id.ResourceType)),
-			entitlement.WithDisplayName(readPermissionName),
-		}
+		permissions := getNamespacePermissions(namespace, resource, syncPermissionActions)
+		entitlements = append(entitlements, getEntitlementsFromNamespacePermissions(namespace, permissions, resource)...)
+	}
+
+	return entitlements
+}
+
+// getEntitlementsFromNamespacePermissions returns an entitlement for each permission of a security namespace.
+func getEntitlementsFromNamespacePermissions(namespace security.SecurityNamespaceDescription, permissions []namespacePermission, resource *v2.Resource) []*v2.Entitlement {
+	var entitlements []*v2.Entitlement
 
-		writePermissionName := getPermissionName(resource.DisplayName, *namespace.Name, "write")
-		writeOptions := []entitlement.EntitlementOption{
+	for _, permission := range permissions {
+		options := []entitlement.EntitlementOption{
 			entitlement.WithGrantableTo(userResourceType, groupResourceType, teamResourceType),
-			entitlement.WithDescription(fmt.Sprintf("Write permission in %s security namespace at %s %s level", *namespace.Name, resource.DisplayName, resource.Id.ResourceType)),
-			entitlement.WithDisplayName(writePermissionName),
+			entitlement.WithDescription(permission.description),
+			entitlement.WithDisplayName(permission.displayName),
 		}
 
-		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(resource, readPermissionName, readOptions...))
-		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(resource, writePermissionName, writeOptions...))
+		permissionName := getPermissionName(resource.DisplayName, *namespace.Name, permission.action)
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(resource, permissionName, options...))
 	}
 
 	return entitlements
@@ -106,62 +213,250 @@ func getEntitlementsFromSecurityNamespaces(namespaces []security.SecurityNamespa
 
 func getGrantsFromSecurityNamespaces(
 	ctx context.Context,
-	client *client.AzureDevOpsClient,
-	users map[string]string,
+	org *organization,
 	namespaces []security.SecurityNamespaceDescription,
 	resource *v2.Resource,
 ) ([]*v2.Grant, error) {
 	var grants []*v2.Grant
 
 	for _, namespace := range namespaces {
-		readPermissionBit := *namespace.ReadPermission
-		writePermissionBit := *namespace.WritePermission
+		permissions := getNamespacePermissions(namespace, resource, org.client.SyncPermissionActions)
+		namespaceGrants, err := getGrantsFromNamespacePermissions(ctx, org, namespace, permissions, resource)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, namespaceGrants...)
+	}
 
-		ACLs, err := client.ListAccessControlsBySecurityNamespace(ctx, *namespace.NamespaceId, parseTokenBySecurityNamespace(namespace.NamespaceId.String(), resource))
+	return grants, nil
+}
+
+// getGrantsFromNamespacePermissions returns a grant for each permission of a security namespace that the ACEs of the
+// resource's token effectively allow.
+func getGrantsFromNamespacePermissions(
+	ctx context.Context,
+	org *organization,
+	namespace security.SecurityNamespaceDescription,
+	permissions []namespacePermission,
+	resource *v2.Resource,
+) ([]*v2.Grant, error) {
+	var grants []*v2.Grant
+
+	token, err := getSecurityToken(ctx, org.client, namespace.NamespaceId.String(), resource)
+	if err != nil {
+		return nil, err
+	}
+
+	ACLs, err := org.client.ListAccessControlsBySecurityNamespace(ctx, *namespace.NamespaceId, token)
+	if err != nil {
+		return nil, err
+	}
+	for _, acl := range ACLs {
+		if acl.AcesDictionary == nil {
+			continue
+		}
+		var aces []security.AccessControlEntry
+		for _, ace := range *acl.AcesDictionary {
+			aces = append(aces, ace)
+		}
+		aceGrants, err := getGrantsFromAccessControlEntries(ctx, org, namespace, permissions, token, resource, aces)
 		if err != nil {
 			return nil, err
 		}
-		for _, acl := range ACLs {
-			for _, ace := range *acl.AcesDictionary {
-				grantResource, err := getIdentityResourceByDescriptor(client, ctx, *ace.Descriptor, users)
+		grants = append(grants, aceGrants...)
+	}
+
+	return grants, nil
+}
+
+// getGrantsFromAccessControlEntries returns a grant for each permission that an ACE of the token effectively allows.
+func getGrantsFromAccessControlEntries(
+	ctx context.Context,
+	org *organization,
+	namespace security.SecurityNamespaceDescription,
+	permissions []namespacePermission,
+	token string,
+	resource *v2.Resource,
+	aces []security.AccessControlEntry,
+) ([]*v2.Grant, error) {
+	var descriptors []string
+	for _, ace := range aces {
+		if ace.Descriptor != nil {
+			descriptors = append(descriptors, *ace.Descriptor)
+		}
+	}
+	identityResources, err := getIdentityResourcesByDescriptor(ctx, org, descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	// The members of a group are only read when a deny keeps its grants from being expanded, once per group.
+	members := make(map[string][]groupMember)
+	membersOf := func(descriptor string) ([]groupMember, error) {
+		if groupMembers, ok := members[strings.ToLower(descriptor)]; ok {
+			return groupMembers, nil
+		}
+		groupMembers, err := getExpandedGroupMembers(ctx, org, descriptor)
+		if err != nil {
+			return nil, err
+		}
+		members[strings.ToLower(descriptor)] = groupMembers
+		return groupMembers, nil
+	}
+
+	return getGrantsFromIdentityEntries(*namespace.Name, permissions, token, resource, aces, identityResources, org.client.SyncGrantSources, membersOf)
+}
+
+// groupMember is a user that is a direct or nested member of a group, with the identity descriptor that access
+// control entries refer to it by.
+type groupMember struct {
+	descriptor string
+	principal  *v2.ResourceId
+}
+
+// getExpandedGroupMembers returns the users that are direct or nested members of a group, by identity descriptor.
+func getExpandedGroupMembers(ctx context.Context, org *organization, descriptor string) ([]groupMember, error) {
+	memberIDs, err := org.client.ListExpandedMemberIDs(ctx, descriptor)
+	if err != nil {
+		return nil, err
+	}
+	if len(memberIDs) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		ids = append(ids, memberID.String())
+	}
+	identities, err := org.identities.identitiesByID(ctx, org.client, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var groupMembers []groupMember
+	for _, id := range ids {
+		member, ok := identities[strings.ToLower(id)]
+		if !ok || member.Descriptor == nil {
+			continue
+		}
+		// Nested groups are expanded already, so only their users are kept.
+		principal := getMemberResourceID(member, nil)
+		if principal == nil || principal.ResourceType != userResourceType.Id {
+			continue
+		}
+		groupMembers = append(groupMembers, groupMember{descriptor: *member.Descriptor, principal: principal})
+	}
+
+	return groupMembers, nil
+}
+
+// getGrantsFromIdentityEntries returns a grant for each permission that an ACE effectively allows to the user, group
+// or team of its descriptor. With grant sources synced, the grants of groups and teams are expanded to their members.
+// A deny only applies to the identities it is set on and their members though, so when an ACE of the token denies a
+// permission, the grants of that permission are not expanded: the members of the allowed groups are granted one by
+// one instead, leaving out the denied identities.
+func getGrantsFromIdentityEntries(
+	namespaceName string,
+	permissions []namespacePermission,
+	token string,
+	resource *v2.Resource,
+	aces []security.AccessControlEntry,
+	identityResources map[string]*v2.Resource,
+	syncGrantSources bool,
+	membersOf func(descriptor string) ([]groupMember, error),
+) ([]*v2.Grant, error) {
+	var grants []*v2.Grant
+
+	deniedByPermission := make(map[int]map[string]bool)
+	denied := func(bit int) (map[string]bool, error) {
+		if deniedDescriptors, ok := deniedByPermission[bit]; ok {
+			return deniedDescriptors, nil
+		}
+		deniedDescriptors := make(map[string]bool)
+		for _, ace := range aces {
+			if ace.Descriptor == nil || (intValue(ace.Deny)|effectiveDeny(ace))&bit == 0 {
+				continue
+			}
+			deniedDescriptors[strings.ToLower(*ace.Descriptor)] = true
+			if deniedResource, ok := identityResources[*ace.Descriptor]; ok && deniedResource.Id.ResourceType != userResourceType.Id {
+				deniedMembers, err := membersOf(*ace.Descriptor)
 				if err != nil {
-					continue
-				}
-				var basicGrantOptions []grant.GrantOption
-
-				if client.SyncGrantSources && grantResource.Id.ResourceType != userResourceType.Id {
-					basicGrantOptions = append(basicGrantOptions, grant.WithAnnotation(&v2.GrantExpandable{
-						EntitlementIds: []string{
-							fmt.Sprintf("team:%s:member", grantResource.Id.Resource),
-							fmt.Sprintf("group:%s:member", grantResource.Id.Resource),
-							fmt.Sprintf("group:%s:admin", grantResource.Id.Resource),
-						},
-						Shallow: true,
-					}))
-				}
-				effectiveAllow := *ace.Allow
-				if ace.ExtendedInfo.EffectiveAllow != nil {
-					effectiveAllow = *ace.ExtendedInfo.EffectiveAllow
+					return nil, err
 				}
-				if readPermissionBit != 0 && effectiveAllow&readPermissionBit == readPermissionBit {
-					// add grant for read
-					grants = append(grants, grant.NewGrant(
-						resource,
-						getPermissionName(resource.DisplayName, *namespace.Name, "read"),
-						grantResource,
-						basicGrantOptions...,
-					))
+				for _, member := range deniedMembers {
+					deniedDescriptors[strings.ToLower(member.descriptor)] = true
 				}
+			}
+		}
+		deniedByPermission[bit] = deniedDescriptors
+		return deniedDescriptors, nil
+	}
+
+	// The members granted one by one, by permission, so that members of several allowed groups are granted once.
+	grantedMembers := make(map[int]map[string]bool)
+
+	for _, ace := range aces {
+		if ace.Descriptor == nil {
+			continue
+		}
+		grantResource, ok := identityResources[*ace.Descriptor]
+		if !ok {
+			continue
+		}
+		expandable := syncGrantSources && grantResource.Id.ResourceType != userResourceType.Id
+
+		for _, permission := range permissions {
+			source, allowed := getPermissionSource(ace, permission.bit)
+			if !allowed {
+				continue
+			}
+			permissionName := getPermissionName(resource.DisplayName, namespaceName, permission.action)
+			metadata := grant.WithGrantMetadata(map[string]interface{}{
+				"permission_source":  source,
+				"security_namespace": namespaceName,
+				"security_token":     token,
+			})
+			if !expandable {
+				grants = append(grants, grant.NewGrant(resource, permissionName, grantResource, metadata))
+				continue
+			}
 
-				if writePermissionBit != 0 && effectiveAllow&writePermissionBit == writePermissionBit {
-					// add grant for write
-					grants = append(grants, grant.NewGrant(
-						resource,
-						getPermissionName(resource.DisplayName, *namespace.Name, "write"),
-						grantResource,
-						basicGrantOptions...,
-					))
+			deniedDescriptors, err := denied(permission.bit)
+			if err != nil {
+				return nil, err
+			}
+			if len(deniedDescriptors) == 0 {
+				grants = append(grants, grant.NewGrant(resource, permissionName, grantResource, metadata, grant.WithAnnotation(&v2.GrantExpandable{
+					EntitlementIds: []string{
+						fmt.Sprintf("team:%s:member", grantResource.Id.Resource),
+						fmt.Sprintf("group:%s:member", grantResource.Id.Resource),
+						fmt.Sprintf("group:%s:admin", grantResource.Id.Resource),
+					},
+					Shallow: true,
+				})))
+				continue
+			}
+
+			grants = append(grants, grant.NewGrant(resource, permissionName, grantResource, metadata))
+			groupMembers, err := membersOf(*ace.Descriptor)
+			if err != nil {
+				return nil, err
+			}
+			if grantedMembers[permission.bit] == nil {
+				grantedMembers[permission.bit] = make(map[string]bool)
+			}
+			for _, member := range groupMembers {
+				descriptor := strings.ToLower(member.descriptor)
+				// Members with an ACE of their own are granted through it.
+				if _, ok := identityResources[member.descriptor]; ok || deniedDescriptors[descriptor] || grantedMembers[permission.bit][descriptor] {
+					continue
 				}
+				grantedMembers[permission.bit][descriptor] = true
+				grants = append(grants, grant.NewGrant(resource, permissionName, member.principal, grant.WithGrantMetadata(map[string]interface{}{
+					"permission_source":  permissionSourceGroupMembership,
+					"security_namespace": namespaceName,
+					"security_token":     token,
+				})))
 			}
 		}
 	}
@@ -169,27 +464,432 @@ func getGrantsFromSecurityNamespaces(
 	return grants, nil
 }
 
-func parseTokenBySecurityNamespace(securityNamespace string, resource *v2.Resource) string {
-	switch securityNamespace {
-	case projectSecurityNamespace:
-		return fmt.Sprintf("$PROJECT:vstfs:///Classification/TeamProject/%s", resource.Id.Resource)
-	case taggingSecurityNamespace:
-		return fmt.Sprintf("/%s", resource.Id.Resource)
-	case versionControlItemsSecurityNamespace:
-		return fmt.Sprintf("$/%s", resource.DisplayName)
-	case analyticsViewsSecurityNamespace:
-		return fmt.Sprintf("$/Shared/%s", resource.Id.Resource)
-	case buildSecurityNamespace:
-		return resource.Id.Resource
-	case gitRepositoriesSecurityNamespace:
-		if resource.ParentResourceId != nil {
-			return fmt.Sprintf("repoV2/%s/%s", resource.ParentResourceId.Resource, resource.Id.Resource)
-		}
-		return fmt.Sprintf("repoV2/%s", resource.Id.Resource)
-	case metaTaskSecurityNamespace:
-		return resource.Id.Resource
-	case releaseManagementSecurityNamespace:
-		return resource.Id.Resource
-	}
-	return ""
+const (
+	// The permission is explicitly allowed on the token for the identity.
+	permissionSourceExplicit = "explicit"
+	// The permission is allowed on a parent token and inherited by the token.
+	permissionSourceInherited = "inherited"
+	// The permission is allowed to a group the identity is a member of.
+	permissionSourceGroupMembership = "group_membership"
+)
+
+// getPermissionSource evaluates whether an ACE gives effective access to a permission bit and where that access
+// comes from. An explicit or effective deny always wins over an allow.
+func getPermissionSource(ace security.AccessControlEntry, bit int) (string, bool) {
+	if bit == 0 {
+		return "", false
+	}
+
+	allow := intValue(ace.Allow)
+	deny := intValue(ace.Deny)
+	effectiveAllow := allow
+	inheritedAllow := 0
+	if ace.ExtendedInfo != nil {
+		if ace.ExtendedInfo.EffectiveAllow != nil {
+			effectiveAllow = *ace.ExtendedInfo.EffectiveAllow
+		}
+		inheritedAllow = intValue(ace.ExtendedInfo.InheritedAllow)
+	}
+
+	if (deny|effectiveDeny(ace))&bit != 0 || effectiveAllow&bit != bit {
+		return "", false
+	}
+
+	switch {
+	case allow&bit == bit:
+		return permissionSourceExplicit, true
+	case inheritedAllow&bit == bit:
+		return permissionSourceInherited, true
+	default:
+		return permissionSourceGroupMembership, true
+	}
+}
+
+// effectiveDeny returns the permissions an ACE effectively denies, which are its explicit denies unless Azure DevOps
+// returned the extended information of the ACE.
+func effectiveDeny(ace security.AccessControlEntry) int {
+	if ace.ExtendedInfo != nil && ace.ExtendedInfo.EffectiveDeny != nil {
+		return *ace.ExtendedInfo.EffectiveDeny
+	}
+	return intValue(ace.Deny)
+}
+
+func intValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// withRateLimit annotates a response with a rate limit state, unless Azure DevOps reported none.
+func withRateLimit(annos annotations.Annotations, description *v2.RateLimitDescription) annotations.Annotations {
+	if description == nil {
+		return annos
+	}
+
+	annos.WithRateLimiting(description)
+	return annos
+}
+
+// getIdentityDescriptor resolves the identity descriptor, as used in access control entries, of a user, group or team.
+func getIdentityDescriptor(ctx context.Context, client client.AzureDevOpsClientInterface, principal *v2.Resource) (string, error) {
+	identityID, err := getIdentityID(ctx, client, principal)
+	if err != nil {
+		return "", err
+	}
+
+	identities, err := client.ListIdentities(ctx, identityID, "")
+	if err != nil {
+		return "", err
+	}
+	if len(identities) == 0 || identities[0].Descriptor == nil {
+		return "", fmt.Errorf("no identity found for %s %s", principal.Id.ResourceType, principal.Id.Resource)
+	}
+
+	return *identities[0].Descriptor, nil
+}
+
+// getSubjectDescriptor resolves the subject descriptor, as used by the graph API, of a user, group or team. Users are
+// identified by their subject descriptor and teams by their identity id. Groups are identified by their origin id,
+// which is the object id of Microsoft Entra groups, so their descriptor is read from their profile.
+func getSubjectDescriptor(ctx context.Context, client client.AzureDevOpsClientInterface, principal *v2.Resource) (string, error) {
+	if descriptor, ok := getGroupProfileDescriptor(principal); ok {
+		return descriptor, nil
+	}
+
+	principalID, err := uuid.Parse(principal.Id.Resource)
+	if err != nil {
+		return principal.Id.Resource, nil
+	}
+
+	return client.GetDescriptor(ctx, principalID)
+}
+
+// getIdentityID resolves the identity id of a user, group or team, as used in role assignments and by the identity
+// API. It is the storage key of the subject descriptor of users and groups, and the id of teams.
+func getIdentityID(ctx context.Context, client client.AzureDevOpsClientInterface, principal *v2.Resource) (string, error) {
+	descriptor, ok := getGroupProfileDescriptor(principal)
+	if !ok {
+		if _, err := uuid.Parse(principal.Id.Resource); err == nil {
+			return principal.Id.Resource, nil
+		}
+		descriptor = principal.Id.Resource
+	}
+
+	storageKey, err := client.GetStorageKey(ctx, descriptor)
+	if err != nil {
+		return "", err
+	}
+
+	return storageKey.String(), nil
+}
+
+// getGroupProfileDescriptor returns the subject descriptor that the profile of a group resource holds.
+func getGroupProfileDescriptor(principal *v2.Resource) (string, bool) {
+	if principal.Id.ResourceType != groupResourceType.Id {
+		return "", false
+	}
+	groupTrait, err := resource.GetGroupTrait(principal)
+	if err != nil {
+		return "", false
+	}
+	descriptor, ok := resource.GetProfileStringValue(groupTrait.GetProfile(), "descriptor")
+
+	return descriptor, ok && descriptor != ""
+}
+
+// getSecurityNamespacePermission returns the security namespace and the permission bit targeted by an entitlement
+// built by getEntitlementsFromSecurityNamespaces, either a read/write permission or a single action.
+// Entitlements are named <resource>_<namespace>_<action>; resource names and action names may contain underscores
+// but namespace names do not, so the namespace name is used to split the entitlement name.
+func getSecurityNamespacePermission(
+	namespaces []security.SecurityNamespaceDescription,
+	entitlementResource *v2.Entitlement,
+) (*security.SecurityNamespaceDescription, int, error) {
+	permissionName := entitlementResource.Slug
+	if permissionName == "" {
+		permissionName = entitlementResource.DisplayName
+	}
+
+	// Resource names may contain the name of another namespace, e.g. a repository named web_Project_api, so a namespace
+	// whose name is found without one of its actions following it does not rule out the other namespaces.
+	var unsupportedErr error
+	for _, namespace := range namespaces {
+		if namespace.Name == nil {
+			continue
+		}
+		separator := fmt.Sprintf("_%s_", *namespace.Name)
+		index := strings.LastIndex(permissionName, separator)
+		if index < 0 {
+			continue
+		}
+		action := permissionName[index+len(separator):]
+
+		permissions := getNamespacePermissions(namespace, entitlementResource.Resource, false)
+		permissions = append(permissions, getNamespacePermissions(namespace, entitlementResource.Resource, true)...)
+		for _, permission := range permissions {
+			if permission.action == action {
+				if permission.bit == 0 {
+					return nil, 0, fmt.Errorf("security namespace %s has no %s permission", *namespace.Name, action)
+				}
+				return &namespace, permission.bit, nil
+			}
+		}
+		if unsupportedErr == nil {
+			unsupportedErr = fmt.Errorf("unsupported permission '%s' for security namespace %s", action, *namespace.Name)
+		}
+	}
+	if unsupportedErr != nil {
+		return nil, 0, unsupportedErr
+	}
+
+	return nil, 0, fmt.Errorf("no security namespace found for permission '%s'", permissionName)
+}
+
+// grantSecurityNamespacePermission sets the permission bit of an entitlement as an explicit allow on the principal's ACE.
+func grantSecurityNamespacePermission(
+	ctx context.Context,
+	client client.AzureDevOpsClientInterface,
+	namespaces []security.SecurityNamespaceDescription,
+	principal *v2.Resource,
+	entitlementResource *v2.Entitlement,
+) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	namespace, permissionBit, err := getSecurityNamespacePermission(namespaces, entitlementResource)
+	if err != nil {
+		return nil, err
+	}
+	token, err := getSecurityToken(ctx, client, namespace.NamespaceId.String(), entitlementResource.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := getIdentityDescriptor(ctx, client, principal)
+	if err != nil {
+		l.Debug("Error getting principal identity descriptor", zap.Error(err))
+		return nil, err
+	}
+
+	ace, err := client.GetAccessControlEntry(ctx, *namespace.NamespaceId, token, descriptor)
+	if err != nil {
+		return nil, err
+	}
+	if ace != nil && ace.Allow != nil && *ace.Allow&permissionBit == permissionBit {
+		l.Info("Permission is already allowed; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	err = client.SetAccessControlEntry(ctx, *namespace.NamespaceId, token, descriptor, permissionBit, 0)
+	if err != nil {
+		l.Debug("Error setting access control entry", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// revokeSecurityNamespacePermission clears the permission bit of an entitlement from the principal's ACE.
+// Permissions inherited from a parent token or from group membership are not affected.
+func revokeSecurityNamespacePermission(
+	ctx context.Context,
+	client client.AzureDevOpsClientInterface,
+	namespaces []security.SecurityNamespaceDescription,
+	grantResource *v2.Grant,
+) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	namespace, permissionBit, err := getSecurityNamespacePermission(namespaces, grantResource.Entitlement)
+	if err != nil {
+		return nil, err
+	}
+	token, err := getSecurityToken(ctx, client, namespace.NamespaceId.String(), grantResource.Entitlement.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := getIdentityDescriptor(ctx, client, grantResource.Principal)
+	if err != nil {
+		l.Debug("Error getting principal identity descriptor", zap.Error(err))
+		return nil, err
+	}
+
+	ace, err := client.GetAccessControlEntry(ctx, *namespace.NamespaceId, token, descriptor)
+	if err != nil {
+		return nil, err
+	}
+	if ace == nil || ace.Allow == nil || *ace.Allow&permissionBit == 0 {
+		l.Info("Permission to revoke is not allowed; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err = client.RemovePermission(ctx, *namespace.NamespaceId, token, descriptor, permissionBit)
+	if err != nil {
+		l.Debug("Error removing permission", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// pipelineAuthorizedPermission is the entitlement of the pipelines authorized to consume a protected resource, such as
+// a variable group or a secure file.
+var pipelineAuthorizedPermission = "authorized"
+
+// getPipelineAuthorizedEntitlement returns the entitlement of the pipelines authorized to consume a protected resource.
+// The project is granted the entitlement when every pipeline of the project is authorized.
+func getPipelineAuthorizedEntitlement(resource *v2.Resource) *v2.Entitlement {
+	options := []entitlement.EntitlementOption{
+		entitlement.WithGrantableTo(pipelineResourceType, projectResourceType),
+		entitlement.WithDescription(fmt.Sprintf("Pipelines authorized to use %s %s", resource.DisplayName, resource.Id.ResourceType)),
+		entitlement.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, pipelineAuthorizedPermission)),
+	}
+
+	return entitlement.NewPermissionEntitlement(resource, pipelineAuthorizedPermission, options...)
+}
+
+// getPipelineAuthorizedGrants returns a grant for each pipeline of the project that is authorized to consume a
+// protected resource, or a grant to the project when all of its pipelines are.
+func getPipelineAuthorizedGrants(ctx context.Context, client *client.AzureDevOpsClient, projectID, resourceType, resourceID string, resource *v2.Resource) ([]*v2.Grant, error) {
+	permissions, err := client.GetPipelinePermissions(ctx, projectID, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipelinePermissionGrants(projectID, resource, permissions), nil
+}
+
+func pipelinePermissionGrants(projectID string, resource *v2.Resource, permissions *pipelinepermissions.ResourcePipelinePermissions) []*v2.Grant {
+	var grants []*v2.Grant
+
+	if allPipelinesAuthorized(permissions) {
+		grants = append(grants, grant.NewGrant(resource, pipelineAuthorizedPermission, &v2.ResourceId{
+			ResourceType: projectResourceType.Id,
+			Resource:     projectID,
+		}))
+	}
+
+	for _, pipelineID := range authorizedPipelineIDs(permissions) {
+		grants = append(grants, grant.NewGrant(resource, pipelineAuthorizedPermission, &v2.ResourceId{
+			ResourceType: pipelineResourceType.Id,
+			Resource:     fmt.Sprintf("%s/%d", projectID, pipelineID),
+		}))
+	}
+
+	return grants
+}
+
+// getPipelinePermissionsProfile returns which pipelines of the project are authorized to consume a protected resource,
+// as profile data of that resource. Pipelines are referenced by their pipeline resource id.
+func getPipelinePermissionsProfile(ctx context.Context, client *client.AzureDevOpsClient, projectID, resourceType, resourceID string) (map[string]interface{}, error) {
+	permissions, err := client.GetPipelinePermissions(ctx, projectID, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizedPipelines := []interface{}{}
+	for _, pipelineID := range authorizedPipelineIDs(permissions) {
+		authorizedPipelines = append(authorizedPipelines, fmt.Sprintf("%s/%d", projectID, pipelineID))
+	}
+
+	return map[string]interface{}{
+		"all_pipelines_authorized": allPipelinesAuthorized(permissions),
+		"authorized_pipelines":     authorizedPipelines,
+	}, nil
+}
+
+func allPipelinesAuthorized(permissions *pipelinepermissions.ResourcePipelinePermissions) bool {
+	return permissions.AllPipelines != nil && permissions.AllPipelines.Authorized != nil && *permissions.AllPipelines.Authorized
+}
+
+func authorizedPipelineIDs(permissions *pipelinepermissions.ResourcePipelinePermissions) []int {
+	var pipelineIDs []int
+	if permissions.Pipelines == nil {
+		return pipelineIDs
+	}
+
+	for _, pipeline := range *permissions.Pipelines {
+		if pipeline.Id == nil || pipeline.Authorized == nil || !*pipeline.Authorized {
+			continue
+		}
+		pipelineIDs = append(pipelineIDs, *pipeline.Id)
+	}
+
+	return pipelineIDs
+}
+
+// grantPipelineAuthorization authorizes a pipeline, or every pipeline of the project when the principal is the
+// project, to consume a protected resource identified by projectId$id.
+func grantPipelineAuthorization(ctx context.Context, client client.AzureDevOpsClientInterface, resourceType string, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	return setPipelineAuthorization(ctx, client, resourceType, principal, entitlementResource.Resource, true)
+}
+
+// revokePipelineAuthorization removes the authorization of a pipeline, or of every pipeline of the project, to
+// consume a protected resource. Pipelines authorized individually keep their authorization when the project's is
+// revoked.
+func revokePipelineAuthorization(ctx context.Context, client client.AzureDevOpsClientInterface, resourceType string, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return setPipelineAuthorization(ctx, client, resourceType, grantResource.Principal, grantResource.Entitlement.Resource, false)
+}
+
+func setPipelineAuthorization(
+	ctx context.Context,
+	client client.AzureDevOpsClientInterface,
+	resourceType string,
+	principal *v2.Resource,
+	resource *v2.Resource,
+	authorized bool,
+) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	projectID, resourceID, found := strings.Cut(resource.Id.Resource, "$")
+	if !found {
+		return nil, fmt.Errorf("invalid %s id '%s'", resource.Id.ResourceType, resource.Id.Resource)
+	}
+
+	var principalProjectID string
+	update := &pipelinepermissions.ResourcePipelinePermissions{}
+	switch principal.Id.ResourceType {
+	case projectResourceType.Id:
+		principalProjectID = principal.Id.Resource
+		update.AllPipelines = &pipelinepermissions.Permission{Authorized: &authorized}
+	case pipelineResourceType.Id:
+		var pipelineID int
+		var err error
+		principalProjectID, pipelineID, err = parsePipelineID(principal.Id.Resource)
+		if err != nil {
+			return nil, err
+		}
+		update.Pipelines = &[]pipelinepermissions.PipelinePermission{{Id: &pipelineID, Authorized: &authorized}}
+	default:
+		return nil, fmt.Errorf("only pipelines and projects can be authorized to use a %s", resource.Id.ResourceType)
+	}
+	if principalProjectID != projectID {
+		return nil, fmt.Errorf("%s %s does not belong to the project of %s %s", principal.Id.ResourceType, principal.Id.Resource, resource.Id.ResourceType, resource.Id.Resource)
+	}
+
+	permissions, err := client.GetPipelinePermissions(ctx, projectID, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	isAuthorized := allPipelinesAuthorized(permissions)
+	if update.Pipelines != nil {
+		isAuthorized = slices.Contains(authorizedPipelineIDs(permissions), *(*update.Pipelines)[0].Id)
+	}
+	if isAuthorized == authorized {
+		if authorized {
+			l.Info("Pipeline authorization already exists; treating as successful because the end state is achieved")
+			return annotations.New(&v2.GrantAlreadyExists{}), nil
+		}
+		l.Info("Pipeline authorization to revoke not found; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err = client.UpdatePipelinePermissions(ctx, projectID, resourceType, resourceID, update)
+	if err != nil {
+		l.Debug("Error updating pipeline permissions", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
 }
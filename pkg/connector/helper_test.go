@@ -0,0 +1,607 @@
+package connector
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/pipelinepermissions"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPermissionSource(t *testing.T) {
+	const readBit = 1
+	const writeBit = 2
+	intPtr := func(i int) *int { return &i }
+
+	testCases := []struct {
+		name           string
+		ace            security.AccessControlEntry
+		bit            int
+		expectedSource string
+		expectedAllow  bool
+	}{
+		{
+			name:           "explicit allow",
+			ace:            security.AccessControlEntry{Allow: intPtr(readBit | writeBit), Deny: intPtr(0)},
+			bit:            writeBit,
+			expectedSource: permissionSourceExplicit,
+			expectedAllow:  true,
+		},
+		{
+			name: "explicit deny wins over group allow",
+			ace: security.AccessControlEntry{
+				Allow: intPtr(0),
+				Deny:  intPtr(writeBit),
+				ExtendedInfo: &security.AceExtendedInformation{
+					EffectiveAllow: intPtr(readBit | writeBit),
+					EffectiveDeny:  intPtr(0),
+				},
+			},
+			bit:           writeBit,
+			expectedAllow: false,
+		},
+		{
+			name: "effective deny wins over explicit allow",
+			ace: security.AccessControlEntry{
+				Allow: intPtr(writeBit),
+				Deny:  intPtr(0),
+				ExtendedInfo: &security.AceExtendedInformation{
+					EffectiveAllow: intPtr(writeBit),
+					EffectiveDeny:  intPtr(writeBit),
+				},
+			},
+			bit:           writeBit,
+			expectedAllow: false,
+		},
+		{
+			name: "inherited from parent token",
+			ace: security.AccessControlEntry{
+				Allow: intPtr(0),
+				Deny:  intPtr(0),
+				ExtendedInfo: &security.AceExtendedInformation{
+					EffectiveAllow: intPtr(readBit),
+					InheritedAllow: intPtr(readBit),
+				},
+			},
+			bit:            readBit,
+			expectedSource: permissionSourceInherited,
+			expectedAllow:  true,
+		},
+		{
+			name: "allowed through group membership",
+			ace: security.AccessControlEntry{
+				Allow: intPtr(0),
+				Deny:  intPtr(0),
+				ExtendedInfo: &security.AceExtendedInformation{
+					EffectiveAllow: intPtr(readBit),
+				},
+			},
+			bit:            readBit,
+			expectedSource: permissionSourceGroupMembership,
+			expectedAllow:  true,
+		},
+		{
+			name:          "not allowed",
+			ace:           security.AccessControlEntry{Allow: intPtr(readBit)},
+			bit:           writeBit,
+			expectedAllow: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			source, allowed := getPermissionSource(tc.ace, tc.bit)
+			assert.Equal(t, tc.expectedAllow, allowed)
+			assert.Equal(t, tc.expectedSource, source)
+		})
+	}
+}
+
+func TestGetMemberResourceID(t *testing.T) {
+	newIdentity := func(isContainer bool, subjectDescriptor string, properties map[string]interface{}) *identity.Identity {
+		id := uuid.New()
+		return &identity.Identity{Id: &id, IsContainer: &isContainer, SubjectDescriptor: &subjectDescriptor, Properties: properties}
+	}
+
+	user := newIdentity(false, "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx", nil)
+	servicePrincipal := newIdentity(false, "aadsp.ZjIxN2FjM2ItNzA4YS00ZGI1LWI2YzMtODA0MDhhZmVkOWM3", nil)
+	group := newIdentity(true, "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5", nil)
+	entraGroup := newIdentity(true, "aadgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5", map[string]interface{}{
+		entraObjectIDProperty: map[string]interface{}{"$type": "System.String", "$value": "0c3f4c5e-7f2d-4b4e-9d2b-6e1f0a8b9c7d"},
+	})
+	team := newIdentity(true, "vssgp.Uy0xLTktMTU1MTM3NDI0NS0yMDQ4", nil)
+	unknown := newIdentity(false, "", nil)
+	teamIDs := map[string]bool{team.Id.String(): true}
+
+	testCases := []struct {
+		name     string
+		member   *identity.Identity
+		expected *v2.ResourceId
+	}{
+		{
+			name:     "user",
+			member:   user,
+			expected: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: *user.SubjectDescriptor},
+		},
+		{
+			name:     "service principal",
+			member:   servicePrincipal,
+			expected: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: *servicePrincipal.SubjectDescriptor},
+		},
+		{
+			name:     "group",
+			member:   group,
+			expected: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: group.Id.String()},
+		},
+		{
+			name:     "entra group",
+			member:   entraGroup,
+			expected: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "0c3f4c5e-7f2d-4b4e-9d2b-6e1f0a8b9c7d"},
+		},
+		{
+			name:     "team",
+			member:   team,
+			expected: &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: team.Id.String()},
+		},
+		{
+			name:   "identity without subject descriptor",
+			member: unknown,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, getMemberResourceID(tc.member, teamIDs))
+		})
+	}
+}
+
+func TestGetGrantsFromIdentityEntries(t *testing.T) {
+	const readBit = 1
+	const writeBit = 2
+	intPtr := func(i int) *int { return &i }
+	strPtr := func(s string) *string { return &s }
+
+	permissions := []namespacePermission{
+		{action: "read", bit: readBit},
+		{action: "write", bit: writeBit},
+	}
+	repository := &v2.Resource{
+		Id:          &v2.ResourceId{ResourceType: "repository", Resource: "contoso/fabrikam"},
+		DisplayName: "fabrikam",
+	}
+	contributors := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "contributors"}}
+	deniedUser := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "aad.denied"}}
+	allowedUser := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "aad.allowed"}
+	identityResources := map[string]*v2.Resource{
+		"Microsoft.TeamFoundation.Identity;contributors": contributors,
+		"Microsoft.TeamFoundation.Identity;denied":       deniedUser,
+	}
+	membersOf := func(descriptor string) ([]groupMember, error) {
+		require.Equal(t, "Microsoft.TeamFoundation.Identity;contributors", descriptor)
+		return []groupMember{
+			{descriptor: "Microsoft.TeamFoundation.Identity;denied", principal: deniedUser.Id},
+			{descriptor: "Microsoft.TeamFoundation.Identity;allowed", principal: allowedUser},
+		}, nil
+	}
+
+	grantsByID := func(grants []*v2.Grant) map[string]*v2.Grant {
+		byID := make(map[string]*v2.Grant)
+		for _, g := range grants {
+			byID[g.Id] = g
+		}
+		return byID
+	}
+	isExpandable := func(g *v2.Grant) bool {
+		annos := annotations.Annotations(g.Annotations)
+		return annos.Contains(&v2.GrantExpandable{})
+	}
+
+	t.Run("group grants are expanded when nothing is denied", func(t *testing.T) {
+		aces := []security.AccessControlEntry{
+			{Descriptor: strPtr("Microsoft.TeamFoundation.Identity;contributors"), Allow: intPtr(readBit | writeBit), Deny: intPtr(0)},
+		}
+
+		grants, err := getGrantsFromIdentityEntries("Git Repositories", permissions, "repoV2/fabrikam", repository, aces, identityResources, true, membersOf)
+		require.NoError(t, err)
+		require.Len(t, grants, 2)
+		for _, g := range grants {
+			assert.Equal(t, contributors.Id.Resource, g.Principal.Id.Resource)
+			assert.True(t, isExpandable(g))
+		}
+	})
+
+	t.Run("members denied a permission do not inherit it from their group", func(t *testing.T) {
+		aces := []security.AccessControlEntry{
+			{Descriptor: strPtr("Microsoft.TeamFoundation.Identity;contributors"), Allow: intPtr(readBit | writeBit), Deny: intPtr(0)},
+			{Descriptor: strPtr("Microsoft.TeamFoundation.Identity;denied"), Allow: intPtr(0), Deny: intPtr(writeBit)},
+		}
+
+		grants, err := getGrantsFromIdentityEntries("Git Repositories", permissions, "repoV2/fabrikam", repository, aces, identityResources, true, membersOf)
+		require.NoError(t, err)
+		byID := grantsByID(grants)
+		require.Len(t, byID, 3)
+
+		// Read is not denied to anyone, so the group grant is still expanded.
+		read := byID["repository:contoso/fabrikam:fabrikam_Git Repositories_read:group:contributors"]
+		require.NotNil(t, read)
+		assert.True(t, isExpandable(read))
+
+		write := byID["repository:contoso/fabrikam:fabrikam_Git Repositories_write:group:contributors"]
+		require.NotNil(t, write)
+		assert.False(t, isExpandable(write))
+
+		memberWrite := byID["repository:contoso/fabrikam:fabrikam_Git Repositories_write:user:aad.allowed"]
+		require.NotNil(t, memberWrite)
+		metadata := &v2.GrantMetadata{}
+		annos := annotations.Annotations(memberWrite.Annotations)
+		ok, err := annos.Pick(metadata)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, permissionSourceGroupMembership, metadata.Metadata.AsMap()["permission_source"])
+
+		assert.NotContains(t, byID, "repository:contoso/fabrikam:fabrikam_Git Repositories_write:user:aad.denied")
+	})
+}
+
+func newSecurityNamespace(id, name string, readBit, writeBit int, actions map[string]int) security.SecurityNamespaceDescription {
+	namespaceID := uuid.MustParse(id)
+	var actionDefinitions []security.ActionDefinition
+	for action, bit := range actions {
+		actionName, actionBit := action, bit
+		actionDefinitions = append(actionDefinitions, security.ActionDefinition{Name: &actionName, Bit: &actionBit})
+	}
+	return security.SecurityNamespaceDescription{
+		NamespaceId:     &namespaceID,
+		Name:            &name,
+		ReadPermission:  &readBit,
+		WritePermission: &writeBit,
+		Actions:         &actionDefinitions,
+	}
+}
+
+func TestGetSecurityNamespacePermission(t *testing.T) {
+	namespaces := []security.SecurityNamespaceDescription{
+		newSecurityNamespace(projectSecurityNamespace, "Project", 1, 2, map[string]int{"GENERIC_READ": 1, "GENERIC_WRITE": 2, "DELETE": 4}),
+		newSecurityNamespace(gitRepositoriesSecurityNamespace, "Git Repositories", 2, 4, map[string]int{"GenericRead": 2, "GenericContribute": 4, "ForcePush": 8}),
+	}
+	repository := &v2.Resource{DisplayName: "web_app", Id: &v2.ResourceId{ResourceType: repositoryResourceType.Id, Resource: "repositoryId"}}
+
+	testCases := []struct {
+		name              string
+		slug              string
+		displayName       string
+		expectedNamespace string
+		expectedBit       int
+		expectedError     string
+	}{
+		{
+			name:              "read permission",
+			slug:              "web_app_Git Repositories_read",
+			expectedNamespace: gitRepositoriesSecurityNamespace,
+			expectedBit:       2,
+		},
+		{
+			name:              "write permission",
+			slug:              "web_app_Git Repositories_write",
+			expectedNamespace: gitRepositoriesSecurityNamespace,
+			expectedBit:       4,
+		},
+		{
+			name:              "action permission",
+			slug:              "web_app_Git Repositories_ForcePush",
+			expectedNamespace: gitRepositoriesSecurityNamespace,
+			expectedBit:       8,
+		},
+		{
+			name:              "resource name containing the name of another namespace",
+			slug:              "web_Project_api_Git Repositories_ForcePush",
+			expectedNamespace: gitRepositoriesSecurityNamespace,
+			expectedBit:       8,
+		},
+		{
+			name:              "resource name containing the name of the namespace",
+			slug:              "tools_Project_legacy_Project_DELETE",
+			expectedNamespace: projectSecurityNamespace,
+			expectedBit:       4,
+		},
+		{
+			name:              "display name when the slug is missing",
+			displayName:       "web_app_Project_GENERIC_WRITE",
+			expectedNamespace: projectSecurityNamespace,
+			expectedBit:       2,
+		},
+		{
+			name:          "unsupported action",
+			slug:          "web_app_Git Repositories_Delete",
+			expectedError: "unsupported permission 'Delete' for security namespace Git Repositories",
+		},
+		{
+			name:          "unknown namespace",
+			slug:          "web_app_Tagging_read",
+			expectedError: "no security namespace found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			namespace, bit, err := getSecurityNamespacePermission(namespaces, &v2.Entitlement{
+				Resource:    repository,
+				Slug:        tc.slug,
+				DisplayName: tc.displayName,
+			})
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedNamespace, namespace.NamespaceId.String())
+			assert.Equal(t, tc.expectedBit, bit)
+		})
+	}
+}
+
+func TestSecurityNamespacePermissionProvisioning(t *testing.T) {
+	const projectID = "2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b"
+	const userDescriptor = "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx"
+	const identityDescriptor = "Microsoft.IdentityModel.Claims.ClaimsIdentity;contoso.com\\jamie@contoso.com"
+	const token = "$PROJECT:vstfs:///Classification/TeamProject/" + projectID
+	userID := uuid.MustParse("5d2f6c1b-8e4a-4f3d-9c7b-1a0e2d3c4b5a")
+	namespaceID := uuid.MustParse(projectSecurityNamespace)
+	ctx := context.Background()
+
+	// The user is allowed to read the project, and neither allowed nor denied to write to it.
+	namespaces := []security.SecurityNamespaceDescription{
+		newSecurityNamespace(projectSecurityNamespace, "Project", 1, 2, nil),
+	}
+	descriptor := identityDescriptor
+	mockClient := &client.MockAzureClient{}
+	mockClient.On("GetStorageKey", ctx, userDescriptor).Return(userID, nil)
+	mockClient.On("ListIdentities", ctx, userID.String(), "").Return([]identity.Identity{{Id: &userID, Descriptor: &descriptor}}, nil)
+	allow := 1
+	mockClient.On("GetAccessControlEntry", ctx, namespaceID, token, identityDescriptor).Return(&security.AccessControlEntry{Allow: &allow}, nil)
+	mockClient.On("SetAccessControlEntry", ctx, namespaceID, token, identityDescriptor, 2, 0).Return(nil)
+	mockClient.On("RemovePermission", ctx, namespaceID, token, identityDescriptor, 1).Return(nil)
+
+	project := &v2.Resource{DisplayName: "Fabrikam_Fiber", Id: &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: projectID}}
+	user := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: userDescriptor}}
+	permissionEntitlement := func(action string) *v2.Entitlement {
+		return &v2.Entitlement{Resource: project, Slug: getPermissionName(project.DisplayName, "Project", action)}
+	}
+
+	t.Run("grant a permission already allowed", func(t *testing.T) {
+		annos, err := grantSecurityNamespacePermission(ctx, mockClient, namespaces, user, permissionEntitlement("read"))
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		mockClient.AssertNotCalled(t, "SetAccessControlEntry", ctx, namespaceID, token, identityDescriptor, 1, 0)
+	})
+
+	t.Run("grant a permission", func(t *testing.T) {
+		annos, err := grantSecurityNamespacePermission(ctx, mockClient, namespaces, user, permissionEntitlement("write"))
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "SetAccessControlEntry", ctx, namespaceID, token, identityDescriptor, 2, 0)
+	})
+
+	t.Run("revoke a permission", func(t *testing.T) {
+		annos, err := revokeSecurityNamespacePermission(ctx, mockClient, namespaces, &v2.Grant{Principal: user, Entitlement: permissionEntitlement("read")})
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "RemovePermission", ctx, namespaceID, token, identityDescriptor, 1)
+	})
+
+	t.Run("revoke a permission that is not allowed", func(t *testing.T) {
+		annos, err := revokeSecurityNamespacePermission(ctx, mockClient, namespaces, &v2.Grant{Principal: user, Entitlement: permissionEntitlement("write")})
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		mockClient.AssertNotCalled(t, "RemovePermission", ctx, namespaceID, token, identityDescriptor, 2)
+	})
+}
+
+func TestPipelinePermissionGrants(t *testing.T) {
+	const projectID = "2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b"
+	authorized, unauthorized := true, false
+	intPtr := func(i int) *int { return &i }
+	variableGroup := &v2.Resource{Id: &v2.ResourceId{ResourceType: variableGroupResourceType.Id, Resource: projectID + "$7"}}
+
+	t.Run("authorized pipelines", func(t *testing.T) {
+		grants := pipelinePermissionGrants(projectID, variableGroup, &pipelinepermissions.ResourcePipelinePermissions{
+			AllPipelines: &pipelinepermissions.Permission{Authorized: &unauthorized},
+			Pipelines: &[]pipelinepermissions.PipelinePermission{
+				{Id: intPtr(12), Authorized: &authorized},
+				{Id: intPtr(13), Authorized: &unauthorized},
+			},
+		})
+		require.Len(t, grants, 1)
+		assert.Equal(t, "variable_group:"+projectID+"$7:authorized", grants[0].Entitlement.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: pipelineResourceType.Id, Resource: projectID + "/12"}, grants[0].Principal.Id)
+	})
+
+	t.Run("all pipelines authorized", func(t *testing.T) {
+		grants := pipelinePermissionGrants(projectID, variableGroup, &pipelinepermissions.ResourcePipelinePermissions{
+			AllPipelines: &pipelinepermissions.Permission{Authorized: &authorized},
+		})
+		require.Len(t, grants, 1)
+		assert.Equal(t, &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: projectID}, grants[0].Principal.Id)
+	})
+}
+
+func TestPipelineAuthorizationProvisioning(t *testing.T) {
+	const projectID = "2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b"
+	authorized, unauthorized := true, false
+	intPtr := func(i int) *int { return &i }
+	ctx := context.Background()
+
+	mockClient := &client.MockAzureClient{}
+	mockClient.On("GetPipelinePermissions", ctx, projectID, "variablegroup", "7").Return(&pipelinepermissions.ResourcePipelinePermissions{
+		AllPipelines: &pipelinepermissions.Permission{Authorized: &unauthorized},
+		Pipelines: &[]pipelinepermissions.PipelinePermission{
+			{Id: intPtr(12), Authorized: &authorized},
+		},
+	}, nil)
+	mockClient.On("UpdatePipelinePermissions", ctx, projectID, "variablegroup", "7", mock.Anything).Return(nil)
+
+	variableGroup := &v2.Resource{Id: &v2.ResourceId{ResourceType: variableGroupResourceType.Id, Resource: projectID + "$7"}}
+	authorizedEntitlement := getPipelineAuthorizedEntitlement(variableGroup)
+	pipeline := func(id string) *v2.Resource {
+		return &v2.Resource{Id: &v2.ResourceId{ResourceType: pipelineResourceType.Id, Resource: id}}
+	}
+	project := &v2.Resource{Id: &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: projectID}}
+
+	t.Run("authorize a pipeline", func(t *testing.T) {
+		annos, err := grantPipelineAuthorization(ctx, mockClient, "variablegroup", pipeline(projectID+"/13"), authorizedEntitlement)
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "UpdatePipelinePermissions", ctx, projectID, "variablegroup", "7", &pipelinepermissions.ResourcePipelinePermissions{
+			Pipelines: &[]pipelinepermissions.PipelinePermission{{Id: intPtr(13), Authorized: &authorized}},
+		})
+	})
+
+	t.Run("authorize a pipeline already authorized", func(t *testing.T) {
+		annos, err := grantPipelineAuthorization(ctx, mockClient, "variablegroup", pipeline(projectID+"/12"), authorizedEntitlement)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	})
+
+	t.Run("authorize every pipeline of the project", func(t *testing.T) {
+		annos, err := grantPipelineAuthorization(ctx, mockClient, "variablegroup", project, authorizedEntitlement)
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "UpdatePipelinePermissions", ctx, projectID, "variablegroup", "7", &pipelinepermissions.ResourcePipelinePermissions{
+			AllPipelines: &pipelinepermissions.Permission{Authorized: &authorized},
+		})
+	})
+
+	t.Run("revoke a pipeline authorization", func(t *testing.T) {
+		annos, err := revokePipelineAuthorization(ctx, mockClient, "variablegroup", &v2.Grant{Principal: pipeline(projectID + "/12"), Entitlement: authorizedEntitlement})
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "UpdatePipelinePermissions", ctx, projectID, "variablegroup", "7", &pipelinepermissions.ResourcePipelinePermissions{
+			Pipelines: &[]pipelinepermissions.PipelinePermission{{Id: intPtr(12), Authorized: &unauthorized}},
+		})
+	})
+
+	t.Run("revoke the authorization of a project that is not authorized", func(t *testing.T) {
+		annos, err := revokePipelineAuthorization(ctx, mockClient, "variablegroup", &v2.Grant{Principal: project, Entitlement: authorizedEntitlement})
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+
+	t.Run("pipelines of other projects cannot be authorized", func(t *testing.T) {
+		_, err := grantPipelineAuthorization(ctx, mockClient, "variablegroup", pipeline("9d8c7b6a-5f4e-4d3c-8b2a-1f0e9d8c7b6a/12"), authorizedEntitlement)
+		assert.ErrorContains(t, err, "does not belong to the project")
+	})
+
+	t.Run("only pipelines and projects can be authorized", func(t *testing.T) {
+		user := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx"}}
+		_, err := grantPipelineAuthorization(ctx, mockClient, "variablegroup", user, authorizedEntitlement)
+		assert.ErrorContains(t, err, "only pipelines and projects can be authorized")
+	})
+}
+
+func TestGetIdentityResourcesByDescriptor(t *testing.T) {
+	ctx := context.Background()
+	newIdentity := func(descriptor string, isContainer bool, subjectDescriptor string) *identity.Identity {
+		id := uuid.New()
+		return &identity.Identity{Id: &id, Descriptor: &descriptor, IsContainer: &isContainer, SubjectDescriptor: &subjectDescriptor}
+	}
+	user := newIdentity("Microsoft.IdentityModel.Claims.ClaimsIdentity;contoso.com\\jamie@contoso.com", false, "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx")
+	servicePrincipal := newIdentity("Microsoft.VisualStudio.Services.Claims.AadServicePrincipal;f217ac3b-708a-4db5-b6c3-80408afed9c7", false, "aadsp.ZjIxN2FjM2ItNzA4YS00ZGI1LWI2YzMtODA0MDhhZmVkOWM3")
+	group := newIdentity("Microsoft.TeamFoundation.Identity;S-1-9-1551374245-1204400969", true, "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5")
+	// Identities such as build services are not containers and have no synced resource.
+	buildService := newIdentity("Microsoft.TeamFoundation.ServiceIdentity;c8a2c3a1-build", false, "")
+
+	byDescriptor := make(map[string]*identity.Identity)
+	for _, known := range []*identity.Identity{user, servicePrincipal, group, buildService} {
+		byDescriptor[strings.ToLower(*known.Descriptor)] = known
+	}
+	org := &organization{
+		client: &client.AzureDevOpsClient{},
+		identities: identityCache{
+			timestamp:    time.Now(),
+			teamIDs:      map[string]bool{},
+			byDescriptor: byDescriptor,
+			byID:         map[string]*identity.Identity{},
+		},
+	}
+
+	resources, err := getIdentityResourcesByDescriptor(ctx, org, []string{*user.Descriptor, *servicePrincipal.Descriptor, *group.Descriptor, *buildService.Descriptor})
+	require.NoError(t, err)
+	require.Len(t, resources, 3)
+	assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: *user.SubjectDescriptor}, resources[*user.Descriptor].Id)
+	assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: *servicePrincipal.SubjectDescriptor}, resources[*servicePrincipal.Descriptor].Id)
+	assert.Equal(t, &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: group.Id.String()}, resources[*group.Descriptor].Id)
+	assert.NotContains(t, resources, *buildService.Descriptor)
+}
+
+func TestPrincipalIdentifiers(t *testing.T) {
+	const entraObjectID = "0c3f4c5e-7f2d-4b4e-9d2b-6e1f0a8b9c7d"
+	const entraGroupDescriptor = "aadgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5"
+	const teamID = "0a9e4d2b-5c6f-4e3a-9b1d-8c7f6e5d4c3b"
+	const userDescriptor = "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx"
+	entraGroupIdentityID := uuid.MustParse("7f3c1a52-9d0e-4c1b-8a6f-2b5d9e4c7a10")
+	userIdentityID := uuid.MustParse("5d2f6c1b-8e4a-4f3d-9c7b-1a0e2d3c4b5a")
+	ctx := context.Background()
+
+	mockClient := &client.MockAzureClient{}
+	mockClient.On("GetStorageKey", ctx, entraGroupDescriptor).Return(entraGroupIdentityID, nil)
+	mockClient.On("GetStorageKey", ctx, userDescriptor).Return(userIdentityID, nil)
+	mockClient.On("GetDescriptor", ctx, uuid.MustParse(teamID)).Return("vssgp.Uy0xLTktMTU1MTM3NDI0NS0yMDQ4", nil)
+
+	entraGroup, err := resource.NewGroupResource("Platform Engineers", groupResourceType, entraObjectID, []resource.GroupTraitOption{
+		resource.WithGroupProfile(map[string]interface{}{"group_id": entraObjectID, "descriptor": entraGroupDescriptor}),
+	})
+	require.NoError(t, err)
+	team := &v2.Resource{Id: &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: teamID}}
+	user := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: userDescriptor}}
+
+	testCases := []struct {
+		name               string
+		principal          *v2.Resource
+		expectedDescriptor string
+		expectedIdentityID string
+	}{
+		{
+			name:               "entra group is resolved through the descriptor of its profile",
+			principal:          entraGroup,
+			expectedDescriptor: entraGroupDescriptor,
+			expectedIdentityID: entraGroupIdentityID.String(),
+		},
+		{
+			name:               "team is identified by its identity id",
+			principal:          team,
+			expectedDescriptor: "vssgp.Uy0xLTktMTU1MTM3NDI0NS0yMDQ4",
+			expectedIdentityID: teamID,
+		},
+		{
+			name:               "user is identified by its subject descriptor",
+			principal:          user,
+			expectedDescriptor: userDescriptor,
+			expectedIdentityID: userIdentityID.String(),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			descriptor, err := getSubjectDescriptor(ctx, mockClient, tc.principal)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedDescriptor, descriptor)
+
+			identityID, err := getIdentityID(ctx, mockClient, tc.principal)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedIdentityID, identityID)
+		})
+	}
+	mockClient.AssertNotCalled(t, "GetDescriptor", ctx, uuid.MustParse(entraObjectID))
+}
@@ -0,0 +1,119 @@
+package connector
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+)
+
+// identityCache caches the identities that the access control entries, role assignments and group memberships of an
+// organization refer to, and the ids of its teams. The same groups appear in the ACLs of every project, repository and
+// pipeline, so each identity is read once per sync rather than once per entry.
+type identityCache struct {
+	mutex     sync.Mutex
+	timestamp time.Time
+	teamIDs   map[string]bool
+	// byDescriptor is keyed by lowercase identity descriptor and byID by identity id. Keys that were read without
+	// resolving to an identity map to nil, so that they are not read again.
+	byDescriptor map[string]*identity.Identity
+	byID         map[string]*identity.Identity
+}
+
+// reset empties the cache once it is older than the TTL, so that a long running connector reads the identities again
+// on its next sync.
+func (c *identityCache) reset() {
+	if c.byDescriptor != nil && time.Since(c.timestamp) < TTL*time.Minute {
+		return
+	}
+
+	c.timestamp = time.Now()
+	c.teamIDs = nil
+	c.byDescriptor = make(map[string]*identity.Identity)
+	c.byID = make(map[string]*identity.Identity)
+}
+
+// loadTeamIDs returns the ids of the teams of the organization, which are listed once.
+func (c *identityCache) loadTeamIDs(ctx context.Context, azureClient client.AzureDevOpsClientInterface) (map[string]bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reset()
+
+	if c.teamIDs == nil {
+		teamIDs, err := azureClient.ListTeamIDs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		c.teamIDs = teamIDs
+	}
+
+	return c.teamIDs, nil
+}
+
+// identitiesByDescriptor returns the identities of identity descriptors, keyed by lowercase descriptor. The descriptors
+// that are not cached yet are read in batches.
+func (c *identityCache) identitiesByDescriptor(ctx context.Context, azureClient client.AzureDevOpsClientInterface, descriptors []string) (map[string]*identity.Identity, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reset()
+
+	return c.identities(ctx, descriptors, c.byDescriptor, azureClient.ReadIdentitiesByDescriptors)
+}
+
+// identitiesByID returns the identities of identity ids, keyed by lowercase id. The ids that are not cached yet are
+// read in batches.
+func (c *identityCache) identitiesByID(ctx context.Context, azureClient client.AzureDevOpsClientInterface, identityIDs []string) (map[string]*identity.Identity, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.reset()
+
+	return c.identities(ctx, identityIDs, c.byID, azureClient.ReadIdentitiesByIDs)
+}
+
+// identities returns the identities of keys from a cache, reading the keys it misses. Every identity read is cached
+// by both its descriptor and its id.
+func (c *identityCache) identities(
+	ctx context.Context,
+	keys []string,
+	cache map[string]*identity.Identity,
+	read func(ctx context.Context, keys []string) ([]identity.Identity, error),
+) (map[string]*identity.Identity, error) {
+	var missing []string
+	for _, k := range keys {
+		if _, ok := cache[strings.ToLower(k)]; !ok {
+			missing = append(missing, k)
+			// Marks the key as read, which also skips duplicates.
+			cache[strings.ToLower(k)] = nil
+		}
+	}
+
+	if len(missing) > 0 {
+		identities, err := read(ctx, missing)
+		if err != nil {
+			for _, k := range missing {
+				delete(cache, strings.ToLower(k))
+			}
+			return nil, err
+		}
+
+		for i := range identities {
+			id := &identities[i]
+			if id.Descriptor != nil {
+				c.byDescriptor[strings.ToLower(*id.Descriptor)] = id
+			}
+			c.byID[strings.ToLower(id.Id.String())] = id
+		}
+	}
+
+	result := make(map[string]*identity.Identity, len(keys))
+	for _, k := range keys {
+		if id := cache[strings.ToLower(k)]; id != nil {
+			result[strings.ToLower(k)] = id
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityCache(t *testing.T) {
+	ctx := context.Background()
+	newIdentity := func(descriptor string) identity.Identity {
+		id := uuid.New()
+		return identity.Identity{Id: &id, Descriptor: &descriptor}
+	}
+	known := map[string]identity.Identity{
+		"microsoft.teamfoundation.identity;s-1-9-1": newIdentity("Microsoft.TeamFoundation.Identity;S-1-9-1"),
+		"microsoft.teamfoundation.identity;s-1-9-2": newIdentity("Microsoft.TeamFoundation.Identity;S-1-9-2"),
+	}
+
+	var reads [][]string
+	failing := false
+	read := func(_ context.Context, descriptors []string) ([]identity.Identity, error) {
+		reads = append(reads, descriptors)
+		if failing {
+			return nil, errors.New("throttled")
+		}
+		var identities []identity.Identity
+		for _, descriptor := range descriptors {
+			if knownIdentity, ok := known[strings.ToLower(descriptor)]; ok {
+				identities = append(identities, knownIdentity)
+			}
+		}
+		return identities, nil
+	}
+
+	cache := &identityCache{}
+	cache.reset()
+
+	identities, err := cache.identities(ctx, []string{
+		"Microsoft.TeamFoundation.Identity;S-1-9-1",
+		"Microsoft.TeamFoundation.Identity;S-1-9-1",
+		"Microsoft.TeamFoundation.Identity;S-1-9-3",
+	}, cache.byDescriptor, read)
+	require.NoError(t, err)
+	assert.Len(t, identities, 1)
+	assert.Contains(t, identities, "microsoft.teamfoundation.identity;s-1-9-1")
+
+	t.Run("duplicates are read once", func(t *testing.T) {
+		assert.Equal(t, [][]string{{"Microsoft.TeamFoundation.Identity;S-1-9-1", "Microsoft.TeamFoundation.Identity;S-1-9-3"}}, reads)
+	})
+
+	t.Run("cached and unresolved descriptors are not read again", func(t *testing.T) {
+		reads = nil
+		identities, err := cache.identities(ctx, []string{
+			"microsoft.teamfoundation.identity;s-1-9-1",
+			"Microsoft.TeamFoundation.Identity;S-1-9-2",
+			"Microsoft.TeamFoundation.Identity;S-1-9-3",
+		}, cache.byDescriptor, read)
+		require.NoError(t, err)
+		assert.Len(t, identities, 2)
+		assert.Equal(t, [][]string{{"Microsoft.TeamFoundation.Identity;S-1-9-2"}}, reads)
+	})
+
+	t.Run("identities are cached by id", func(t *testing.T) {
+		id := known["microsoft.teamfoundation.identity;s-1-9-2"].Id.String()
+		identities, err := cache.identities(ctx, []string{id}, cache.byID, func(context.Context, []string) ([]identity.Identity, error) {
+			t.Fatal("identity read again")
+			return nil, nil
+		})
+		require.NoError(t, err)
+		assert.Contains(t, identities, id)
+	})
+
+	t.Run("failed reads are not cached", func(t *testing.T) {
+		failing = true
+		_, err := cache.identities(ctx, []string{"Microsoft.TeamFoundation.Identity;S-1-9-4"}, cache.byDescriptor, read)
+		require.Error(t, err)
+
+		failing = false
+		reads = nil
+		_, err = cache.identities(ctx, []string{"Microsoft.TeamFoundation.Identity;S-1-9-4"}, cache.byDescriptor, read)
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{"Microsoft.TeamFoundation.Identity;S-1-9-4"}}, reads)
+	})
+}
@@ -0,0 +1,103 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// membershipProvisioner grants and revokes the memberships of groups and teams, which are both graph groups. It checks
+// the membership first so that retried provisioning tasks succeed with GrantAlreadyExists or GrantAlreadyRevoked.
+type membershipProvisioner struct {
+	client client.AzureDevOpsClientInterface
+}
+
+// grant adds a principal to the group or team of an entitlement, and returns the resulting grant of the entitlement.
+func (p membershipProvisioner) grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	containerDescriptor, memberDescriptor, err := p.descriptors(ctx, entitlementResource.Resource, principal)
+	if err != nil {
+		return nil, nil, err
+	}
+	grants := []*v2.Grant{newMembershipGrant(entitlementResource.Resource, memberPermission, principal.Id)}
+
+	membership, err := p.client.GetMembership(ctx, containerDescriptor, memberDescriptor)
+	if err != nil && !errors.Is(err, client.ErrNotFound) {
+		l.Debug("Error getting membership", zap.Error(err))
+		return nil, nil, err
+	}
+	if membership != nil {
+		l.Info("Membership already exists; treating as successful because the end state is achieved")
+		return grants, annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	_, err = p.client.CreateMembership(ctx, containerDescriptor, memberDescriptor)
+	if err != nil {
+		l.Debug("Error creating membership", zap.Error(err))
+		return nil, nil, err
+	}
+
+	return grants, nil, nil
+}
+
+// revoke removes the principal of a grant from the group or team of its entitlement.
+func (p membershipProvisioner) revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	containerDescriptor, memberDescriptor, err := p.descriptors(ctx, grantResource.Entitlement.Resource, grantResource.Principal)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.client.GetMembership(ctx, containerDescriptor, memberDescriptor)
+	if err == nil {
+		err = p.client.RevokeMembership(ctx, containerDescriptor, memberDescriptor)
+	}
+	// The membership may also be removed between the check and the revocation.
+	if errors.Is(err, client.ErrNotFound) {
+		l.Info("Membership to revoke not found; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+	if err != nil {
+		l.Debug("Error revoking membership", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// descriptors returns the subject descriptors of a group or team and of a principal.
+func (p membershipProvisioner) descriptors(ctx context.Context, container *v2.Resource, principal *v2.Resource) (string, string, error) {
+	containerDescriptor, err := getSubjectDescriptor(ctx, p.client, container)
+	if err != nil {
+		return "", "", err
+	}
+
+	memberDescriptor, err := getSubjectDescriptor(ctx, p.client, principal)
+	if err != nil {
+		return "", "", err
+	}
+
+	return containerDescriptor, memberDescriptor, nil
+}
+
+// newMembershipGrant returns a grant of a group or team entitlement. Grants to groups and teams expand to their
+// members, so that the baton-sdk computes the transitive membership.
+func newMembershipGrant(resource *v2.Resource, permission string, principal *v2.ResourceId) *v2.Grant {
+	var grantOptions []grant.GrantOption
+	if principal.ResourceType != userResourceType.Id {
+		grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantExpandable{
+			EntitlementIds: []string{fmt.Sprintf("%s:%s:%s", principal.ResourceType, principal.Resource, memberPermission)},
+		}))
+	}
+
+	return grant.NewGrant(resource, permission, principal, grantOptions...)
+}
@@ -0,0 +1,102 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	mockService "github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMembershipProvisioner(t *testing.T) {
+	const testGroupId = "7f3c1a52-9d0e-4c1b-8a6f-2b5d9e4c7a10"
+	const testNestedGroupId = "0a9e4d2b-5c6f-4e3a-9b1d-8c7f6e5d4c3b"
+	const testGroupDescriptor = "vssgp.testGroupDescriptor"
+	const testNestedGroupDescriptor = "vssgp.testNestedGroupDescriptor"
+	const testUserDescriptor = "aad.OTk5ZDIwNjQtOWQyMy03YzBmLWFmYDUtNWQ3ZmU1MzNhMTc4"
+	const testRemovedUserDescriptor = "aad.ZmY1NTNkMmEtYjA3Ny03ZjQ1LWE1YjQtZTY5ZDQ4N2FkNWIx"
+	ctx := context.Background()
+
+	mockClient := &mockService.MockAzureClient{}
+	mockClient.On("GetDescriptor", ctx, uuid.MustParse(testGroupId)).Return(testGroupDescriptor, nil)
+	mockClient.On("GetDescriptor", ctx, uuid.MustParse(testNestedGroupId)).Return(testNestedGroupDescriptor, nil)
+	mockClient.On("GetMembership", ctx, testGroupDescriptor, testNestedGroupDescriptor).Return((*graph.GraphMembership)(nil), mockService.ErrNotFound)
+	mockClient.On("CreateMembership", ctx, testGroupDescriptor, testNestedGroupDescriptor).Return(&graph.GraphMembership{}, nil)
+	mockClient.On("GetMembership", ctx, testGroupDescriptor, testUserDescriptor).Return(&graph.GraphMembership{}, nil)
+	mockClient.On("RevokeMembership", ctx, testGroupDescriptor, testUserDescriptor).Return(nil)
+	// The user is removed by someone else between the check and the revocation.
+	mockClient.On("GetMembership", ctx, testGroupDescriptor, testRemovedUserDescriptor).Return(&graph.GraphMembership{}, nil)
+	mockClient.On("RevokeMembership", ctx, testGroupDescriptor, testRemovedUserDescriptor).Return(mockService.ErrNotFound)
+	provisioner := membershipProvisioner{client: mockClient}
+
+	groupResource := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testGroupId}}
+	memberEntitlement := &v2.Entitlement{DisplayName: memberPermission, Resource: groupResource}
+	userPrincipal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testUserDescriptor}}
+
+	t.Run("grant a nested group", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testNestedGroupId}}
+
+		grants, annos, err := provisioner.grant(ctx, principal, memberEntitlement)
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		require.Len(t, grants, 1)
+		assert.Equal(t, "group:"+testGroupId+":member", grants[0].Entitlement.Id)
+
+		annos = annotations.Annotations(grants[0].Annotations)
+		expandable := &v2.GrantExpandable{}
+		ok, err := annos.Pick(expandable)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, []string{"group:" + testNestedGroupId + ":member"}, expandable.EntitlementIds)
+	})
+
+	t.Run("grant an existing membership", func(t *testing.T) {
+		grants, annos, err := provisioner.grant(ctx, userPrincipal, memberEntitlement)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		require.Len(t, grants, 1)
+		annos = annotations.Annotations(grants[0].Annotations)
+		assert.False(t, annos.Contains(&v2.GrantExpandable{}))
+		mockClient.AssertNotCalled(t, "CreateMembership", ctx, testGroupDescriptor, testUserDescriptor)
+	})
+
+	t.Run("revoke a membership", func(t *testing.T) {
+		annos, err := provisioner.revoke(ctx, &v2.Grant{Principal: userPrincipal, Entitlement: memberEntitlement})
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+	})
+
+	t.Run("revoke a missing membership", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testNestedGroupId}}
+
+		annos, err := provisioner.revoke(ctx, &v2.Grant{Principal: principal, Entitlement: memberEntitlement})
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		mockClient.AssertNotCalled(t, "RevokeMembership", ctx, testGroupDescriptor, testNestedGroupDescriptor)
+	})
+
+	t.Run("revoke a membership removed concurrently", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testRemovedUserDescriptor}}
+
+		annos, err := provisioner.revoke(ctx, &v2.Grant{Principal: principal, Entitlement: memberEntitlement})
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+
+	t.Run("membership check failures are returned", func(t *testing.T) {
+		failingClient := &mockService.MockAzureClient{}
+		failingClient.On("GetDescriptor", ctx, uuid.MustParse(testGroupId)).Return(testGroupDescriptor, nil)
+		failingClient.On("GetMembership", ctx, testGroupDescriptor, testUserDescriptor).Return((*graph.GraphMembership)(nil), mockService.ErrForbidden)
+
+		_, _, err := membershipProvisioner{client: failingClient}.grant(ctx, userPrincipal, memberEntitlement)
+		assert.ErrorIs(t, err, mockService.ErrForbidden)
+		failingClient.AssertNotCalled(t, "CreateMembership")
+	})
+
+	mockClient.AssertExpectations(t)
+}
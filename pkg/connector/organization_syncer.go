@@ -0,0 +1,499 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"google.golang.org/protobuf/proto"
+)
+
+// organizationSyncer syncs a resource type across every organization. The resources of an organization are synced by
+// the organization's own resource syncer, and the ids of the resources, entitlements and grants it returns are
+// namespaced with the organization name, e.g. contoso/2b3a6f0e-..., so that identical project GUIDs or group names of
+// different organizations never collide. The responses are annotated with the rate limit state of the organization.
+type organizationSyncer struct {
+	resourceType  *v2.ResourceType
+	organizations []string
+	syncers       map[string]connectorbuilder.ResourceSyncer
+	rateLimits    func(organizationName string) *v2.RateLimitDescription
+}
+
+func (o *organizationSyncer) ResourceType(_ context.Context) *v2.ResourceType {
+	return o.resourceType
+}
+
+func (o *organizationSyncer) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	// Every resource belongs to an organization, so resources are only listed under their organization or project.
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	organizationName, localParentResourceID, err := splitOrganizationResourceID(parentResourceID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	resources, nextPageToken, annos, err := syncer.List(ctx, localParentResourceID, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for i, resource := range resources {
+		resources[i] = namespaceResource(organizationName, resource)
+	}
+
+	return resources, nextPageToken, o.withRateLimit(organizationName, annos), nil
+}
+
+func (o *organizationSyncer) Entitlements(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	organizationName, localResource, err := splitOrganizationResource(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	entitlements, nextPageToken, annos, err := syncer.Entitlements(ctx, localResource, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for i, entitlement := range entitlements {
+		entitlements[i] = namespaceEntitlement(organizationName, entitlement)
+	}
+
+	return entitlements, nextPageToken, o.withRateLimit(organizationName, annos), nil
+}
+
+func (o *organizationSyncer) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	organizationName, localResource, err := splitOrganizationResource(resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	grants, nextPageToken, annos, err := syncer.Grants(ctx, localResource, pToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for i, grantResource := range grants {
+		grants[i], err = namespaceGrant(organizationName, grantResource)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	return grants, nextPageToken, o.withRateLimit(organizationName, annos), nil
+}
+
+// syncer returns the resource syncer of an organization.
+func (o *organizationSyncer) syncer(organizationName string) (connectorbuilder.ResourceSyncer, error) {
+	syncer, ok := o.syncers[organizationName]
+	if !ok {
+		return nil, fmt.Errorf("baton-azure-devops: organization %s is not synced by the connector", organizationName)
+	}
+
+	return syncer, nil
+}
+
+// withRateLimit annotates a response with the rate limit state of an organization.
+func (o *organizationSyncer) withRateLimit(organizationName string, annos annotations.Annotations) annotations.Annotations {
+	if o.rateLimits == nil {
+		return annos
+	}
+
+	return withRateLimit(annos, o.rateLimits(organizationName))
+}
+
+// organizationProvisioner grants and revokes the entitlements of a resource type across every organization.
+type organizationProvisioner struct {
+	*organizationSyncer
+}
+
+func (o *organizationProvisioner) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	organizationName, localEntitlement, localPrincipal, err := splitOrganizationGrant(entitlementResource, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	provisioner, err := o.provisioner(organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	return provisioner.Grant(ctx, localPrincipal, localEntitlement)
+}
+
+func (o *organizationProvisioner) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return o.revoke(ctx, grantResource)
+}
+
+func (o *organizationProvisioner) provisioner(organizationName string) (connectorbuilder.ResourceProvisioner, error) {
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	return syncer.(connectorbuilder.ResourceProvisioner), nil
+}
+
+// organizationProvisionerV2 grants and revokes the entitlements of a resource type across every organization, for
+// resource syncers that return the grants they create.
+type organizationProvisionerV2 struct {
+	*organizationSyncer
+}
+
+func (o *organizationProvisionerV2) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	organizationName, localEntitlement, localPrincipal, err := splitOrganizationGrant(entitlementResource, principal)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	grants, annos, err := syncer.(connectorbuilder.ResourceProvisionerV2).Grant(ctx, localPrincipal, localEntitlement)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, grantResource := range grants {
+		grants[i], err = namespaceGrant(organizationName, grantResource)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return grants, annos, nil
+}
+
+func (o *organizationProvisionerV2) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return o.revoke(ctx, grantResource)
+}
+
+// revoke revokes a grant through the resource syncer of the organization of its entitlement.
+func (o *organizationSyncer) revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	organizationName, localEntitlement, localPrincipal, err := splitOrganizationGrant(grantResource.Entitlement, grantResource.Principal)
+	if err != nil {
+		return nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	localGrant := proto.Clone(grantResource).(*v2.Grant)
+	localGrant.Entitlement = localEntitlement
+	localGrant.Principal = localPrincipal
+
+	revoker, ok := syncer.(interface {
+		Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("baton-azure-devops: %s does not support revoking grants", o.resourceType.Id)
+	}
+
+	return revoker.Revoke(ctx, localGrant)
+}
+
+// organizationAccountManager creates accounts in the organization named by the account profile.
+type organizationAccountManager struct {
+	*organizationSyncer
+}
+
+func (o *organizationAccountManager) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	credentialOptions *v2.CredentialOptions) (
+	connectorbuilder.CreateAccountResponse,
+	[]*v2.PlaintextData,
+	annotations.Annotations,
+	error,
+) {
+	organizationName, err := o.accountOrganization(accountInfo)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	response, plaintextData, annos, err := syncer.(connectorbuilder.AccountManager).CreateAccount(ctx, accountInfo, credentialOptions)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	switch result := response.(type) {
+	case *v2.CreateAccountResponse_SuccessResult:
+		result.Resource = namespaceResource(organizationName, result.Resource)
+	case *v2.CreateAccountResponse_ActionRequiredResult:
+		result.Resource = namespaceResource(organizationName, result.Resource)
+	}
+
+	return response, plaintextData, annos, nil
+}
+
+func (o *organizationAccountManager) CreateAccountCapabilityDetails(ctx context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	syncer, err := o.syncer(o.organizations[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return syncer.(connectorbuilder.AccountManager).CreateAccountCapabilityDetails(ctx)
+}
+
+// organizationAccountDeleter creates accounts like organizationAccountManager, and deletes them from the organization
+// of their namespaced id.
+type organizationAccountDeleter struct {
+	*organizationAccountManager
+}
+
+func (o *organizationAccountDeleter) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	organizationName, localResourceID, err := splitOrganizationResourceID(resourceId)
+	if err != nil {
+		return nil, err
+	}
+
+	syncer, err := o.syncer(organizationName)
+	if err != nil {
+		return nil, err
+	}
+
+	return syncer.(connectorbuilder.ResourceDeleter).Delete(ctx, localResourceID)
+}
+
+// accountOrganization returns the organization named by the 'organization' field of the account profile, which may be
+// omitted when a single organization is synced.
+func (o *organizationAccountManager) accountOrganization(accountInfo *v2.AccountInfo) (string, error) {
+	organizationName, _ := accountInfo.GetProfile().AsMap()["organization"].(string)
+	if organizationName == "" {
+		if len(o.organizations) == 1 {
+			return o.organizations[0], nil
+		}
+		return "", fmt.Errorf("missing 'organization' in account profile")
+	}
+
+	// Organization names are case insensitive.
+	for _, name := range o.organizations {
+		if strings.EqualFold(name, organizationName) {
+			return name, nil
+		}
+	}
+
+	return "", fmt.Errorf("baton-azure-devops: organization %s is not synced by the connector", organizationName)
+}
+
+// newOrganizationSyncer wraps the resource syncers of every organization for a resource type, provisioning grants or
+// accounts, and deleting accounts, when the resource syncers of the organizations do. rateLimits returns the rate
+// limit state of an organization, and may be nil.
+func newOrganizationSyncer(
+	resourceType *v2.ResourceType,
+	organizations []string,
+	syncers map[string]connectorbuilder.ResourceSyncer,
+	rateLimits func(organizationName string) *v2.RateLimitDescription,
+) connectorbuilder.ResourceSyncer {
+	syncer := &organizationSyncer{
+		resourceType:  resourceType,
+		organizations: organizations,
+		syncers:       syncers,
+		rateLimits:    rateLimits,
+	}
+
+	switch syncers[organizations[0]].(type) {
+	case connectorbuilder.ResourceProvisioner:
+		return &organizationProvisioner{organizationSyncer: syncer}
+	case connectorbuilder.ResourceProvisionerV2:
+		return &organizationProvisionerV2{organizationSyncer: syncer}
+	case connectorbuilder.AccountManager:
+		accountManager := &organizationAccountManager{organizationSyncer: syncer}
+		if _, ok := syncers[organizations[0]].(connectorbuilder.ResourceDeleter); ok {
+			return &organizationAccountDeleter{organizationAccountManager: accountManager}
+		}
+		return accountManager
+	default:
+		return syncer
+	}
+}
+
+// organizationResourceID namespaces the id of a resource of an organization.
+func organizationResourceID(organizationName, id string) string {
+	return organizationName + "/" + id
+}
+
+// splitOrganizationResourceID returns the organization of a namespaced resource id, and the resource id the resource
+// syncers of the organization use, which is nil for the organization itself.
+func splitOrganizationResourceID(resourceID *v2.ResourceId) (string, *v2.ResourceId, error) {
+	if resourceID.ResourceType == organizationResourceType.Id {
+		return resourceID.Resource, nil, nil
+	}
+
+	// Organization names cannot contain a slash, while pipeline ids can.
+	organizationName, id, ok := strings.Cut(resourceID.Resource, "/")
+	if !ok || organizationName == "" || id == "" {
+		return "", nil, fmt.Errorf("baton-azure-devops: %s id '%s' is not namespaced with an organization", resourceID.ResourceType, resourceID.Resource)
+	}
+
+	return organizationName, &v2.ResourceId{
+		ResourceType:  resourceID.ResourceType,
+		Resource:      id,
+		BatonResource: resourceID.BatonResource,
+	}, nil
+}
+
+// splitOrganizationResource returns the organization of a namespaced resource, and a copy of the resource with the ids
+// the resource syncers of the organization use.
+func splitOrganizationResource(resource *v2.Resource) (string, *v2.Resource, error) {
+	organizationName, localResourceID, err := splitOrganizationResourceID(resource.Id)
+	if err != nil {
+		return "", nil, err
+	}
+
+	localResource := proto.Clone(resource).(*v2.Resource)
+	localResource.Id = localResourceID
+	localResource.ParentResourceId = nil
+
+	if resource.ParentResourceId != nil {
+		parentOrganizationName, localParentResourceID, err := splitOrganizationResourceID(resource.ParentResourceId)
+		if err != nil {
+			return "", nil, err
+		}
+		if parentOrganizationName != organizationName {
+			return "", nil, fmt.Errorf("baton-azure-devops: %s '%s' does not belong to organization %s", resource.Id.ResourceType, resource.Id.Resource, parentOrganizationName)
+		}
+		localResource.ParentResourceId = localParentResourceID
+	}
+
+	return organizationName, localResource, nil
+}
+
+// splitOrganizationGrant returns the organization of an entitlement and a principal, which must belong to the same
+// organization, with copies of them that use the ids the resource syncers of the organization use.
+func splitOrganizationGrant(entitlementResource *v2.Entitlement, principal *v2.Resource) (string, *v2.Entitlement, *v2.Resource, error) {
+	organizationName, localEntitlementResource, err := splitOrganizationResource(entitlementResource.Resource)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	principalOrganizationName, localPrincipal, err := splitOrganizationResource(principal)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	if principalOrganizationName != organizationName {
+		return "", nil, nil, fmt.Errorf(
+			"baton-azure-devops: %s of organization %s cannot be granted an entitlement of organization %s",
+			principal.Id.ResourceType,
+			principalOrganizationName,
+			organizationName,
+		)
+	}
+
+	localEntitlement := proto.Clone(entitlementResource).(*v2.Entitlement)
+	localEntitlement.Resource = localEntitlementResource
+
+	return organizationName, localEntitlement, localPrincipal, nil
+}
+
+// namespaceResource returns a copy of a resource of an organization with namespaced ids. Resources without a parent
+// are parented by their organization.
+func namespaceResource(organizationName string, resource *v2.Resource) *v2.Resource {
+	if resource == nil {
+		return nil
+	}
+
+	namespaced := proto.Clone(resource).(*v2.Resource)
+	namespaced.Id = namespaceResourceID(organizationName, resource.Id)
+	if resource.ParentResourceId == nil {
+		namespaced.ParentResourceId = &v2.ResourceId{
+			ResourceType: organizationResourceType.Id,
+			Resource:     organizationName,
+		}
+	} else {
+		namespaced.ParentResourceId = namespaceResourceID(organizationName, resource.ParentResourceId)
+	}
+
+	return namespaced
+}
+
+func namespaceResourceID(organizationName string, resourceID *v2.ResourceId) *v2.ResourceId {
+	return &v2.ResourceId{
+		ResourceType:  resourceID.ResourceType,
+		Resource:      organizationResourceID(organizationName, resourceID.Resource),
+		BatonResource: resourceID.BatonResource,
+	}
+}
+
+// namespaceEntitlementID namespaces the resource of an entitlement id, which has the form type:resource:slug.
+func namespaceEntitlementID(organizationName, entitlementID string) string {
+	resourceType, rest, ok := strings.Cut(entitlementID, ":")
+	if !ok {
+		return entitlementID
+	}
+
+	return resourceType + ":" + organizationResourceID(organizationName, rest)
+}
+
+func namespaceEntitlement(organizationName string, entitlementResource *v2.Entitlement) *v2.Entitlement {
+	namespaced := proto.Clone(entitlementResource).(*v2.Entitlement)
+	namespaced.Id = namespaceEntitlementID(organizationName, entitlementResource.Id)
+	namespaced.Resource = namespaceResource(organizationName, entitlementResource.Resource)
+
+	return namespaced
+}
+
+// namespaceGrant returns a copy of a grant of an organization with namespaced ids, including the entitlements the
+// grant expands to.
+func namespaceGrant(organizationName string, grantResource *v2.Grant) (*v2.Grant, error) {
+	namespaced := proto.Clone(grantResource).(*v2.Grant)
+	namespaced.Entitlement = namespaceEntitlement(organizationName, grantResource.Entitlement)
+
+	return namespaceGrantPrincipal(organizationName, namespaced)
+}
+
+// namespaceGrantPrincipal returns a copy of a grant with the namespaced id of its principal, for grants of entitlements
+// that are already namespaced, such as the entitlements of the organization itself.
+func namespaceGrantPrincipal(organizationName string, grantResource *v2.Grant) (*v2.Grant, error) {
+	namespaced := proto.Clone(grantResource).(*v2.Grant)
+	namespaced.Principal = namespaceResource(organizationName, grantResource.Principal)
+	namespaced.Id = grant.NewGrantID(namespaced.Principal, namespaced.Entitlement)
+
+	annos := annotations.Annotations(namespaced.Annotations)
+	expandable := &v2.GrantExpandable{}
+	ok, err := annos.Pick(expandable)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for i, entitlementID := range expandable.EntitlementIds {
+			expandable.EntitlementIds[i] = namespaceEntitlementID(organizationName, entitlementID)
+		}
+		annos.Update(expandable)
+		namespaced.Annotations = annos
+	}
+
+	return namespaced, nil
+}
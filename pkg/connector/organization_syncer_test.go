@@ -0,0 +1,220 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	mockService "github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// repositorySyncer returns the same repository for every organization, granting a group membership on it.
+type repositorySyncer struct{}
+
+func (o *repositorySyncer) ResourceType(_ context.Context) *v2.ResourceType {
+	return repositoryResourceType
+}
+
+func (o *repositorySyncer) List(_ context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	repositoryResource, err := resource.NewResource("repository", repositoryResourceType, "repositoryId", resource.WithParentResourceID(parentResourceID))
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return []*v2.Resource{repositoryResource}, "", nil, nil
+}
+
+func (o *repositorySyncer) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func (o *repositorySyncer) Grants(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return []*v2.Grant{
+		grant.NewGrant(
+			resource,
+			"Git Repositories_read",
+			&v2.ResourceId{ResourceType: groupResourceType.Id, Resource: "groupId"},
+			grant.WithAnnotation(&v2.GrantExpandable{EntitlementIds: []string{"group:groupId:member"}}),
+		),
+	}, "", nil, nil
+}
+
+func TestOrganizationSyncer(t *testing.T) {
+	ctx := context.Background()
+	syncer := newOrganizationSyncer(repositoryResourceType, []string{"contoso", "fabrikam"}, map[string]connectorbuilder.ResourceSyncer{
+		"contoso":  &repositorySyncer{},
+		"fabrikam": &repositorySyncer{},
+	}, nil)
+
+	t.Run("nothing is listed without an organization", func(t *testing.T) {
+		resources, _, _, err := syncer.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		assert.Empty(t, resources)
+	})
+
+	var repositories []*v2.Resource
+	for _, organizationName := range []string{"contoso", "fabrikam"} {
+		resources, _, _, err := syncer.List(ctx, &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: organizationName + "/projectId"}, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		repositories = append(repositories, resources[0])
+	}
+
+	t.Run("resource ids are namespaced", func(t *testing.T) {
+		assert.Equal(t, "contoso/repositoryId", repositories[0].Id.Resource)
+		assert.Equal(t, "contoso/projectId", repositories[0].ParentResourceId.Resource)
+		assert.Equal(t, "fabrikam/repositoryId", repositories[1].Id.Resource)
+		assert.Equal(t, "fabrikam/projectId", repositories[1].ParentResourceId.Resource)
+	})
+
+	t.Run("grant ids are namespaced", func(t *testing.T) {
+		grants, _, _, err := syncer.Grants(ctx, repositories[1], &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, grants, 1)
+
+		assert.Equal(t, "repository:fabrikam/repositoryId:Git Repositories_read", grants[0].Entitlement.Id)
+		assert.Equal(t, "fabrikam/repositoryId", grants[0].Entitlement.Resource.Id.Resource)
+		assert.Equal(t, "fabrikam/groupId", grants[0].Principal.Id.Resource)
+		assert.Equal(t, "repository:fabrikam/repositoryId:Git Repositories_read:group:fabrikam/groupId", grants[0].Id)
+
+		expandable := &v2.GrantExpandable{}
+		annos := annotations.Annotations(grants[0].Annotations)
+		ok, err := annos.Pick(expandable)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, []string{"group:fabrikam/groupId:member"}, expandable.EntitlementIds)
+	})
+
+	t.Run("ids without an organization are rejected", func(t *testing.T) {
+		_, _, _, err := syncer.Grants(ctx, &v2.Resource{Id: &v2.ResourceId{ResourceType: repositoryResourceType.Id, Resource: "repositoryId"}}, &pagination.Token{})
+		assert.ErrorContains(t, err, "is not namespaced with an organization")
+	})
+
+	t.Run("responses are annotated with the rate limit of the organization", func(t *testing.T) {
+		syncer := newOrganizationSyncer(repositoryResourceType, []string{"contoso"}, map[string]connectorbuilder.ResourceSyncer{
+			"contoso": &repositorySyncer{},
+		}, func(organizationName string) *v2.RateLimitDescription {
+			return &v2.RateLimitDescription{Status: v2.RateLimitDescription_STATUS_OK, Limit: 200, Remaining: 150}
+		})
+
+		_, _, annos, err := syncer.List(ctx, &v2.ResourceId{ResourceType: organizationResourceType.Id, Resource: "contoso"}, &pagination.Token{})
+		require.NoError(t, err)
+
+		rateLimit := &v2.RateLimitDescription{}
+		ok, err := annos.Pick(rateLimit)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, int64(150), rateLimit.Remaining)
+	})
+}
+
+func TestOrganizationProvisioner(t *testing.T) {
+	const testTeamId = "11c0f886-25c4-11f0-b643-325096b39f47"
+	const testPrincipalDescriptor = "aad.OTk5ZDIwNjQtOWQyMy03YzBmLWFmYDUtNWQ3ZmU1MzNhMTc4"
+	ctx := context.Background()
+
+	contosoClient := &mockService.MockAzureClient{}
+	contosoClient.On("GetDescriptor", ctx, uuid.MustParse(testTeamId)).Return("contosoTeamDescriptor", nil)
+	contosoClient.On("GetMembership", ctx, "contosoTeamDescriptor", testPrincipalDescriptor).Return(&graph.GraphMembership{}, nil)
+	contosoClient.On("RevokeMembership", ctx, "contosoTeamDescriptor", testPrincipalDescriptor).Return(nil)
+	fabrikamClient := &mockService.MockAzureClient{}
+
+	syncer := newOrganizationSyncer(teamResourceType, []string{"contoso", "fabrikam"}, map[string]connectorbuilder.ResourceSyncer{
+		"contoso":  &teamBuilder{client: contosoClient},
+		"fabrikam": &teamBuilder{client: fabrikamClient},
+	}, nil)
+	provisioner, ok := syncer.(connectorbuilder.ResourceProvisionerV2)
+	require.True(t, ok)
+
+	teamResource := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: "contoso/" + testTeamId},
+		ParentResourceId: &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: "contoso/projectId"},
+	}
+
+	t.Run("granted grants are namespaced", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "contoso/" + testPrincipalDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "member", Resource: teamResource}
+
+		grants, annos, err := provisioner.Grant(ctx, principal, entitlementResource)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		require.Len(t, grants, 1)
+		assert.Equal(t, "team:contoso/"+testTeamId+":member", grants[0].Entitlement.Id)
+		assert.Equal(t, "contoso/"+testPrincipalDescriptor, grants[0].Principal.Id.Resource)
+	})
+
+	t.Run("revoke from the organization of the team", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "contoso/" + testPrincipalDescriptor}}
+		grantResource := &v2.Grant{
+			Entitlement: &v2.Entitlement{DisplayName: "member", Resource: teamResource},
+			Principal:   principal,
+		}
+
+		_, err := provisioner.Revoke(ctx, grantResource)
+		require.NoError(t, err)
+		contosoClient.AssertExpectations(t)
+		fabrikamClient.AssertNotCalled(t, "RevokeMembership")
+	})
+
+	t.Run("principal of another organization", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "fabrikam/" + testPrincipalDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "member", Resource: teamResource}
+
+		_, _, err := provisioner.Grant(ctx, principal, entitlementResource)
+		assert.ErrorContains(t, err, "cannot be granted an entitlement of organization contoso")
+	})
+}
+
+// accountSyncer records the accounts it deletes.
+type accountSyncer struct {
+	repositorySyncer
+	deleted []string
+}
+
+func (o *accountSyncer) CreateAccount(_ context.Context, _ *v2.AccountInfo, _ *v2.CredentialOptions) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	return &v2.CreateAccountResponse_SuccessResult{}, nil, nil, nil
+}
+
+func (o *accountSyncer) CreateAccountCapabilityDetails(_ context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{}, nil, nil
+}
+
+func (o *accountSyncer) Delete(_ context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	o.deleted = append(o.deleted, resourceId.Resource)
+	return nil, nil
+}
+
+func TestOrganizationAccountDeleter(t *testing.T) {
+	ctx := context.Background()
+	contoso := &accountSyncer{}
+	fabrikam := &accountSyncer{}
+	syncer := newOrganizationSyncer(userResourceType, []string{"contoso", "fabrikam"}, map[string]connectorbuilder.ResourceSyncer{
+		"contoso":  contoso,
+		"fabrikam": fabrikam,
+	}, nil)
+	_, ok := syncer.(connectorbuilder.AccountManager)
+	require.True(t, ok)
+	deleter, ok := syncer.(connectorbuilder.ResourceDeleter)
+	require.True(t, ok)
+
+	t.Run("delete from the organization of the account", func(t *testing.T) {
+		_, err := deleter.Delete(ctx, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "fabrikam/aad.userDescriptor"})
+		require.NoError(t, err)
+		assert.Empty(t, contoso.deleted)
+		assert.Equal(t, []string{"aad.userDescriptor"}, fabrikam.deleted)
+	})
+
+	t.Run("ids without an organization are rejected", func(t *testing.T) {
+		_, err := deleter.Delete(ctx, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "aad.userDescriptor"})
+		assert.ErrorContains(t, err, "is not namespaced with an organization")
+	})
+}
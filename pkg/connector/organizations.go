@@ -0,0 +1,256 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"google.golang.org/protobuf/proto"
+)
+
+// organizationSecurityNamespace is an organization level security namespace, with the token that secures the
+// organization and the actions of the namespace that are synced as entitlements of the organization. These namespaces
+// are kept apart from the project level token builders, so they cannot be configured as project namespaces.
+type organizationSecurityNamespace struct {
+	id      string
+	token   string
+	actions []string
+}
+
+// organizationSecurityNamespaces control who administers the organization: who edits its settings and policies, creates
+// projects, manages its processes and reads or streams its audit log.
+var organizationSecurityNamespaces = []organizationSecurityNamespace{
+	{
+		id:      collectionSecurityNamespace,
+		token:   "NAMESPACE",
+		actions: []string{"GENERIC_WRITE", "CREATE_PROJECTS", "MANAGE_TEMPLATE", "DELETE_FIELD", "MANAGE_ENTERPRISE_POLICIES"},
+	},
+	{
+		id:      processSecurityNamespace,
+		token:   "$PROCESS",
+		actions: []string{"Create", "Edit", "Delete", "AdministerProcessPermissions"},
+	},
+	{
+		id:      auditLogSecurityNamespace,
+		token:   "AllPermissions",
+		actions: []string{"Read", "Manage_Streams", "Delete_Streams"},
+	},
+}
+
+func organizationSecurityNamespaceIDs() []string {
+	namespaceIDs := make([]string, 0, len(organizationSecurityNamespaces))
+	for _, namespace := range organizationSecurityNamespaces {
+		namespaceIDs = append(namespaceIDs, namespace.id)
+	}
+
+	return namespaceIDs
+}
+
+// getOrganizationSecurityToken returns the token that secures the organization in an organization level security
+// namespace.
+func getOrganizationSecurityToken(securityNamespace string) (string, error) {
+	for _, namespace := range organizationSecurityNamespaces {
+		if strings.EqualFold(namespace.id, securityNamespace) {
+			return namespace.token, nil
+		}
+	}
+
+	return "", fmt.Errorf("security namespace %s does not secure the organization", securityNamespace)
+}
+
+// getOrganizationPermissions returns the permissions of an organization level security namespace that are synced as
+// entitlements of the organization, one per action.
+func getOrganizationPermissions(namespace security.SecurityNamespaceDescription, resource *v2.Resource) []namespacePermission {
+	var actions []string
+	for _, organizationNamespace := range organizationSecurityNamespaces {
+		if namespace.NamespaceId != nil && strings.EqualFold(organizationNamespace.id, namespace.NamespaceId.String()) {
+			actions = organizationNamespace.actions
+		}
+	}
+
+	var permissions []namespacePermission
+	for _, permission := range getNamespacePermissions(namespace, resource, true) {
+		for _, action := range actions {
+			if strings.EqualFold(permission.action, action) {
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+
+	return permissions
+}
+
+type organizationBuilder struct {
+	resourceType  *v2.ResourceType
+	organizations []*organization
+}
+
+func (o *organizationBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return organizationResourceType
+}
+
+func (o *organizationBuilder) List(_ context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID != nil {
+		return nil, "", nil, nil
+	}
+
+	var resources []*v2.Resource
+	for _, org := range o.organizations {
+		organizationResource, err := parseIntoOrganizationResource(org)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, organizationResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+// Entitlements returns the organization level permissions of the organization security namespaces.
+func (o *organizationBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	org, err := o.organization(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	namespaces, err := org.client.ListSecurityNamespaces(ctx, organizationSecurityNamespaceIDs())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var entitlements []*v2.Entitlement
+	for _, namespace := range namespaces {
+		permissions := getOrganizationPermissions(namespace, resource)
+		entitlements = append(entitlements, getEntitlementsFromNamespacePermissions(namespace, permissions, resource)...)
+	}
+
+	return entitlements, "", withRateLimit(nil, org.client.RateLimitDescription()), nil
+}
+
+func (o *organizationBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	org, err := o.organization(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	namespaces, err := org.client.ListSecurityNamespaces(ctx, organizationSecurityNamespaceIDs())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var grants []*v2.Grant
+	for _, namespace := range namespaces {
+		permissions := getOrganizationPermissions(namespace, resource)
+		namespaceGrants, err := getGrantsFromNamespacePermissions(ctx, org, namespace, permissions, resource)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		// The organization is not synced by an organizationSyncer, so only the principals are namespaced.
+		for _, namespaceGrant := range namespaceGrants {
+			namespaced, err := namespaceGrantPrincipal(org.name, namespaceGrant)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			grants = append(grants, namespaced)
+		}
+	}
+
+	return grants, "", withRateLimit(nil, org.client.RateLimitDescription()), nil
+}
+
+func (o *organizationBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	org, localPrincipal, err := o.principalOrganization(entitlementResource.Resource, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := org.client.ListSecurityNamespaces(ctx, organizationSecurityNamespaceIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	return grantSecurityNamespacePermission(ctx, org.client, namespaces, localPrincipal, entitlementResource)
+}
+
+func (o *organizationBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	org, localPrincipal, err := o.principalOrganization(grantResource.Entitlement.Resource, grantResource.Principal)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaces, err := org.client.ListSecurityNamespaces(ctx, organizationSecurityNamespaceIDs())
+	if err != nil {
+		return nil, err
+	}
+
+	localGrant := proto.Clone(grantResource).(*v2.Grant)
+	localGrant.Principal = localPrincipal
+
+	return revokeSecurityNamespacePermission(ctx, org.client, namespaces, localGrant)
+}
+
+// organization returns the organization of an organization resource id.
+func (o *organizationBuilder) organization(organizationName string) (*organization, error) {
+	for _, org := range o.organizations {
+		if org.name == organizationName {
+			return org, nil
+		}
+	}
+
+	return nil, fmt.Errorf("baton-azure-devops: organization %s is not synced by the connector", organizationName)
+}
+
+// principalOrganization returns the organization of an organization resource, and a copy of the principal with the id
+// the organization uses. The principal must belong to the organization.
+func (o *organizationBuilder) principalOrganization(organizationResource, principal *v2.Resource) (*organization, *v2.Resource, error) {
+	org, err := o.organization(organizationResource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	principalOrganizationName, localPrincipal, err := splitOrganizationResource(principal)
+	if err != nil {
+		return nil, nil, err
+	}
+	if principalOrganizationName != org.name {
+		return nil, nil, fmt.Errorf(
+			"baton-azure-devops: %s of organization %s cannot be granted an entitlement of organization %s",
+			principal.Id.ResourceType,
+			principalOrganizationName,
+			org.name,
+		)
+	}
+
+	return org, localPrincipal, nil
+}
+
+func parseIntoOrganizationResource(org *organization) (*v2.Resource, error) {
+	childResourceTypes := []proto.Message{
+		&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: projectResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: teamResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: groupResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: agentPoolResourceType.Id},
+	}
+	if !org.client.Server {
+		childResourceTypes = append(
+			childResourceTypes,
+			&v2.ChildResourceType{ResourceTypeId: accessLevelResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: groupRuleResourceType.Id},
+		)
+	}
+
+	return resource.NewResource(org.name, organizationResourceType, org.name, resource.WithAnnotation(childResourceTypes...))
+}
+
+func newOrganizationBuilder(organizations []*organization) *organizationBuilder {
+	return &organizationBuilder{
+		resourceType:  organizationResourceType,
+		organizations: organizations,
+	}
+}
@@ -0,0 +1,119 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOrganizationPermissions(t *testing.T) {
+	newAction := func(name string, bit int) security.ActionDefinition {
+		return security.ActionDefinition{Name: &name, Bit: &bit}
+	}
+	namespaceID := uuid.MustParse(collectionSecurityNamespace)
+	name := "Collection"
+	namespace := security.SecurityNamespaceDescription{
+		NamespaceId: &namespaceID,
+		Name:        &name,
+		Actions: &[]security.ActionDefinition{
+			newAction("GENERIC_READ", 1),
+			newAction("GENERIC_WRITE", 2),
+			newAction("CREATE_PROJECTS", 4),
+			newAction("TRIGGER_EVENT", 8),
+			newAction("MANAGE_TEMPLATE", 16),
+		},
+	}
+	resource := &v2.Resource{DisplayName: "contoso", Id: &v2.ResourceId{ResourceType: organizationResourceType.Id, Resource: "contoso"}}
+
+	permissions := getOrganizationPermissions(namespace, resource)
+	var actions []string
+	for _, permission := range permissions {
+		actions = append(actions, permission.action)
+	}
+	assert.Equal(t, []string{"GENERIC_WRITE", "CREATE_PROJECTS", "MANAGE_TEMPLATE"}, actions)
+
+	entitlements := getEntitlementsFromNamespacePermissions(namespace, permissions, resource)
+	require.Len(t, entitlements, 3)
+	assert.Equal(t, "organization:contoso:contoso_Collection_CREATE_PROJECTS", entitlements[1].Id)
+
+	t.Run("organization tokens only secure organizations", func(t *testing.T) {
+		token, err := getSecurityToken(context.Background(), nil, collectionSecurityNamespace, resource)
+		require.NoError(t, err)
+		assert.Equal(t, "NAMESPACE", token)
+
+		project := &v2.Resource{Id: &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: "projectId"}}
+		_, err = getSecurityToken(context.Background(), nil, collectionSecurityNamespace, project)
+		assert.Error(t, err)
+
+		_, err = getSecurityToken(context.Background(), nil, projectSecurityNamespace, resource)
+		assert.Error(t, err)
+	})
+}
+
+func TestParseIntoGroupResource(t *testing.T) {
+	newGroup := func(displayName, domain string) *graph.GraphGroup {
+		empty := ""
+		originID := uuid.NewString()
+		return &graph.GraphGroup{
+			OriginId:    &originID,
+			DisplayName: &displayName,
+			Description: &empty,
+			Url:         &empty,
+			Descriptor:  &empty,
+			Domain:      &domain,
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		group           *graph.GraphGroup
+		adminEquivalent bool
+		scope           string
+	}{
+		{
+			name:            "project collection administrators",
+			group:           newGroup("Project Collection Administrators", "vstfs:///Framework/IdentityDomain/b8bd4b1e-5c0d-4b4b-9a5e-6d5a5b2e1f6c"),
+			adminEquivalent: true,
+			scope:           "organization",
+		},
+		{
+			name:  "project collection valid users",
+			group: newGroup("Project Collection Valid Users", "vstfs:///Framework/IdentityDomain/b8bd4b1e-5c0d-4b4b-9a5e-6d5a5b2e1f6c"),
+			scope: "organization",
+		},
+		{
+			name:            "project administrators",
+			group:           newGroup("Project Administrators", "vstfs:///Classification/TeamProject/projectId"),
+			adminEquivalent: true,
+			scope:           "project",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			groupResource, err := parseIntoGroupResource(tc.group)
+			require.NoError(t, err)
+
+			groupTrait := &v2.GroupTrait{}
+			for _, annotation := range groupResource.Annotations {
+				if annotation.MessageIs(groupTrait) {
+					require.NoError(t, annotation.UnmarshalTo(groupTrait))
+				}
+			}
+			profile := groupTrait.GetProfile().AsMap()
+			assert.Equal(t, tc.adminEquivalent, profile["admin_equivalent"])
+			assert.Equal(t, tc.scope, profile["scope"])
+			if tc.scope == "project" {
+				assert.Equal(t, "projectId", groupResource.ParentResourceId.Resource)
+			} else {
+				assert.Nil(t, groupResource.ParentResourceId)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+)
+
+// pipelineBuildActions are the actions of the Build security namespace that are modeled as pipeline entitlements.
+var pipelineBuildActions = []string{
+	"QueueBuilds",
+	"EditBuildDefinition",
+	"AdministerBuildPermissions",
+	"DeleteBuildDefinition",
+}
+
+type pipelineBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *pipelineBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return pipelineResourceType
+}
+
+func (o *pipelineBuilder) List(ctx context.Context, parent *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	if parent == nil {
+		return resources, "", nil, nil
+	}
+
+	definitions, nextPageToken, err := o.client.ListBuildDefinitions(ctx, parent.Resource, pToken.Token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, definition := range definitions {
+		definitionCopy := &definition
+		pipelineResource, err := parseIntoPipelineResource(parent.Resource, definitionCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, pipelineResource)
+	}
+
+	return resources, nextPageToken, nil, nil
+}
+
+func (o *pipelineBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	namespaces, err := o.listSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return getEntitlementsFromSecurityNamespaces(namespaces, resource, true), "", nil, nil
+}
+
+// Grants returns the permissions allowed on the pipeline, whether they are set on the pipeline itself or on one of the
+// folders containing it.
+func (o *pipelineBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var grants []*v2.Grant
+
+	namespaces, err := o.listSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	tokens, err := getPipelineSecurityTokens(ctx, o.client, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	token := tokens[len(tokens)-1]
+
+	for _, namespace := range namespaces {
+		// ACEs set on a folder are not returned for the pipeline token, so the identities holding them are collected
+		// first and their effective permissions are then evaluated on the pipeline token.
+		var descriptors []string
+		for _, folderToken := range tokens {
+			ACLs, err := o.client.ListAccessControlsBySecurityNamespace(ctx, *namespace.NamespaceId, folderToken)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			for _, acl := range ACLs {
+				if acl.AcesDictionary == nil {
+					continue
+				}
+				for descriptor := range *acl.AcesDictionary {
+					if !slices.Contains(descriptors, descriptor) {
+						descriptors = append(descriptors, descriptor)
+					}
+				}
+			}
+		}
+
+		aces, err := o.client.ListAccessControlEntries(ctx, *namespace.NamespaceId, token, descriptors)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		permissions := getNamespacePermissions(namespace, resource, true)
+		aceGrants, err := getGrantsFromAccessControlEntries(ctx, o.organization, namespace, permissions, token, resource, aces)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		grants = append(grants, aceGrants...)
+	}
+
+	return grants, "", nil, nil
+}
+
+func (o *pipelineBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	namespaces, err := o.listSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return grantSecurityNamespacePermission(ctx, o.client, namespaces, principal, entitlementResource)
+}
+
+func (o *pipelineBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	namespaces, err := o.listSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return revokeSecurityNamespacePermission(ctx, o.client, namespaces, grantResource)
+}
+
+// listSecurityNamespaces returns the Build security namespace restricted to the pipeline actions.
+func (o *pipelineBuilder) listSecurityNamespaces(ctx context.Context) ([]security.SecurityNamespaceDescription, error) {
+	namespaces, err := o.client.ListSecurityNamespaces(ctx, []string{buildSecurityNamespace})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, namespace := range namespaces {
+		if namespace.Actions == nil {
+			continue
+		}
+		var actions []security.ActionDefinition
+		for _, action := range *namespace.Actions {
+			if action.Name != nil && slices.Contains(pipelineBuildActions, *action.Name) {
+				actions = append(actions, action)
+			}
+		}
+		namespaces[i].Actions = &actions
+	}
+
+	return namespaces, nil
+}
+
+// getPipelineSecurityTokens returns the Build security tokens from the project down to the pipeline, e.g.
+// projectId, projectId/folder, projectId/folder/definitionId.
+func getPipelineSecurityTokens(ctx context.Context, client client.AzureDevOpsClientInterface, resource *v2.Resource) ([]string, error) {
+	projectID, definitionID, err := parsePipelineID(resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	definition, err := client.GetBuildDefinition(ctx, projectID, definitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []string{projectID}
+	token := projectID
+	if definition.Path != nil {
+		for _, folder := range strings.Split(*definition.Path, `\`) {
+			if folder == "" {
+				continue
+			}
+			token = fmt.Sprintf("%s/%s", token, folder)
+			tokens = append(tokens, token)
+		}
+	}
+	tokens = append(tokens, fmt.Sprintf("%s/%d", token, definitionID))
+
+	return tokens, nil
+}
+
+// getPipelineSecurityToken returns the Build security token of a pipeline.
+func getPipelineSecurityToken(ctx context.Context, client client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error) {
+	tokens, err := getPipelineSecurityTokens(ctx, client, resource)
+	if err != nil {
+		return "", err
+	}
+
+	return tokens[len(tokens)-1], nil
+}
+
+// parsePipelineID splits a pipeline resource id, formatted as projectId/definitionId.
+func parsePipelineID(pipelineID string) (string, int, error) {
+	projectID, definition, found := strings.Cut(pipelineID, "/")
+	if !found || projectID == "" {
+		return "", 0, fmt.Errorf("invalid pipeline id '%s'", pipelineID)
+	}
+
+	definitionID, err := strconv.Atoi(definition)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid pipeline id '%s': %w", pipelineID, err)
+	}
+
+	return projectID, definitionID, nil
+}
+
+func parseIntoPipelineResource(projectID string, definition *build.BuildDefinitionReference) (*v2.Resource, error) {
+	path := `\`
+	if definition.Path != nil && *definition.Path != "" {
+		path = *definition.Path
+	}
+
+	pipelineResource, err := resource.NewResource(
+		*definition.Name,
+		pipelineResourceType,
+		fmt.Sprintf("%s/%d", projectID, *definition.Id),
+		resource.WithDescription(fmt.Sprintf("Build pipeline %s in folder %s", *definition.Name, path)),
+		resource.WithParentResourceID(
+			&v2.ResourceId{
+				ResourceType: projectResourceType.Id,
+				Resource:     projectID,
+			}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return pipelineResource, nil
+}
+
+func newPipelineBuilder(c *client.AzureDevOpsClient, org *organization) *pipelineBuilder {
+	return &pipelineBuilder{
+		resourceType: pipelineResourceType,
+		client:       c,
+		organization: org,
+	}
+}
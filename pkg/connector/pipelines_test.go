@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	mockService "github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/build"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePipelineID(t *testing.T) {
+	const projectID = "2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b"
+
+	testCases := []struct {
+		pipelineID           string
+		expectedProjectID    string
+		expectedDefinitionID int
+		expectedError        string
+	}{
+		{pipelineID: projectID + "/12", expectedProjectID: projectID, expectedDefinitionID: 12},
+		{pipelineID: projectID, expectedError: "invalid pipeline id"},
+		{pipelineID: "/12", expectedError: "invalid pipeline id"},
+		{pipelineID: projectID + "/", expectedError: "invalid pipeline id"},
+		{pipelineID: projectID + "/build", expectedError: "invalid pipeline id"},
+		{pipelineID: projectID + "/folder/12", expectedError: "invalid pipeline id"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pipelineID, func(t *testing.T) {
+			projectID, definitionID, err := parsePipelineID(tc.pipelineID)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedProjectID, projectID)
+			assert.Equal(t, tc.expectedDefinitionID, definitionID)
+		})
+	}
+}
+
+func TestGetPipelineSecurityTokens(t *testing.T) {
+	const projectID = "2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b"
+	ctx := context.Background()
+	path := func(p string) *string { return &p }
+
+	mockClient := &mockService.MockAzureClient{}
+	mockClient.On("GetBuildDefinition", ctx, projectID, 1).Return(&build.BuildDefinitionReference{Path: path(`\`)}, nil)
+	mockClient.On("GetBuildDefinition", ctx, projectID, 2).Return(&build.BuildDefinitionReference{}, nil)
+	mockClient.On("GetBuildDefinition", ctx, projectID, 3).Return(&build.BuildDefinitionReference{Path: path(`\web\deploy`)}, nil)
+
+	testCases := []struct {
+		name           string
+		pipelineID     string
+		expectedTokens []string
+		expectedError  string
+	}{
+		{
+			name:           "root folder",
+			pipelineID:     projectID + "/1",
+			expectedTokens: []string{projectID, projectID + "/1"},
+		},
+		{
+			name:           "no folder",
+			pipelineID:     projectID + "/2",
+			expectedTokens: []string{projectID, projectID + "/2"},
+		},
+		{
+			name:       "nested folders",
+			pipelineID: projectID + "/3",
+			expectedTokens: []string{
+				projectID,
+				projectID + "/web",
+				projectID + "/web/deploy",
+				projectID + "/web/deploy/3",
+			},
+		},
+		{
+			name:          "malformed id",
+			pipelineID:    projectID + "/deploy",
+			expectedError: "invalid pipeline id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pipeline := &v2.Resource{Id: &v2.ResourceId{ResourceType: pipelineResourceType.Id, Resource: tc.pipelineID}}
+
+			tokens, err := getPipelineSecurityTokens(ctx, mockClient, pipeline)
+			if tc.expectedError != "" {
+				assert.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedTokens, tokens)
+
+			token, err := getPipelineSecurityToken(ctx, mockClient, pipeline)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedTokens[len(tc.expectedTokens)-1], token)
+		})
+	}
+}
@@ -10,36 +10,13 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
-)
-
-const (
-	projectSecurityNamespace             = "52d39943-cb85-4d7f-8fa8-c6baac873819"
-	taggingSecurityNamespace             = "bb50f182-8e5e-40b8-bc21-e8752a1e7ae2"
-	versionControlItemsSecurityNamespace = "a39371cf-0841-4c16-bbd3-276e341bc052"
-	analyticsViewsSecurityNamespace      = "d34d3680-dfe5-4cc6-a949-7d9c68f73cba"
-	buildSecurityNamespace               = "33344d9c-fc72-4d6f-aba5-fa317101a7e9"
-	gitRepositoriesSecurityNamespace     = "2e9eb7ed-3c0a-47d4-87c1-0ffdd275fd87"
-	metaTaskSecurityNamespace            = "f6a4de49-dbe2-4704-86dc-f8ec1a294436"
-	releaseManagementSecurityNamespace   = "c788c23e-1b46-4162-8f5e-d7585343b5de"
-)
-
-var (
-	securityNamespaces = []string{
-		projectSecurityNamespace,
-		taggingSecurityNamespace,
-		versionControlItemsSecurityNamespace,
-		analyticsViewsSecurityNamespace,
-		buildSecurityNamespace,
-		gitRepositoriesSecurityNamespace,
-		metaTaskSecurityNamespace,
-		releaseManagementSecurityNamespace,
-	}
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
 )
 
 type projectBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.AzureDevOpsClient
-	connector    *Connector
+	organization *organization
 }
 
 func (o *projectBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -67,31 +44,53 @@ func (o *projectBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pag
 }
 
 func (o *projectBuilder) Entitlements(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
-	namespaces, err := o.client.ListSecurityNamespaces(ctx, securityNamespaces)
+	namespaces, err := o.listSecurityNamespaces(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	return getEntitlementsFromSecurityNamespaces(namespaces, resource), "", nil, nil
+	return getEntitlementsFromSecurityNamespaces(namespaces, resource, o.client.SyncPermissionActions), "", nil, nil
 }
 
 // Grants always returns an empty slice for users since they don't have any entitlements.
 func (o *projectBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	err := o.connector.loadUsers(ctx)
+	namespaces, err := o.listSecurityNamespaces(ctx)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	namespaces, err := o.client.ListSecurityNamespaces(ctx, securityNamespaces)
+	grants, err := getGrantsFromSecurityNamespaces(ctx, o.organization, namespaces, resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
+	return grants, "", nil, nil
+}
 
-	grants, err := getGrantsFromSecurityNamespaces(ctx, o.client, o.connector.users, namespaces, resource)
+func (o *projectBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	namespaces, err := o.listSecurityNamespaces(ctx)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, err
 	}
-	return grants, "", nil, nil
+
+	return grantSecurityNamespacePermission(ctx, o.client, namespaces, principal, entitlementResource)
+}
+
+func (o *projectBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	namespaces, err := o.listSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return revokeSecurityNamespacePermission(ctx, o.client, namespaces, grantResource)
+}
+
+func (o *projectBuilder) listSecurityNamespaces(ctx context.Context) ([]security.SecurityNamespaceDescription, error) {
+	namespaceIDs, err := o.organization.loadSecurityNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.client.ListSecurityNamespaces(ctx, namespaceIDs)
 }
 
 func getPermissionName(projectName, namespace, action string) string {
@@ -104,7 +103,15 @@ func parseIntoProjectResource(project *core.TeamProjectReference) (*v2.Resource,
 		projectResourceType,
 		project.Id.String(),
 		resource.WithAnnotation(
+			// Teams are only listed per project when the organization wide teams API is not available.
+			&v2.ChildResourceType{ResourceTypeId: teamResourceType.Id},
 			&v2.ChildResourceType{ResourceTypeId: repositoryResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: pipelineResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: serviceConnectionResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: agentQueueResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: environmentResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: variableGroupResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: secureFileResourceType.Id},
 		),
 	)
 	if err != nil {
@@ -114,10 +121,10 @@ func parseIntoProjectResource(project *core.TeamProjectReference) (*v2.Resource,
 	return userResource, nil
 }
 
-func newProjectBuilder(c *client.AzureDevOpsClient, d *Connector) *projectBuilder {
+func newProjectBuilder(c *client.AzureDevOpsClient, org *organization) *projectBuilder {
 	return &projectBuilder{
 		resourceType: projectResourceType,
 		client:       c,
-		connector:    d,
+		organization: org,
 	}
 }
@@ -14,7 +14,7 @@ import (
 type repositoryBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.AzureDevOpsClient
-	connector    *Connector
+	organization *organization
 }
 
 func (o *repositoryBuilder) ResourceType(_ context.Context) *v2.ResourceType {
@@ -49,27 +49,40 @@ func (o *repositoryBuilder) Entitlements(ctx context.Context, resource *v2.Resou
 		return nil, "", nil, err
 	}
 
-	return getEntitlementsFromSecurityNamespaces(namespaces, resource), "", nil, nil
+	return getEntitlementsFromSecurityNamespaces(namespaces, resource, o.client.SyncPermissionActions), "", nil, nil
 }
 
 // Grants always returns an empty slice for users since they don't have any entitlements.
 func (o *repositoryBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
-	err := o.connector.loadUsers(ctx)
+	namespaces, err := o.client.ListSecurityNamespaces(ctx, []string{gitRepositoriesSecurityNamespace})
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	namespaces, err := o.client.ListSecurityNamespaces(ctx, []string{gitRepositoriesSecurityNamespace})
+	grants, err := getGrantsFromSecurityNamespaces(ctx, o.organization, namespaces, resource)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	grants, err := getGrantsFromSecurityNamespaces(ctx, o.client, o.connector.users, namespaces, resource)
+	return grants, "", nil, nil
+}
+
+func (o *repositoryBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	namespaces, err := o.client.ListSecurityNamespaces(ctx, []string{gitRepositoriesSecurityNamespace})
 	if err != nil {
-		return nil, "", nil, err
+		return nil, err
 	}
 
-	return grants, "", nil, nil
+	return grantSecurityNamespacePermission(ctx, o.client, namespaces, principal, entitlementResource)
+}
+
+func (o *repositoryBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	namespaces, err := o.client.ListSecurityNamespaces(ctx, []string{gitRepositoriesSecurityNamespace})
+	if err != nil {
+		return nil, err
+	}
+
+	return revokeSecurityNamespacePermission(ctx, o.client, namespaces, grantResource)
 }
 
 func parseIntoRepositoryResource(repository *git.GitRepository) (*v2.Resource, error) {
@@ -90,10 +103,10 @@ func parseIntoRepositoryResource(repository *git.GitRepository) (*v2.Resource, e
 	return userResource, nil
 }
 
-func newRepositoryBuilder(c *client.AzureDevOpsClient, d *Connector) *repositoryBuilder {
+func newRepositoryBuilder(c *client.AzureDevOpsClient, org *organization) *repositoryBuilder {
 	return &repositoryBuilder{
 		resourceType: repositoryResourceType,
 		client:       c,
-		connector:    d,
+		organization: org,
 	}
 }
@@ -4,6 +4,13 @@ import (
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 )
 
+// The organization resource type is the root of the projects, teams, groups and users of an organization.
+var organizationResourceType = &v2.ResourceType{
+	Id:          "organization",
+	DisplayName: "Organization",
+	Traits:      []v2.ResourceType_Trait{},
+}
+
 // The user resource type is for all user objects from the database.
 var userResourceType = &v2.ResourceType{
 	Id:          "user",
@@ -29,7 +36,55 @@ var groupResourceType = &v2.ResourceType{
 	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
 }
 
+// The group rule resource type is for the group entitlements that assign a license and project access to the members
+// of a group.
+var groupRuleResourceType = &v2.ResourceType{
+	Id:          "group_rule",
+	DisplayName: "Group Rule",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
+
 var repositoryResourceType = &v2.ResourceType{
 	Id:          "repository",
 	DisplayName: "Repository",
 }
+
+var accessLevelResourceType = &v2.ResourceType{
+	Id:          "access_level",
+	DisplayName: "Access Level",
+}
+
+var pipelineResourceType = &v2.ResourceType{
+	Id:          "pipeline",
+	DisplayName: "Pipeline",
+}
+
+var serviceConnectionResourceType = &v2.ResourceType{
+	Id:          "service_connection",
+	DisplayName: "Service Connection",
+}
+
+var agentPoolResourceType = &v2.ResourceType{
+	Id:          "agent_pool",
+	DisplayName: "Agent Pool",
+}
+
+var agentQueueResourceType = &v2.ResourceType{
+	Id:          "agent_queue",
+	DisplayName: "Agent Queue",
+}
+
+var environmentResourceType = &v2.ResourceType{
+	Id:          "environment",
+	DisplayName: "Environment",
+}
+
+var variableGroupResourceType = &v2.ResourceType{
+	Id:          "variable_group",
+	DisplayName: "Variable Group",
+}
+
+var secureFileResourceType = &v2.ResourceType{
+	Id:          "secure_file",
+	DisplayName: "Secure File",
+}
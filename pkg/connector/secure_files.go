@@ -0,0 +1,122 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	"github.com/conductorone/baton-azure-devops/pkg/client/securefiles"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type secureFileBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *secureFileBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return secureFileResourceType
+}
+
+func (o *secureFileBuilder) List(ctx context.Context, parent *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	if parent == nil {
+		return resources, "", nil, nil
+	}
+
+	files, err := o.client.ListSecureFiles(ctx, parent.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, file := range files {
+		profile, err := getPipelinePermissionsProfile(ctx, o.client, parent.Resource, "securefile", file.Id.String())
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		fileCopy := &file
+		fileResource, err := parseIntoSecureFileResource(parent.Resource, fileCopy, profile)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, fileResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *secureFileBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := getRoleEntitlements(resource, libraryRoles)
+	entitlements = append(entitlements, getPipelineAuthorizedEntitlement(resource))
+
+	return entitlements, "", nil, nil
+}
+
+func (o *secureFileBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	grants, err := getRoleGrants(ctx, o.organization, secureFileRoleScope, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Resources are identified by projectId$id, the id of the resource in its security role scope.
+	projectID, resourceID, _ := strings.Cut(resource.Id.Resource, "$")
+	pipelineGrants, err := getPipelineAuthorizedGrants(ctx, o.client, projectID, "securefile", resourceID, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	grants = append(grants, pipelineGrants...)
+
+	return grants, "", nil, nil
+}
+
+func (o *secureFileBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	if entitlementResource.Slug == pipelineAuthorizedPermission {
+		return grantPipelineAuthorization(ctx, o.client, "securefile", principal, entitlementResource)
+	}
+
+	return grantRole(ctx, o.client, secureFileRoleScope, libraryRoles, principal, entitlementResource)
+}
+
+func (o *secureFileBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	if grantResource.Entitlement.Slug == pipelineAuthorizedPermission {
+		return revokePipelineAuthorization(ctx, o.client, "securefile", grantResource)
+	}
+
+	return revokeRole(ctx, o.client, secureFileRoleScope, libraryRoles, grantResource)
+}
+
+// parseIntoSecureFileResource builds a secure file resource identified by projectId$secureFileId, the id of the
+// secure file in its security role scope.
+func parseIntoSecureFileResource(projectID string, file *securefiles.SecureFile, profile map[string]interface{}) (*v2.Resource, error) {
+	fileResource, err := resource.NewResource(
+		*file.Name,
+		secureFileResourceType,
+		fmt.Sprintf("%s$%s", projectID, file.Id.String()),
+		resource.WithAppTrait(resource.WithAppProfile(profile)),
+		resource.WithParentResourceID(
+			&v2.ResourceId{
+				ResourceType: projectResourceType.Id,
+				Resource:     projectID,
+			}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return fileResource, nil
+}
+
+func newSecureFileBuilder(c *client.AzureDevOpsClient, org *organization) *secureFileBuilder {
+	return &secureFileBuilder{
+		resourceType: secureFileResourceType,
+		client:       c,
+		organization: org,
+	}
+}
@@ -0,0 +1,169 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/workitemtracking"
+)
+
+const (
+	projectSecurityNamespace              = "52d39943-cb85-4d7f-8fa8-c6baac873819"
+	taggingSecurityNamespace              = "bb50f182-8e5e-40b8-bc21-e8752a1e7ae2"
+	versionControlItemsSecurityNamespace  = "a39371cf-0841-4c16-bbd3-276e341bc052"
+	analyticsViewsSecurityNamespace       = "d34d3680-dfe5-4cc6-a949-7d9c68f73cba"
+	buildSecurityNamespace                = "33344d9c-fc72-4d6f-aba5-fa317101a7e9"
+	gitRepositoriesSecurityNamespace      = "2e9eb7ed-3c0a-47d4-87c1-0ffdd275fd87"
+	metaTaskSecurityNamespace             = "f6a4de49-dbe2-4704-86dc-f8ec1a294436"
+	releaseManagementSecurityNamespace    = "c788c23e-1b46-4162-8f5e-d7585343b5de"
+	workItemQueryFoldersSecurityNamespace = "71356614-aad7-4757-8f2c-0fb3bff6f680"
+	cssSecurityNamespace                  = "83e28ad4-2d72-4ceb-97b0-c7726d5502c3"
+	iterationSecurityNamespace            = "bf7bfa03-b2b7-47db-8113-fa2e002cc5b1"
+	serviceEndpointsSecurityNamespace     = "49b48001-ca20-4adc-8111-5b60c903a50c"
+	librarySecurityNamespace              = "b7e84409-6553-448a-bbb2-af228e07cbeb"
+	environmentSecurityNamespace          = "83d4c2e6-e57d-4d6e-892b-b87222b7ad20"
+	collectionSecurityNamespace           = "3e65f728-f8bc-4ecd-8764-7e378b19bfa7"
+	processSecurityNamespace              = "2dab47f9-bd70-49ed-9bd5-8eb051e59c02"
+	auditLogSecurityNamespace             = "a6cc6381-a1ca-4b36-b3c1-4e65211e82b6"
+	identitySecurityNamespace             = "5a27515b-ccd7-42c9-84f1-54c998f03866"
+)
+
+// defaultProjectSecurityNamespaces are the security namespaces synced at the project level when none are configured.
+var defaultProjectSecurityNamespaces = []string{
+	projectSecurityNamespace,
+	taggingSecurityNamespace,
+	versionControlItemsSecurityNamespace,
+	analyticsViewsSecurityNamespace,
+	buildSecurityNamespace,
+	gitRepositoriesSecurityNamespace,
+	metaTaskSecurityNamespace,
+	releaseManagementSecurityNamespace,
+}
+
+// securityTokenBuilder builds the security token that secures a resource in a security namespace.
+type securityTokenBuilder func(ctx context.Context, client client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error)
+
+// securityTokenBuilders holds the token format of every supported security namespace, keyed by namespace id.
+// Only namespaces with a registered builder can be synced.
+var securityTokenBuilders = map[string]securityTokenBuilder{}
+
+// registerSecurityTokenBuilder adds support for a security namespace.
+func registerSecurityTokenBuilder(securityNamespace string, builder securityTokenBuilder) {
+	securityTokenBuilders[securityNamespace] = builder
+}
+
+// resourceIDToken builds a token by formatting the resource id.
+func resourceIDToken(format string) securityTokenBuilder {
+	return func(_ context.Context, _ client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error) {
+		return fmt.Sprintf(format, resource.Id.Resource), nil
+	}
+}
+
+// classificationNodeToken builds the token of the root area or iteration node of a project.
+func classificationNodeToken(structureGroup workitemtracking.TreeStructureGroup) securityTokenBuilder {
+	return func(ctx context.Context, client client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error) {
+		nodeID, err := client.GetRootClassificationNodeID(ctx, resource.Id.Resource, structureGroup)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("vstfs:///Classification/Node/%s", nodeID), nil
+	}
+}
+
+func init() {
+	registerSecurityTokenBuilder(projectSecurityNamespace, resourceIDToken("$PROJECT:vstfs:///Classification/TeamProject/%s"))
+	registerSecurityTokenBuilder(taggingSecurityNamespace, resourceIDToken("/%s"))
+	registerSecurityTokenBuilder(versionControlItemsSecurityNamespace, func(_ context.Context, _ client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error) {
+		return fmt.Sprintf("$/%s", resource.DisplayName), nil
+	})
+	registerSecurityTokenBuilder(analyticsViewsSecurityNamespace, resourceIDToken("$/Shared/%s"))
+	registerSecurityTokenBuilder(buildSecurityNamespace, func(ctx context.Context, client client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error) {
+		if resource.Id.ResourceType == pipelineResourceType.Id {
+			return getPipelineSecurityToken(ctx, client, resource)
+		}
+		return resource.Id.Resource, nil
+	})
+	registerSecurityTokenBuilder(gitRepositoriesSecurityNamespace, func(_ context.Context, _ client.AzureDevOpsClientInterface, resource *v2.Resource) (string, error) {
+		if resource.ParentResourceId != nil {
+			return fmt.Sprintf("repoV2/%s/%s", resource.ParentResourceId.Resource, resource.Id.Resource), nil
+		}
+		return fmt.Sprintf("repoV2/%s", resource.Id.Resource), nil
+	})
+	registerSecurityTokenBuilder(metaTaskSecurityNamespace, resourceIDToken("%s"))
+	registerSecurityTokenBuilder(releaseManagementSecurityNamespace, resourceIDToken("%s"))
+	registerSecurityTokenBuilder(workItemQueryFoldersSecurityNamespace, resourceIDToken("$/%s"))
+	registerSecurityTokenBuilder(cssSecurityNamespace, classificationNodeToken(workitemtracking.TreeStructureGroupValues.Areas))
+	registerSecurityTokenBuilder(iterationSecurityNamespace, classificationNodeToken(workitemtracking.TreeStructureGroupValues.Iterations))
+	registerSecurityTokenBuilder(serviceEndpointsSecurityNamespace, resourceIDToken("endpoints/%s"))
+	registerSecurityTokenBuilder(librarySecurityNamespace, resourceIDToken("Library/%s"))
+	registerSecurityTokenBuilder(environmentSecurityNamespace, resourceIDToken("Environments/%s"))
+}
+
+// getSecurityToken returns the security token of a resource in a security namespace. Organizations are secured by the
+// organization level security namespaces, and every other resource by the project level ones.
+func getSecurityToken(ctx context.Context, client client.AzureDevOpsClientInterface, securityNamespace string, resource *v2.Resource) (string, error) {
+	if resource.Id.ResourceType == organizationResourceType.Id {
+		return getOrganizationSecurityToken(securityNamespace)
+	}
+
+	builder, ok := securityTokenBuilders[strings.ToLower(securityNamespace)]
+	if !ok {
+		return "", fmt.Errorf("unsupported security namespace %s", securityNamespace)
+	}
+
+	return builder(ctx, client, resource)
+}
+
+// resolveSecurityNamespaces returns the ids of the configured security namespaces, which may be given by name or id,
+// without the excluded ones. When no namespaces are configured the default project namespaces are used.
+func resolveSecurityNamespaces(namespaces []security.SecurityNamespaceDescription, included, excluded []string) ([]string, error) {
+	if len(included) == 0 {
+		included = defaultProjectSecurityNamespaces
+	}
+
+	findNamespace := func(nameOrID string) (string, error) {
+		nameOrID = strings.TrimSpace(nameOrID)
+		for _, namespace := range namespaces {
+			if namespace.NamespaceId == nil {
+				continue
+			}
+			namespaceID := namespace.NamespaceId.String()
+			if strings.EqualFold(namespaceID, nameOrID) || (namespace.Name != nil && strings.EqualFold(*namespace.Name, nameOrID)) {
+				return namespaceID, nil
+			}
+		}
+		return "", fmt.Errorf("security namespace '%s' not found", nameOrID)
+	}
+
+	excludedIDs := make(map[string]bool)
+	for _, nameOrID := range excluded {
+		namespaceID, err := findNamespace(nameOrID)
+		if err != nil {
+			return nil, err
+		}
+		excludedIDs[namespaceID] = true
+	}
+
+	namespaceIDs := make([]string, 0, len(included))
+	seen := make(map[string]bool)
+	for _, nameOrID := range included {
+		namespaceID, err := findNamespace(nameOrID)
+		if err != nil {
+			return nil, err
+		}
+		if excludedIDs[namespaceID] || seen[namespaceID] {
+			continue
+		}
+		if _, ok := securityTokenBuilders[namespaceID]; !ok {
+			return nil, fmt.Errorf("security namespace '%s' is not supported", nameOrID)
+		}
+		seen[namespaceID] = true
+		namespaceIDs = append(namespaceIDs, namespaceID)
+	}
+
+	return namespaceIDs, nil
+}
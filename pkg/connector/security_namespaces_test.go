@@ -0,0 +1,67 @@
+package connector
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecurityNamespaces(t *testing.T) {
+	newNamespace := func(id, name string) security.SecurityNamespaceDescription {
+		namespaceID := uuid.MustParse(id)
+		return security.SecurityNamespaceDescription{NamespaceId: &namespaceID, Name: &name}
+	}
+	namespaces := []security.SecurityNamespaceDescription{
+		newNamespace(projectSecurityNamespace, "Project"),
+		newNamespace(taggingSecurityNamespace, "Tagging"),
+		newNamespace(versionControlItemsSecurityNamespace, "VersionControlItems"),
+		newNamespace(analyticsViewsSecurityNamespace, "AnalyticsViews"),
+		newNamespace(buildSecurityNamespace, "Build"),
+		newNamespace(gitRepositoriesSecurityNamespace, "Git Repositories"),
+		newNamespace(metaTaskSecurityNamespace, "MetaTask"),
+		newNamespace(releaseManagementSecurityNamespace, "ReleaseManagement"),
+		newNamespace(librarySecurityNamespace, "Library"),
+		newNamespace("5a27515b-ccd7-42c9-84f1-54c998f03866", "Identity"),
+		newNamespace("101eae8c-1709-47f9-b228-0e476c35b3ba", "DistributedTask"),
+		newNamespace(collectionSecurityNamespace, "Collection"),
+		newNamespace(processSecurityNamespace, "Process"),
+		newNamespace(auditLogSecurityNamespace, "AuditLog"),
+	}
+
+	t.Run("defaults without excluded namespaces", func(t *testing.T) {
+		namespaceIDs, err := resolveSecurityNamespaces(namespaces, nil, []string{"tagging"})
+		require.NoError(t, err)
+		assert.Len(t, namespaceIDs, len(defaultProjectSecurityNamespaces)-1)
+		assert.NotContains(t, namespaceIDs, taggingSecurityNamespace)
+	})
+
+	t.Run("names and ids", func(t *testing.T) {
+		namespaceIDs, err := resolveSecurityNamespaces(namespaces, []string{"Library", projectSecurityNamespace, "git repositories"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{librarySecurityNamespace, projectSecurityNamespace, gitRepositoriesSecurityNamespace}, namespaceIDs)
+	})
+
+	t.Run("unknown namespace", func(t *testing.T) {
+		_, err := resolveSecurityNamespaces(namespaces, []string{"Unknown"}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("namespace without token builder", func(t *testing.T) {
+		_, err := resolveSecurityNamespaces(namespaces, []string{"Identity"}, nil)
+		assert.Error(t, err)
+
+		// Agent pools are secured by a single organization wide token, which no project has its own entries in.
+		_, err = resolveSecurityNamespaces(namespaces, []string{"DistributedTask"}, nil)
+		assert.ErrorContains(t, err, "is not supported")
+	})
+
+	t.Run("organization namespaces are not project namespaces", func(t *testing.T) {
+		for _, name := range []string{"Collection", "Process", "AuditLog"} {
+			_, err := resolveSecurityNamespaces(namespaces, []string{name}, nil)
+			assert.ErrorContains(t, err, "is not supported", name)
+		}
+	})
+}
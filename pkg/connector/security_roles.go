@@ -0,0 +1,235 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	"github.com/conductorone/baton-azure-devops/pkg/client/securityroles"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Security role scopes of the resources that are secured by role assignments instead of security namespace ACLs.
+const (
+	serviceEndpointRoleScope = "distributedtask.serviceendpointrole"
+	agentPoolRoleScope       = "distributedtask.agentpoolrole"
+	agentQueueRoleScope      = "distributedtask.agentqueuerole"
+	environmentRoleScope     = "distributedtask.environmentreferencerole"
+	variableGroupRoleScope   = "distributedtask.variablegroup"
+	secureFileRoleScope      = "distributedtask.securefile"
+)
+
+var (
+	administratorRole = "Administrator"
+	userRole          = "User"
+	creatorRole       = "Creator"
+	readerRole        = "Reader"
+)
+
+// getRoleEntitlements returns one entitlement per role that can be assigned on the resource.
+func getRoleEntitlements(resource *v2.Resource, roles []string) []*v2.Entitlement {
+	var entitlements []*v2.Entitlement
+
+	for _, role := range roles {
+		options := []entitlement.EntitlementOption{
+			entitlement.WithGrantableTo(userResourceType, groupResourceType, teamResourceType),
+			entitlement.WithDescription(fmt.Sprintf("%s role on %s %s", role, resource.DisplayName, resource.Id.ResourceType)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s %s", resource.DisplayName, role)),
+		}
+		entitlements = append(entitlements, entitlement.NewPermissionEntitlement(resource, strings.ToLower(role), options...))
+	}
+
+	return entitlements
+}
+
+// getRoleGrants returns a grant for every role assignment of the resource. Role assignments inherited from the
+// project or the organization are marked immutable since they cannot be revoked on the resource itself.
+func getRoleGrants(
+	ctx context.Context,
+	org *organization,
+	scopeID string,
+	resource *v2.Resource,
+) ([]*v2.Grant, error) {
+	roleAssignments, err := org.client.ListRoleAssignments(ctx, scopeID, resource.Id.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return getRoleAssignmentGrants(ctx, org, resource, roleAssignments)
+}
+
+// getRoleAssignmentGrants returns a grant for each role assignment whose identity maps to a user, group or team.
+func getRoleAssignmentGrants(
+	ctx context.Context,
+	org *organization,
+	resource *v2.Resource,
+	roleAssignments []securityroles.RoleAssignment,
+) ([]*v2.Grant, error) {
+	var grants []*v2.Grant
+
+	var identityIDs []string
+	for _, roleAssignment := range roleAssignments {
+		if roleAssignment.Identity != nil && roleAssignment.Identity.Id != nil {
+			identityIDs = append(identityIDs, *roleAssignment.Identity.Id)
+		}
+	}
+	principals, err := getIdentityResourcesByID(ctx, org, identityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, roleAssignment := range roleAssignments {
+		if roleAssignment.Identity == nil || roleAssignment.Identity.Id == nil || roleAssignment.Role == nil || roleAssignment.Role.Name == nil {
+			continue
+		}
+		principal, ok := principals[*roleAssignment.Identity.Id]
+		if !ok {
+			continue
+		}
+
+		access := securityroles.RoleAccessValues.Assigned
+		if roleAssignment.Access != nil {
+			access = *roleAssignment.Access
+		}
+		grantOptions := []grant.GrantOption{
+			grant.WithGrantMetadata(map[string]interface{}{
+				"role_access": string(access),
+			}),
+		}
+		if access == securityroles.RoleAccessValues.Inherited {
+			grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantImmutable{}))
+		}
+		if org.client.SyncGrantSources && principal.Id.ResourceType != userResourceType.Id {
+			grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: []string{
+					fmt.Sprintf("team:%s:member", principal.Id.Resource),
+					fmt.Sprintf("group:%s:member", principal.Id.Resource),
+				},
+				Shallow: true,
+			}))
+		}
+
+		grants = append(grants, grant.NewGrant(resource, strings.ToLower(*roleAssignment.Role.Name), principal, grantOptions...))
+	}
+
+	return grants, nil
+}
+
+// grantRole assigns the role of an entitlement to the principal on the entitlement resource.
+func grantRole(
+	ctx context.Context,
+	client client.AzureDevOpsClientInterface,
+	scopeID string,
+	roles []string,
+	principal *v2.Resource,
+	entitlementResource *v2.Entitlement,
+) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	role, err := getEntitlementRole(roles, entitlementResource)
+	if err != nil {
+		return nil, err
+	}
+
+	identityID, err := getIdentityID(ctx, client, principal)
+	if err != nil {
+		l.Debug("Error getting principal identity id", zap.Error(err))
+		return nil, err
+	}
+
+	resourceID := entitlementResource.Resource.Id.Resource
+	roleAssignment, err := getDirectRoleAssignment(ctx, client, scopeID, resourceID, identityID)
+	if err != nil {
+		return nil, err
+	}
+	if roleAssignment != nil && strings.EqualFold(*roleAssignment.Role.Name, role) {
+		l.Info("Role is already assigned; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
+	}
+
+	err = client.SetRoleAssignment(ctx, scopeID, resourceID, identityID, role)
+	if err != nil {
+		l.Debug("Error setting role assignment", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// revokeRole removes the role assignment of a grant. Inherited role assignments are not affected.
+func revokeRole(
+	ctx context.Context,
+	client client.AzureDevOpsClientInterface,
+	scopeID string,
+	roles []string,
+	grantResource *v2.Grant,
+) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	role, err := getEntitlementRole(roles, grantResource.Entitlement)
+	if err != nil {
+		return nil, err
+	}
+
+	identityID, err := getIdentityID(ctx, client, grantResource.Principal)
+	if err != nil {
+		l.Debug("Error getting principal identity id", zap.Error(err))
+		return nil, err
+	}
+
+	resourceID := grantResource.Entitlement.Resource.Id.Resource
+	roleAssignment, err := getDirectRoleAssignment(ctx, client, scopeID, resourceID, identityID)
+	if err != nil {
+		return nil, err
+	}
+	if roleAssignment == nil || !strings.EqualFold(*roleAssignment.Role.Name, role) {
+		l.Info("Role is not assigned; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err = client.RemoveRoleAssignment(ctx, scopeID, resourceID, identityID)
+	if err != nil {
+		l.Debug("Error removing role assignment", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// getDirectRoleAssignment returns the role directly assigned to an identity on a resource, or nil when there is none.
+func getDirectRoleAssignment(ctx context.Context, client client.AzureDevOpsClientInterface, scopeID, resourceID, identityID string) (*securityroles.RoleAssignment, error) {
+	roleAssignments, err := client.ListRoleAssignments(ctx, scopeID, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, roleAssignment := range roleAssignments {
+		if roleAssignment.Identity == nil || roleAssignment.Identity.Id == nil || roleAssignment.Role == nil || roleAssignment.Role.Name == nil {
+			continue
+		}
+		if roleAssignment.Access != nil && *roleAssignment.Access != securityroles.RoleAccessValues.Assigned {
+			continue
+		}
+		if strings.EqualFold(*roleAssignment.Identity.Id, identityID) {
+			return &roleAssignment, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func getEntitlementRole(roles []string, entitlementResource *v2.Entitlement) (string, error) {
+	for _, role := range roles {
+		if strings.EqualFold(entitlementResource.Slug, role) {
+			return role, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported role '%s' for %s", entitlementResource.Slug, entitlementResource.Resource.Id.ResourceType)
+}
@@ -0,0 +1,239 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockService "github.com/conductorone/baton-azure-devops/pkg/client"
+	"github.com/conductorone/baton-azure-devops/pkg/client/securityroles"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRoleAssignment(identityID, uniqueName string, isContainer bool, role string, access securityroles.RoleAccess) securityroles.RoleAssignment {
+	return securityroles.RoleAssignment{
+		Access:   &access,
+		Identity: &webapi.IdentityRef{Id: &identityID, UniqueName: &uniqueName, IsContainer: &isContainer},
+		Role:     &securityroles.SecurityRole{Name: &role},
+	}
+}
+
+func TestGetRoleAssignmentGrants(t *testing.T) {
+	const testUserID = "5d2f6c1b-8e4a-4f3d-9c7b-1a0e2d3c4b5a"
+	const testUserDescriptor = "aad.OTk5ZDIwNjQtOWQyMy03YzBmLWFmYDUtNWQ3ZmU1MzNhMTc4"
+	const testGroupID = "7f3c1a52-9d0e-4c1b-8a6f-2b5d9e4c7a10"
+	const testTeamID = "0a9e4d2b-5c6f-4e3a-9b1d-8c7f6e5d4c3b"
+	const testServicePrincipalID = "9e8d7c6b-5a4f-4e3d-8c2b-1a0f9e8d7c6b"
+	const testServicePrincipalDescriptor = "aadsp.ZjIxN2FjM2ItNzA4YS00ZGI1LWI2YzMtODA0MDhhZmVkOWM3"
+	const testEntraGroupID = "3b1e6d2a-4c5f-4a7e-8d9c-0f1e2d3c4b5a"
+	const testEntraGroupObjectID = "0c3f4c5e-7f2d-4b4e-9d2b-6e1f0a8b9c7d"
+	ctx := context.Background()
+
+	formerUserID := uuid.NewString()
+	newIdentity := func(id string, isContainer bool, subjectDescriptor string) *identity.Identity {
+		identityID := uuid.MustParse(id)
+		return &identity.Identity{Id: &identityID, IsContainer: &isContainer, SubjectDescriptor: &subjectDescriptor}
+	}
+	// Microsoft Entra groups are synced by their Entra object id rather than by their identity id.
+	entraGroup := newIdentity(testEntraGroupID, true, "aadgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5")
+	entraGroup.Properties = map[string]interface{}{
+		entraObjectIDProperty: map[string]interface{}{"$type": "System.String", "$value": testEntraGroupObjectID},
+	}
+	org := &organization{
+		client: &mockService.AzureDevOpsClient{SyncGrantSources: true},
+		identities: identityCache{
+			timestamp:    time.Now(),
+			teamIDs:      map[string]bool{testTeamID: true},
+			byDescriptor: map[string]*identity.Identity{},
+			byID: map[string]*identity.Identity{
+				testUserID:             newIdentity(testUserID, false, testUserDescriptor),
+				testServicePrincipalID: newIdentity(testServicePrincipalID, false, testServicePrincipalDescriptor),
+				testGroupID:            newIdentity(testGroupID, true, "vssgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5"),
+				testTeamID:             newIdentity(testTeamID, true, "vssgp.Uy0xLTktMTU1MTM3NDI0NS0yMDQ4"),
+				testEntraGroupID:       entraGroup,
+				// The identities that were read without resolving.
+				formerUserID: nil,
+			},
+		},
+	}
+	environment := &v2.Resource{Id: &v2.ResourceId{ResourceType: environmentResourceType.Id, Resource: "contoso/3"}}
+
+	grants, err := getRoleAssignmentGrants(ctx, org, environment, []securityroles.RoleAssignment{
+		newRoleAssignment(testUserID, "jamie@contoso.com", false, administratorRole, securityroles.RoleAccessValues.Assigned),
+		newRoleAssignment(testGroupID, "[Fabrikam]\\Contributors", true, userRole, securityroles.RoleAccessValues.Inherited),
+		newRoleAssignment(testTeamID, "[Fabrikam]\\Fabrikam Team", true, readerRole, securityroles.RoleAccessValues.Assigned),
+		newRoleAssignment(testServicePrincipalID, "deploy-pipeline", false, userRole, securityroles.RoleAccessValues.Assigned),
+		newRoleAssignment(testEntraGroupID, "[TEAM FOUNDATION]\\Platform Engineers", true, readerRole, securityroles.RoleAccessValues.Assigned),
+		// Identities that are not synced are left out.
+		newRoleAssignment(formerUserID, "former@contoso.com", false, readerRole, securityroles.RoleAccessValues.Assigned),
+	})
+	require.NoError(t, err)
+	require.Len(t, grants, 5)
+
+	t.Run("direct assignment", func(t *testing.T) {
+		g := grants[0]
+		assert.Equal(t, "environment:contoso/3:administrator", g.Entitlement.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testUserDescriptor}, g.Principal.Id)
+
+		annos := annotations.Annotations(g.Annotations)
+		assert.False(t, annos.Contains(&v2.GrantImmutable{}))
+		assert.False(t, annos.Contains(&v2.GrantExpandable{}))
+		metadata := &v2.GrantMetadata{}
+		ok, err := annos.Pick(metadata)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, "assigned", metadata.Metadata.AsMap()["role_access"])
+	})
+
+	t.Run("inherited assignment is immutable", func(t *testing.T) {
+		g := grants[1]
+		assert.Equal(t, "environment:contoso/3:user", g.Entitlement.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testGroupID}, g.Principal.Id)
+
+		annos := annotations.Annotations(g.Annotations)
+		assert.True(t, annos.Contains(&v2.GrantImmutable{}))
+		assert.True(t, annos.Contains(&v2.GrantExpandable{}))
+	})
+
+	t.Run("team assignment", func(t *testing.T) {
+		g := grants[2]
+		assert.Equal(t, &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: testTeamID}, g.Principal.Id)
+
+		annos := annotations.Annotations(g.Annotations)
+		assert.False(t, annos.Contains(&v2.GrantImmutable{}))
+	})
+
+	t.Run("service principal assignment", func(t *testing.T) {
+		g := grants[3]
+		assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testServicePrincipalDescriptor}, g.Principal.Id)
+	})
+
+	t.Run("entra group assignment", func(t *testing.T) {
+		g := grants[4]
+		assert.Equal(t, "environment:contoso/3:reader", g.Entitlement.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testEntraGroupObjectID}, g.Principal.Id)
+	})
+}
+
+func TestRoleProvisioning(t *testing.T) {
+	const testUserDescriptor = "aad.OTk5ZDIwNjQtOWQyMy03YzBmLWFmYDUtNWQ3ZmU1MzNhMTc4"
+	const testResourceID = "contoso/3"
+	testUserID := uuid.MustParse("5d2f6c1b-8e4a-4f3d-9c7b-1a0e2d3c4b5a")
+	const testGroupID = "7f3c1a52-9d0e-4c1b-8a6f-2b5d9e4c7a10"
+	const testInheritedGroupID = "0a9e4d2b-5c6f-4e3a-9b1d-8c7f6e5d4c3b"
+	const testEntraGroupObjectID = "0c3f4c5e-7f2d-4b4e-9d2b-6e1f0a8b9c7d"
+	const testEntraGroupDescriptor = "aadgp.Uy0xLTktMTU1MTM3NDI0NS0xMjA0NDAwOTY5"
+	testEntraGroupID := uuid.MustParse("3b1e6d2a-4c5f-4a7e-8d9c-0f1e2d3c4b5a")
+	ctx := context.Background()
+
+	mockClient := &mockService.MockAzureClient{}
+	mockClient.On("GetStorageKey", ctx, testUserDescriptor).Return(testUserID, nil)
+	mockClient.On("GetStorageKey", ctx, testEntraGroupDescriptor).Return(testEntraGroupID, nil)
+	mockClient.On("ListRoleAssignments", ctx, environmentRoleScope, testResourceID).Return([]securityroles.RoleAssignment{
+		newRoleAssignment(testUserID.String(), "jamie@contoso.com", false, userRole, securityroles.RoleAccessValues.Assigned),
+		newRoleAssignment(testGroupID, "[Fabrikam]\\Contributors", true, readerRole, securityroles.RoleAccessValues.Assigned),
+		newRoleAssignment(testInheritedGroupID, "[Fabrikam]\\Project Administrators", true, administratorRole, securityroles.RoleAccessValues.Inherited),
+	}, nil)
+	mockClient.On("SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testGroupID, userRole).Return(nil)
+	mockClient.On("SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testInheritedGroupID, administratorRole).Return(nil)
+	mockClient.On("SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testEntraGroupID.String(), readerRole).Return(nil)
+	mockClient.On("RemoveRoleAssignment", ctx, environmentRoleScope, testResourceID, testUserID.String()).Return(nil)
+
+	environment := &v2.Resource{Id: &v2.ResourceId{ResourceType: environmentResourceType.Id, Resource: testResourceID}}
+	roleEntitlement := func(slug string) *v2.Entitlement {
+		return &v2.Entitlement{Resource: environment, Slug: slug}
+	}
+	user := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testUserDescriptor}}
+	group := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testGroupID}}
+	inheritedGroup := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: testInheritedGroupID}}
+	entraGroup, err := resource.NewGroupResource("Platform Engineers", groupResourceType, testEntraGroupObjectID, []resource.GroupTraitOption{
+		resource.WithGroupProfile(map[string]interface{}{"group_id": testEntraGroupObjectID, "descriptor": testEntraGroupDescriptor}),
+	})
+	require.NoError(t, err)
+
+	t.Run("grant a role already assigned", func(t *testing.T) {
+		annos, err := grantRole(ctx, mockClient, environmentRoleScope, environmentRoles, user, roleEntitlement("user"))
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		mockClient.AssertNotCalled(t, "SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testUserID.String(), userRole)
+	})
+
+	t.Run("grant replaces the role directly assigned", func(t *testing.T) {
+		annos, err := grantRole(ctx, mockClient, environmentRoleScope, environmentRoles, group, roleEntitlement("user"))
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testGroupID, userRole)
+	})
+
+	t.Run("grant a role only inherited", func(t *testing.T) {
+		annos, err := grantRole(ctx, mockClient, environmentRoleScope, environmentRoles, inheritedGroup, roleEntitlement("administrator"))
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testInheritedGroupID, administratorRole)
+	})
+
+	t.Run("grant a role to an entra group by its identity id", func(t *testing.T) {
+		annos, err := grantRole(ctx, mockClient, environmentRoleScope, environmentRoles, entraGroup, roleEntitlement("reader"))
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "SetRoleAssignment", ctx, environmentRoleScope, testResourceID, testEntraGroupID.String(), readerRole)
+	})
+
+	t.Run("grant an unsupported role", func(t *testing.T) {
+		_, err := grantRole(ctx, mockClient, environmentRoleScope, environmentRoles, group, roleEntitlement("owner"))
+		assert.ErrorContains(t, err, "unsupported role 'owner' for environment")
+	})
+
+	t.Run("revoke a role", func(t *testing.T) {
+		annos, err := revokeRole(ctx, mockClient, environmentRoleScope, environmentRoles, &v2.Grant{Principal: user, Entitlement: roleEntitlement("user")})
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		mockClient.AssertCalled(t, "RemoveRoleAssignment", ctx, environmentRoleScope, testResourceID, testUserID.String())
+	})
+
+	t.Run("revoke a role assigned differently", func(t *testing.T) {
+		annos, err := revokeRole(ctx, mockClient, environmentRoleScope, environmentRoles, &v2.Grant{Principal: group, Entitlement: roleEntitlement("user")})
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		mockClient.AssertNotCalled(t, "RemoveRoleAssignment", ctx, environmentRoleScope, testResourceID, testGroupID)
+	})
+
+	t.Run("revoke a role only inherited", func(t *testing.T) {
+		annos, err := revokeRole(ctx, mockClient, environmentRoleScope, environmentRoles, &v2.Grant{Principal: inheritedGroup, Entitlement: roleEntitlement("administrator")})
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		mockClient.AssertNotCalled(t, "RemoveRoleAssignment", ctx, environmentRoleScope, testResourceID, testInheritedGroupID)
+	})
+}
+
+func TestGetEntitlementRole(t *testing.T) {
+	environment := &v2.Resource{Id: &v2.ResourceId{ResourceType: environmentResourceType.Id, Resource: "contoso/3"}}
+
+	testCases := []struct {
+		slug     string
+		expected string
+	}{
+		{slug: "administrator", expected: administratorRole},
+		{slug: "Reader", expected: readerRole},
+		{slug: "owner"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.slug, func(t *testing.T) {
+			role, err := getEntitlementRole(environmentRoles, &v2.Entitlement{Resource: environment, Slug: tc.slug})
+			if tc.expected == "" {
+				assert.ErrorContains(t, err, "unsupported role")
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, role)
+		})
+	}
+}
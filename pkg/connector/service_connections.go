@@ -0,0 +1,108 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/serviceendpoint"
+)
+
+var serviceConnectionRoles = []string{administratorRole, userRole, creatorRole, readerRole}
+
+type serviceConnectionBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *serviceConnectionBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return serviceConnectionResourceType
+}
+
+func (o *serviceConnectionBuilder) List(ctx context.Context, parent *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	if parent == nil {
+		return resources, "", nil, nil
+	}
+
+	endpoints, err := o.client.ListServiceEndpoints(ctx, parent.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, endpoint := range endpoints {
+		endpointCopy := &endpoint
+		serviceConnectionResource, err := parseIntoServiceConnectionResource(parent.Resource, endpointCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, serviceConnectionResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *serviceConnectionBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return getRoleEntitlements(resource, serviceConnectionRoles), "", nil, nil
+}
+
+func (o *serviceConnectionBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	grants, err := getRoleGrants(ctx, o.organization, serviceEndpointRoleScope, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return grants, "", nil, nil
+}
+
+func (o *serviceConnectionBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	return grantRole(ctx, o.client, serviceEndpointRoleScope, serviceConnectionRoles, principal, entitlementResource)
+}
+
+func (o *serviceConnectionBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	return revokeRole(ctx, o.client, serviceEndpointRoleScope, serviceConnectionRoles, grantResource)
+}
+
+// parseIntoServiceConnectionResource builds a service connection resource identified by projectId_endpointId, the id
+// of the service connection in its security role scope. A service connection shared with several projects is synced
+// once per project.
+func parseIntoServiceConnectionResource(projectID string, endpoint *serviceendpoint.ServiceEndpoint) (*v2.Resource, error) {
+	description := "Service connection"
+	if endpoint.Type != nil {
+		description = fmt.Sprintf("%s service connection", *endpoint.Type)
+	}
+	if endpoint.Description != nil && *endpoint.Description != "" {
+		description = *endpoint.Description
+	}
+
+	serviceConnectionResource, err := resource.NewResource(
+		*endpoint.Name,
+		serviceConnectionResourceType,
+		fmt.Sprintf("%s_%s", projectID, endpoint.Id.String()),
+		resource.WithDescription(description),
+		resource.WithParentResourceID(
+			&v2.ResourceId{
+				ResourceType: projectResourceType.Id,
+				Resource:     projectID,
+			}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return serviceConnectionResource, nil
+}
+
+func newServiceConnectionBuilder(c *client.AzureDevOpsClient, org *organization) *serviceConnectionBuilder {
+	return &serviceConnectionBuilder{
+		resourceType: serviceConnectionResourceType,
+		client:       c,
+		organization: org,
+	}
+}
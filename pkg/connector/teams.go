@@ -2,7 +2,10 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/conductorone/baton-azure-devops/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
@@ -20,24 +23,60 @@ import (
 type teamBuilder struct {
 	resourceType *v2.ResourceType
 	client       client.AzureDevOpsClientInterface
+	organization *organization
+
+	// allTeams records whether the organization wide teams API is available, once it is known.
+	allTeams      *bool
+	allTeamsMutex sync.Mutex
 }
 
 var (
 	adminPermission = "admin"
 
 	permissions = []string{memberPermission, adminPermission}
+
+	identitySecurityNamespaceID = uuid.MustParse(identitySecurityNamespace)
 )
 
+// manageMembershipPermission is the ManageMembership bit of the Identity security namespace. Team administrators are
+// the identities allowed to manage the membership of their team.
+const manageMembershipPermission = 8
+
 func (o *teamBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return teamResourceType
 }
 
-func (o *teamBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+// List pages through the teams of the organization, or through the teams of each project when the organization wide
+// teams API is not available.
+func (o *teamBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
 	var resources []*v2.Resource
 
-	teams, err := o.client.ListTeams(ctx)
-	if err != nil {
-		return nil, "", nil, err
+	var teams []core.WebApiTeam
+	var nextPageToken string
+	if parentResourceID != nil && parentResourceID.ResourceType == projectResourceType.Id {
+		allTeams, err := o.listsAllTeams(ctx)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if allTeams {
+			return nil, "", nil, nil
+		}
+
+		teams, nextPageToken, err = o.client.ListProjectTeams(ctx, parentResourceID.Resource, pToken.Token)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	} else {
+		var err error
+		teams, nextPageToken, err = o.client.ListTeams(ctx, pToken.Token)
+		if errors.Is(err, client.ErrNotFound) {
+			o.setAllTeams(false)
+			return nil, "", nil, nil
+		}
+		if err != nil {
+			return nil, "", nil, err
+		}
+		o.setAllTeams(true)
 	}
 
 	for _, team := range teams {
@@ -49,7 +88,32 @@ func (o *teamBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.
 		resources = append(resources, teamResource)
 	}
 
-	return resources, "", nil, nil
+	return resources, nextPageToken, nil, nil
+}
+
+// listsAllTeams returns whether the teams are listed across the organization rather than per project, checking that
+// the organization wide teams API is available when no team was listed yet.
+func (o *teamBuilder) listsAllTeams(ctx context.Context) (bool, error) {
+	o.allTeamsMutex.Lock()
+	defer o.allTeamsMutex.Unlock()
+
+	if o.allTeams == nil {
+		_, _, err := o.client.ListTeams(ctx, "")
+		if err != nil && !errors.Is(err, client.ErrNotFound) {
+			return false, err
+		}
+		allTeams := err == nil
+		o.allTeams = &allTeams
+	}
+
+	return *o.allTeams, nil
+}
+
+func (o *teamBuilder) setAllTeams(allTeams bool) {
+	o.allTeamsMutex.Lock()
+	defer o.allTeamsMutex.Unlock()
+
+	o.allTeams = &allTeams
 }
 
 func (o *teamBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
@@ -68,7 +132,7 @@ func (o *teamBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *
 	return entitlements, "", nil, nil
 }
 
-func (o *teamBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+func (o *teamBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var grants []*v2.Grant
 
 	teamId := resource.Id.Resource
@@ -77,109 +141,177 @@ func (o *teamBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagi
 	}
 	projectId := resource.ParentResourceId.Resource
 
-	members, err := o.client.ListTeamMembers(ctx, projectId, teamId)
+	members, nextPageToken, err := o.client.ListTeamMembers(ctx, projectId, teamId, pToken.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
+	ids := make([]string, 0, len(members))
 	for _, member := range members {
-		finalResource := &v2.Resource{}
-		if member.Identity.IsContainer != nil && *member.Identity.IsContainer {
-			finalResource.Id = &v2.ResourceId{
-				ResourceType: groupResourceType.Id,
-				Resource:     *member.Identity.Id,
-			}
-		} else {
-			finalResource.Id = &v2.ResourceId{
-				ResourceType: userResourceType.Id,
-				Resource:     *member.Identity.Descriptor,
-			}
+		if member.Identity != nil && member.Identity.Id != nil {
+			ids = append(ids, *member.Identity.Id)
 		}
-		permissionName := memberPermission
-		if member.IsTeamAdmin != nil && *member.IsTeamAdmin {
-			permissionName = adminPermission
+	}
+	if len(ids) == 0 {
+		return grants, nextPageToken, nil, nil
+	}
+
+	teamIDs, err := o.organization.identities.loadTeamIDs(ctx, o.client)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	// The members are shared by many teams, so they are read through the identity cache.
+	identities, err := o.organization.identities.identitiesByID(ctx, o.client, ids)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, member := range members {
+		if member.Identity == nil || member.Identity.Id == nil {
+			continue
+		}
+		memberIdentity, ok := identities[strings.ToLower(*member.Identity.Id)]
+		if !ok {
+			continue
 		}
-		var grantOptions []grant.GrantOption
-		if permissionName == adminPermission {
-			grantOptions = append(grantOptions, grant.WithAnnotation(&v2.GrantImmutable{}))
+		principal := getMemberResourceID(memberIdentity, teamIDs)
+		if principal == nil {
+			continue
+		}
+		// Team administrators are members of the team as well.
+		grants = append(grants, newMembershipGrant(resource, memberPermission, principal))
+		if member.IsTeamAdmin != nil && *member.IsTeamAdmin {
+			grants = append(grants, newMembershipGrant(resource, adminPermission, principal))
 		}
-		membershipGrant := grant.NewGrant(resource, permissionName, finalResource.Id, grantOptions...)
-		grants = append(grants, membershipGrant)
 	}
-	return grants, "", nil, nil
+	return grants, nextPageToken, nil, nil
 }
 
-func (o *teamBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+func (o *teamBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 	grantType := entitlementResource.DisplayName
-	if grantType != memberPermission {
+	if grantType != memberPermission && grantType != adminPermission {
+		l.Debug("Grant type is not supported", zap.String("grantType", grantType))
+		return nil, nil, fmt.Errorf("grant type %s not supported", grantType)
+	}
+
+	memberships := membershipProvisioner{client: o.client}
+	if grantType == memberPermission {
+		return memberships.grant(ctx, principal, entitlementResource)
+	}
+
+	// Team administrators are listed among the team members, so they are added to the team as well.
+	_, _, err := memberships.grant(ctx, principal, entitlementResource)
+	if err != nil {
+		return nil, nil, err
+	}
+	principalDescriptor, err := getSubjectDescriptor(ctx, o.client, principal)
+	if err != nil {
+		l.Debug("Error fetching principal descriptor", zap.Error(err))
+		return nil, nil, err
+	}
+	annos, err := o.grantTeamAdmin(ctx, entitlementResource.Resource, principalDescriptor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []*v2.Grant{grant.NewGrant(entitlementResource.Resource, adminPermission, principal.Id)}, annos, nil
+}
+
+func (o *teamBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+	grantType := grantResource.Entitlement.DisplayName
+	if grantType != memberPermission && grantType != adminPermission {
 		l.Debug("Grant type is not supported", zap.String("grantType", grantType))
 		return nil, fmt.Errorf("grant type %s not supported", grantType)
 	}
-	resourceId := entitlementResource.Resource.Id.Resource
-	parsedUUID, err := uuid.Parse(resourceId)
+
+	memberships := membershipProvisioner{client: o.client}
+	if grantType == memberPermission {
+		return memberships.revoke(ctx, grantResource)
+	}
+
+	// Revoking the admin entitlement keeps the principal a member of the team.
+	principalDescriptor, err := getSubjectDescriptor(ctx, o.client, grantResource.Principal)
 	if err != nil {
+		l.Debug("Error fetching principal descriptor", zap.Error(err))
 		return nil, err
 	}
-	teamDescriptor, err := o.client.GetDescriptor(ctx, parsedUUID)
+
+	return o.revokeTeamAdmin(ctx, grantResource.Entitlement.Resource, principalDescriptor)
+}
+
+// grantTeamAdmin allows a principal, by subject descriptor, to manage the membership of a team in the Identity
+// security namespace, which is what makes it a team administrator.
+func (o *teamBuilder) grantTeamAdmin(ctx context.Context, teamResource *v2.Resource, principalDescriptor string) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	token, identityDescriptor, err := o.teamAdminEntry(ctx, teamResource, principalDescriptor)
 	if err != nil {
-		l.Debug("Error getting group descriptor", zap.Error(err))
 		return nil, err
 	}
-	memberDescriptor := principal.Id.Resource
 
-	if parsedUUID, err := uuid.Parse(principal.Id.Resource); err == nil {
-		memberDescriptor, err = o.client.GetDescriptor(ctx, parsedUUID)
-		if err != nil {
-			l.Debug("Error fetching principal descriptor", zap.Error(err))
-			return nil, err
-		}
+	ace, err := o.client.GetAccessControlEntry(ctx, identitySecurityNamespaceID, token, identityDescriptor)
+	if err != nil {
+		return nil, err
+	}
+	if ace != nil && ace.Allow != nil && *ace.Allow&manageMembershipPermission != 0 {
+		l.Info("Team admin already exists; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyExists{}), nil
 	}
 
-	_, err = o.client.CreateMembership(ctx, teamDescriptor, memberDescriptor)
+	err = o.client.SetAccessControlEntry(ctx, identitySecurityNamespaceID, token, identityDescriptor, manageMembershipPermission, 0)
 	if err != nil {
-		l.Debug("Error creating membership", zap.Error(err))
+		l.Debug("Error setting team admin access control entry", zap.Error(err))
 		return nil, err
 	}
 
 	return nil, nil
 }
 
-func (o *teamBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+// revokeTeamAdmin clears the permission of a principal, by subject descriptor, to manage the membership of a team.
+func (o *teamBuilder) revokeTeamAdmin(ctx context.Context, teamResource *v2.Resource, principalDescriptor string) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
-	grantType := grantResource.Entitlement.DisplayName
-	if grantType != memberPermission {
-		l.Debug("Grant type is not supported", zap.String("grantType", grantType))
-		return nil, fmt.Errorf("grant type %s not supported", grantType)
-	}
-	principal := grantResource.Principal
-	principalDescriptor := principal.Id.Resource
-	if parsedUUID, err := uuid.Parse(principal.Id.Resource); err == nil {
-		principalDescriptor, err = o.client.GetDescriptor(ctx, parsedUUID)
-		if err != nil {
-			l.Debug("Error fetching principal descriptor", zap.Error(err))
-			return nil, err
-		}
-	}
-	resourceId := grantResource.Entitlement.Resource.Id.Resource
-	parsedUUID, err := uuid.Parse(resourceId)
+
+	token, identityDescriptor, err := o.teamAdminEntry(ctx, teamResource, principalDescriptor)
 	if err != nil {
-		l.Debug("Error parsing team uuid", zap.Error(err))
 		return nil, err
 	}
-	teamDescriptor, err := o.client.GetDescriptor(ctx, parsedUUID)
+
+	ace, err := o.client.GetAccessControlEntry(ctx, identitySecurityNamespaceID, token, identityDescriptor)
 	if err != nil {
 		return nil, err
 	}
-	err = o.client.RevokeMembership(ctx, teamDescriptor, principalDescriptor)
+	if ace == nil || ace.Allow == nil || *ace.Allow&manageMembershipPermission == 0 {
+		l.Info("Team admin to revoke not found; treating as successful because the end state is achieved")
+		return annotations.New(&v2.GrantAlreadyRevoked{}), nil
+	}
+
+	err = o.client.RemovePermission(ctx, identitySecurityNamespaceID, token, identityDescriptor, manageMembershipPermission)
 	if err != nil {
-		l.Debug("Error revoking team membership", zap.Error(err))
+		l.Debug("Error removing team admin permission", zap.Error(err))
 		return nil, err
 	}
 
 	return nil, nil
 }
 
+// teamAdminEntry returns the Identity security namespace token of a team, {projectId}\{teamId}, and the identity
+// descriptor of a principal on it.
+func (o *teamBuilder) teamAdminEntry(ctx context.Context, teamResource *v2.Resource, principalDescriptor string) (string, string, error) {
+	if teamResource.ParentResourceId == nil {
+		return "", "", fmt.Errorf("team %s has no project", teamResource.Id.Resource)
+	}
+	token := fmt.Sprintf("%s\\%s", teamResource.ParentResourceId.Resource, teamResource.Id.Resource)
+
+	identityDescriptor, err := o.client.GetIdentityDescriptor(ctx, principalDescriptor)
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, identityDescriptor, nil
+}
+
 func parseIntoTeamResource(ctx context.Context, team *core.WebApiTeam) (*v2.Resource, error) {
 	l := ctxzap.Extract(ctx)
 
@@ -213,9 +345,10 @@ func parseIntoTeamResource(ctx context.Context, team *core.WebApiTeam) (*v2.Reso
 	return ret, nil
 }
 
-func newTeamBuilder(c *client.AzureDevOpsClient) *teamBuilder {
+func newTeamBuilder(c *client.AzureDevOpsClient, org *organization) *teamBuilder {
 	return &teamBuilder{
 		resourceType: teamResourceType,
 		client:       c,
+		organization: org,
 	}
 }
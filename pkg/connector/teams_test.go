@@ -2,12 +2,19 @@ package connector
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	"time"
 
 	mockService "github.com/conductorone/baton-azure-devops/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/core"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/security"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/webapi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -17,57 +24,253 @@ func TestTeamBuilderWithMockClient(t *testing.T) {
 	const testTeamId = "11c0f886-25c4-11f0-b643-325096b39f47"
 	var parsedTeamUUUID = uuid.UUID{0x11, 0xc0, 0xf8, 0x86, 0x25, 0xc4, 0x11, 0xf0, 0xb6, 0x43, 0x32, 0x50, 0x96, 0xb3, 0x9f, 0x47}
 	const testTeamDescriptor = "testTeamDescriptor"
+	const testProjectId = "6ce954b1-ce1f-45d1-b94d-e6bf2464ba2c"
+	// The principal is not a member of the team, the admin is a member and a team admin, and the former member left.
 	const testPrincipalDescriptor = "aad.OTk5ZDIwNjQtOWQyMy03YzBmLWFmYDUtNWQ3ZmU1MzNhMTc4"
+	const testAdminDescriptor = "aad.YzJhZjQ1NDMtNGYzNC03OTA1LWI1NjYtNzZiMjE4MzE4YWFl"
+	const testFormerMemberDescriptor = "aad.ZmY1NTNkMmEtYjA3Ny03ZjQ1LWE1YjQtZTY5ZDQ4N2FkNWIx"
 	containerDescriptor := "ContainerDescriptor"
 	memberDescriptor := "MemberDescriptor"
 	testMembership := &graph.GraphMembership{
 		ContainerDescriptor: &containerDescriptor,
 		MemberDescriptor:    &memberDescriptor,
 	}
+	testTeamResource := &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: testTeamId},
+		ParentResourceId: &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: testProjectId},
+	}
 	ctx := context.Background()
 	mockClient := &mockService.MockAzureClient{}
 
 	// Mock the behavior of the methods
 	mockClient.On("GetDescriptor", ctx, parsedTeamUUUID).Return(testTeamDescriptor, nil)
+	mockClient.On("GetMembership", ctx, testTeamDescriptor, testPrincipalDescriptor).Return((*graph.GraphMembership)(nil), mockService.ErrNotFound)
+	mockClient.On("GetMembership", ctx, testTeamDescriptor, testAdminDescriptor).Return(testMembership, nil)
+	mockClient.On("GetMembership", ctx, testTeamDescriptor, testFormerMemberDescriptor).Return((*graph.GraphMembership)(nil), mockService.ErrNotFound)
 	mockClient.On("CreateMembership", ctx, testTeamDescriptor, testPrincipalDescriptor).Return(testMembership, nil)
-	mockClient.On("RevokeMembership", ctx, testTeamDescriptor, testPrincipalDescriptor).Return(nil)
+	mockClient.On("RevokeMembership", ctx, testTeamDescriptor, testAdminDescriptor).Return(nil)
+
+	// Team admins are allowed to manage the membership of the team in the Identity security namespace.
+	const testAdminToken = testProjectId + `\` + testTeamId
+	adminAllow := manageMembershipPermission
+	mockClient.On("GetIdentityDescriptor", ctx, testPrincipalDescriptor).Return("Microsoft.IdentityModel.Claims.ClaimsIdentity;member", nil)
+	mockClient.On("GetIdentityDescriptor", ctx, testAdminDescriptor).Return("Microsoft.IdentityModel.Claims.ClaimsIdentity;admin", nil)
+	mockClient.On("GetAccessControlEntry", ctx, identitySecurityNamespaceID, testAdminToken, "Microsoft.IdentityModel.Claims.ClaimsIdentity;member").
+		Return((*security.AccessControlEntry)(nil), nil)
+	mockClient.On("GetAccessControlEntry", ctx, identitySecurityNamespaceID, testAdminToken, "Microsoft.IdentityModel.Claims.ClaimsIdentity;admin").
+		Return(&security.AccessControlEntry{Allow: &adminAllow}, nil)
+	mockClient.On("SetAccessControlEntry", ctx, identitySecurityNamespaceID, testAdminToken, "Microsoft.IdentityModel.Claims.ClaimsIdentity;member", manageMembershipPermission, 0).
+		Return(nil)
+	mockClient.On("RemovePermission", ctx, identitySecurityNamespaceID, testAdminToken, "Microsoft.IdentityModel.Claims.ClaimsIdentity;admin", manageMembershipPermission).
+		Return(nil)
 	builder := &teamBuilder{client: mockClient}
 
 	t.Run("Grant team member entitlement", func(t *testing.T) {
-		principal := &v2.Resource{Id: &v2.ResourceId{Resource: testPrincipalDescriptor}}
-		entitlementResource := &v2.Entitlement{DisplayName: "member", Resource: &v2.Resource{Id: &v2.ResourceId{Resource: testTeamId}}}
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testPrincipalDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "member", Resource: testTeamResource}
 
-		_, err := builder.Grant(ctx, principal, entitlementResource)
+		grants, annos, err := builder.Grant(ctx, principal, entitlementResource)
 		require.NoError(t, err)
+		assert.Empty(t, annos)
+		require.Len(t, grants, 1)
+		assert.Equal(t, "team:"+testTeamId+":member", grants[0].Entitlement.Id)
+		assert.Equal(t, testPrincipalDescriptor, grants[0].Principal.Id.Resource)
 	})
 
-	t.Run("Grant invalid team admin entitlement should error", func(t *testing.T) {
-		principal := &v2.Resource{Id: &v2.ResourceId{Resource: testPrincipalDescriptor}}
+	t.Run("Grant existing team membership already exists", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testAdminDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "member", Resource: testTeamResource}
+
+		grants, annos, err := builder.Grant(ctx, principal, entitlementResource)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+		require.Len(t, grants, 1)
+		mockClient.AssertNotCalled(t, "CreateMembership", ctx, testTeamDescriptor, testAdminDescriptor)
+	})
+
+	t.Run("Grant team admin entitlement", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testPrincipalDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "admin", Resource: testTeamResource}
+
+		grants, annos, err := builder.Grant(ctx, principal, entitlementResource)
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+		require.Len(t, grants, 1)
+		assert.Equal(t, "team:"+testTeamId+":admin", grants[0].Entitlement.Id)
+	})
+
+	t.Run("Grant existing team admin entitlement", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testAdminDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "admin", Resource: testTeamResource}
+
+		_, annos, err := builder.Grant(ctx, principal, entitlementResource)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyExists{}))
+	})
+
+	t.Run("Grant team admin entitlement of a team without project should error", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: testPrincipalDescriptor}}
 		entitlementResource := &v2.Entitlement{DisplayName: "admin", Resource: &v2.Resource{Id: &v2.ResourceId{Resource: testTeamId}}}
 
-		_, err := builder.Grant(ctx, principal, entitlementResource)
+		_, _, err := builder.Grant(ctx, principal, entitlementResource)
 		require.Error(t, err)
 	})
 
 	t.Run("Revoke team member entitlement", func(t *testing.T) {
-		entitlementResource := &v2.Entitlement{DisplayName: "member", Resource: &v2.Resource{Id: &v2.ResourceId{Resource: testTeamId}}}
 		grant := &v2.Grant{
-			Principal:   &v2.Resource{Id: &v2.ResourceId{Resource: testPrincipalDescriptor}},
-			Entitlement: entitlementResource,
+			Principal:   &v2.Resource{Id: &v2.ResourceId{Resource: testAdminDescriptor}},
+			Entitlement: &v2.Entitlement{DisplayName: "member", Resource: testTeamResource},
 		}
-		_, err := builder.Revoke(ctx, grant)
-		assert.NoError(t, err)
+		annos, err := builder.Revoke(ctx, grant)
+		require.NoError(t, err)
+		assert.Empty(t, annos)
 	})
 
-	t.Run("Revoke invalid team admin entitlement should error", func(t *testing.T) {
-		entitlementResource := &v2.Entitlement{DisplayName: "admin", Resource: &v2.Resource{Id: &v2.ResourceId{Resource: testTeamId}}}
+	t.Run("Revoke missing team membership is already revoked", func(t *testing.T) {
+		grant := &v2.Grant{
+			Principal:   &v2.Resource{Id: &v2.ResourceId{Resource: testFormerMemberDescriptor}},
+			Entitlement: &v2.Entitlement{DisplayName: "member", Resource: testTeamResource},
+		}
+		annos, err := builder.Revoke(ctx, grant)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+		mockClient.AssertNotCalled(t, "RevokeMembership", ctx, testTeamDescriptor, testFormerMemberDescriptor)
+	})
+
+	t.Run("Revoke team admin entitlement", func(t *testing.T) {
+		grant := &v2.Grant{
+			Principal:   &v2.Resource{Id: &v2.ResourceId{Resource: testAdminDescriptor}},
+			Entitlement: &v2.Entitlement{DisplayName: "admin", Resource: testTeamResource},
+		}
+		annos, err := builder.Revoke(ctx, grant)
+		require.NoError(t, err)
+		assert.Empty(t, annos)
+	})
+
+	t.Run("Revoke missing team admin entitlement is already revoked", func(t *testing.T) {
 		grant := &v2.Grant{
 			Principal:   &v2.Resource{Id: &v2.ResourceId{Resource: testPrincipalDescriptor}},
-			Entitlement: entitlementResource,
+			Entitlement: &v2.Entitlement{DisplayName: "admin", Resource: testTeamResource},
 		}
-		_, err := builder.Revoke(ctx, grant)
+		annos, err := builder.Revoke(ctx, grant)
+		require.NoError(t, err)
+		assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+	})
+
+	t.Run("Invalid team entitlement should error", func(t *testing.T) {
+		principal := &v2.Resource{Id: &v2.ResourceId{Resource: testPrincipalDescriptor}}
+		entitlementResource := &v2.Entitlement{DisplayName: "owner", Resource: testTeamResource}
+
+		_, _, err := builder.Grant(ctx, principal, entitlementResource)
+		require.Error(t, err)
+		_, err = builder.Revoke(ctx, &v2.Grant{Principal: principal, Entitlement: entitlementResource})
 		require.Error(t, err)
 	})
 
 	mockClient.AssertExpectations(t)
 }
+
+func TestTeamBuilderPagination(t *testing.T) {
+	ctx := context.Background()
+	projectID := uuid.New()
+	newTeam := func(name string) core.WebApiTeam {
+		id := uuid.New()
+		empty := ""
+		projectName := "project"
+		return core.WebApiTeam{Id: &id, Name: &name, ProjectId: &projectID, ProjectName: &projectName, Description: &empty, Url: &empty}
+	}
+	projectResourceID := &v2.ResourceId{ResourceType: projectResourceType.Id, Resource: projectID.String()}
+
+	t.Run("teams are paged across the organization", func(t *testing.T) {
+		mockClient := &mockService.MockAzureClient{}
+		mockClient.On("ListTeams", ctx, "").Return([]core.WebApiTeam{newTeam("first")}, "100", nil)
+		mockClient.On("ListTeams", ctx, "100").Return([]core.WebApiTeam{newTeam("second")}, "", nil)
+		builder := &teamBuilder{client: mockClient}
+
+		resources, nextPageToken, _, err := builder.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "100", nextPageToken)
+
+		resources, nextPageToken, _, err = builder.List(ctx, nil, &pagination.Token{Token: nextPageToken})
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, "second", resources[0].DisplayName)
+		assert.Empty(t, nextPageToken)
+
+		resources, _, _, err = builder.List(ctx, projectResourceID, &pagination.Token{})
+		require.NoError(t, err)
+		assert.Empty(t, resources)
+		mockClient.AssertNotCalled(t, "ListProjectTeams")
+	})
+
+	t.Run("teams are paged per project without the organization wide teams API", func(t *testing.T) {
+		mockClient := &mockService.MockAzureClient{}
+		mockClient.On("ListTeams", ctx, "").Return([]core.WebApiTeam{}, "", fmt.Errorf("wrapped: %w", mockService.ErrNotFound))
+		mockClient.On("ListProjectTeams", ctx, projectID.String(), "").Return([]core.WebApiTeam{newTeam("first")}, "100", nil)
+		builder := &teamBuilder{client: mockClient}
+
+		resources, _, _, err := builder.List(ctx, projectResourceID, &pagination.Token{})
+		require.NoError(t, err)
+		require.Len(t, resources, 1)
+		assert.Equal(t, projectID.String(), resources[0].ParentResourceId.Resource)
+
+		resources, _, _, err = builder.List(ctx, nil, &pagination.Token{})
+		require.NoError(t, err)
+		assert.Empty(t, resources)
+	})
+
+	t.Run("team members are paged and resolved through the identity cache", func(t *testing.T) {
+		const entraObjectID = "0c3f4c5e-7f2d-4b4e-9d2b-6e1f0a8b9c7d"
+		userDescriptor := "aad.NjY0ZjE2ZTQtMzY4ZS03N2U0LWE3NDUtYjY1YmVhMjg0ZWQx"
+		userID, entraGroupID, nestedTeamID := uuid.New(), uuid.New(), uuid.New()
+		isContainer, isUser := true, false
+		newMember := func(id uuid.UUID, isContainer bool) webapi.TeamMember {
+			memberID := id.String()
+			return webapi.TeamMember{Identity: &webapi.IdentityRef{Id: &memberID, IsContainer: &isContainer}}
+		}
+
+		mockClient := &mockService.MockAzureClient{}
+		// Team administrators are granted both the member and the admin entitlements.
+		isTeamAdmin := true
+		admin := newMember(userID, false)
+		admin.IsTeamAdmin = &isTeamAdmin
+		mockClient.On("ListTeamMembers", ctx, projectID.String(), "teamId", "100").Return([]webapi.TeamMember{
+			admin,
+			newMember(entraGroupID, true),
+			newMember(nestedTeamID, true),
+		}, "200", nil)
+		builder := &teamBuilder{
+			client: mockClient,
+			organization: &organization{
+				identities: identityCache{
+					timestamp:    time.Now(),
+					teamIDs:      map[string]bool{nestedTeamID.String(): true},
+					byDescriptor: map[string]*identity.Identity{},
+					byID: map[string]*identity.Identity{
+						userID.String(): {Id: &userID, IsContainer: &isUser, SubjectDescriptor: &userDescriptor},
+						entraGroupID.String(): {Id: &entraGroupID, IsContainer: &isContainer, Properties: map[string]interface{}{
+							entraObjectIDProperty: map[string]interface{}{"$type": "System.String", "$value": entraObjectID},
+						}},
+						nestedTeamID.String(): {Id: &nestedTeamID, IsContainer: &isContainer},
+					},
+				},
+			},
+		}
+
+		teamResource := &v2.Resource{
+			Id:               &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: "teamId"},
+			ParentResourceId: projectResourceID,
+		}
+		grants, nextPageToken, _, err := builder.Grants(ctx, teamResource, &pagination.Token{Token: "100"})
+		require.NoError(t, err)
+		require.Len(t, grants, 4)
+		assert.Equal(t, "team:teamId:member", grants[0].Entitlement.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: userDescriptor}, grants[0].Principal.Id)
+		assert.Equal(t, "team:teamId:admin", grants[1].Entitlement.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: userDescriptor}, grants[1].Principal.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: entraObjectID}, grants[2].Principal.Id)
+		assert.Equal(t, &v2.ResourceId{ResourceType: teamResourceType.Id, Resource: nestedTeamID.String()}, grants[3].Principal.Id)
+		assert.Equal(t, "200", nextPageToken)
+	})
+}
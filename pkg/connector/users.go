@@ -2,7 +2,9 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/conductorone/baton-azure-devops/pkg/client"
 	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
@@ -11,9 +13,12 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/accounts"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
 	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/licensing"
+	"go.uber.org/zap"
 )
 
 type userBuilder struct {
@@ -25,9 +30,20 @@ func (o *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return userResourceType
 }
 
-// List returns all the users from the database as resource objects.
+// servicePrincipalsPageToken prefixes the page tokens of the service principals, which are listed after the users.
+const servicePrincipalsPageToken = "service_principals:"
+
+// servicePrincipalDescriptorPrefix is the prefix of the subject descriptors of Microsoft Entra service principals.
+const servicePrincipalDescriptorPrefix = "aadsp."
+
+// List returns all the users from the database as resource objects, followed by the service principals, which are
+// synced as service accounts since they are granted group memberships and permissions the same way users are.
 // Users include a UserTrait because they are the 'shape' of a standard user.
 func (o *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if servicePrincipalsToken, ok := strings.CutPrefix(pToken.Token, servicePrincipalsPageToken); ok {
+		return o.listServicePrincipals(ctx, servicePrincipalsToken)
+	}
+
 	var resources []*v2.Resource
 
 	users, nextPageToken, err := o.client.ListUsers(ctx, pToken.Token)
@@ -44,6 +60,37 @@ func (o *userBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pagina
 		resources = append(resources, organizationResource)
 	}
 
+	if nextPageToken == "" && !o.client.Server {
+		nextPageToken = servicePrincipalsPageToken
+	}
+
+	return resources, nextPageToken, nil, nil
+}
+
+func (o *userBuilder) listServicePrincipals(ctx context.Context, pageToken string) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	servicePrincipals, nextPageToken, err := o.client.ListServicePrincipals(ctx, pageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, servicePrincipal := range servicePrincipals {
+		if servicePrincipal.Descriptor == nil {
+			continue
+		}
+		servicePrincipalCopy := &servicePrincipal
+		servicePrincipalResource, err := parseIntoServicePrincipalResource(servicePrincipalCopy)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, servicePrincipalResource)
+	}
+
+	if nextPageToken != "" {
+		nextPageToken = servicePrincipalsPageToken + nextPageToken
+	}
+
 	return resources, nextPageToken, nil, nil
 }
 
@@ -65,6 +112,7 @@ func (o *userBuilder) CreateAccount(
 	annotations.Annotations,
 	error,
 ) {
+	l := ctxzap.Extract(ctx)
 	profile := accountInfo.GetProfile().AsMap()
 
 	principalNameRaw, ok := profile["principal_name"]
@@ -84,6 +132,11 @@ func (o *userBuilder) CreateAccount(
 		return nil, nil, nil, err
 	}
 
+	requestedProjectEntitlements, err := projectEntitlements(ctx, o.client, profile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	// In azure devops the subjectKind is always user.
 	subjectKind := "user"
 	userEntitlement := &userentitlement.UserEntitlement{
@@ -96,22 +149,288 @@ func (o *userBuilder) CreateAccount(
 			SubjectKind:   &subjectKind,
 		},
 	}
+	if len(requestedProjectEntitlements) > 0 {
+		userEntitlement.ProjectEntitlements = &requestedProjectEntitlements
+	}
 
-	created, err := o.client.CreateUserAccount(ctx, userEntitlement)
+	resp, err := o.client.CreateUserAccount(ctx, userEntitlement)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	resourceC, err := parseIntoUserResource(created)
+	resourceC, err := parseIntoUserResource(resp.UserEntitlement)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to build user resource: %w", err)
 	}
 
+	// The user is created even when it could not be added to some of its projects. The failed projects are reported to
+	// the caller in an action required result rather than failing the creation of the account.
+	failed, unattributedErrors := failedProjectEntitlements(requestedProjectEntitlements, resp)
+	if len(failed) > 0 {
+		for _, failure := range failed {
+			l.Warn("Failed to add the created user to a project",
+				zap.String("project_id", failure.ProjectRef.Id.String()),
+				zap.String("group_type", string(*failure.Group.GroupType)),
+				zap.Strings("errors", failure.errors),
+			)
+		}
+		if len(unattributedErrors) > 0 {
+			l.Warn("Failed to add the created user to projects", zap.Strings("errors", unattributedErrors))
+		}
+
+		return &v2.CreateAccountResponse_ActionRequiredResult{
+			Resource:              resourceC,
+			Message:               failedProjectEntitlementsMessage(failed, unattributedErrors),
+			IsCreateAccountResult: true,
+		}, nil, nil, nil
+	}
+
 	return &v2.CreateAccountResponse_SuccessResult{
 		Resource: resourceC,
 	}, nil, nil, nil
 }
 
+// projectEntitlements returns the project entitlements requested at account or group rule creation: every project of
+// the "projects" field, by name or id, with the group of the "project_group" field.
+func projectEntitlements(ctx context.Context, c *client.AzureDevOpsClient, profile map[string]interface{}) ([]userentitlement.ProjectEntitlement, error) {
+	projects, err := profileStrings(profile, "projects")
+	if err != nil {
+		return nil, err
+	}
+	if len(projects) == 0 {
+		return nil, nil
+	}
+
+	projectGroup, _ := profile["project_group"].(string)
+	groupType, err := mapProjectGroupType(projectGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	projectEntitlements := make([]userentitlement.ProjectEntitlement, 0, len(projects))
+	for _, project := range projects {
+		projectID, err := projectID(ctx, c, project)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project '%s': %w", project, err)
+		}
+		projectEntitlements = append(projectEntitlements, userentitlement.ProjectEntitlement{
+			Group:      &userentitlement.Group{GroupType: groupType},
+			ProjectRef: &userentitlement.ProjectRef{Id: &projectID},
+		})
+	}
+
+	return projectEntitlements, nil
+}
+
+// projectID returns the id of a project, by name, id or resource id.
+func projectID(ctx context.Context, c *client.AzureDevOpsClient, project string) (uuid.UUID, error) {
+	// The ids of project resources are prefixed with their organization when several organizations are synced. Project
+	// names cannot contain a slash.
+	if _, id, ok := strings.Cut(project, "/"); ok {
+		project = id
+	}
+
+	projectID, err := uuid.Parse(project)
+	if err == nil {
+		return projectID, nil
+	}
+
+	return c.GetProjectID(ctx, project)
+}
+
+// failedProjectEntitlement is a requested project entitlement that the created user did not get, with the errors that
+// name its project.
+type failedProjectEntitlement struct {
+	userentitlement.ProjectEntitlement
+	errors []string
+}
+
+// failedProjectEntitlements returns the requested project entitlements that the created user did not get, when the
+// operation that created it did not succeed, along with the errors that name none of them. The errors of the
+// operation are matched to the projects by id; when a single project failed, it gets every error.
+func failedProjectEntitlements(requested []userentitlement.ProjectEntitlement, resp *userentitlement.UserEntitlementsPostResponse) ([]failedProjectEntitlement, []string) {
+	if resp.OperationResult == nil || resp.OperationResult.IsSuccess == nil || *resp.OperationResult.IsSuccess {
+		return nil, nil
+	}
+
+	granted := make(map[uuid.UUID]bool)
+	if resp.UserEntitlement != nil && resp.UserEntitlement.ProjectEntitlements != nil {
+		for _, projectEntitlement := range *resp.UserEntitlement.ProjectEntitlements {
+			if projectEntitlement.ProjectRef != nil && projectEntitlement.ProjectRef.Id != nil {
+				granted[*projectEntitlement.ProjectRef.Id] = true
+			}
+		}
+	}
+
+	var failed []failedProjectEntitlement
+	for _, projectEntitlement := range requested {
+		if !granted[*projectEntitlement.ProjectRef.Id] {
+			failed = append(failed, failedProjectEntitlement{ProjectEntitlement: projectEntitlement})
+		}
+	}
+	if len(failed) == 0 {
+		return nil, nil
+	}
+
+	var unattributedErrors []string
+	for _, operationError := range client.OperationErrors(resp.OperationResult.Errors) {
+		matched := false
+		for i := range failed {
+			if strings.Contains(strings.ToLower(operationError), failed[i].ProjectRef.Id.String()) {
+				failed[i].errors = append(failed[i].errors, operationError)
+				matched = true
+			}
+		}
+		if matched {
+			continue
+		}
+		if len(failed) == 1 {
+			failed[0].errors = append(failed[0].errors, operationError)
+			continue
+		}
+		unattributedErrors = append(unattributedErrors, operationError)
+	}
+
+	return failed, unattributedErrors
+}
+
+// failedProjectEntitlementsMessage describes the projects that the created user could not be added to, with their
+// errors, so that they can be granted again.
+func failedProjectEntitlementsMessage(failed []failedProjectEntitlement, unattributedErrors []string) string {
+	projects := make([]string, 0, len(failed))
+	for _, failure := range failed {
+		project := fmt.Sprintf("%s (%s)", failure.ProjectRef.Id.String(), *failure.Group.GroupType)
+		if len(failure.errors) > 0 {
+			project += ": " + strings.Join(failure.errors, "; ")
+		}
+		projects = append(projects, project)
+	}
+
+	message := "the user was created but could not be added to projects " + strings.Join(projects, ", ")
+	if len(unattributedErrors) > 0 {
+		message += ". Errors: " + strings.Join(unattributedErrors, "; ")
+	}
+	return message
+}
+
+// Delete removes a user from the organization, with its access level and memberships. Users that were already removed
+// are deleted successfully.
+func (o *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	err := checkNotServicePrincipal(resourceId.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := o.client.GetStorageKey(ctx, resourceId.Resource)
+	if err == nil {
+		err = o.client.DeleteUserEntitlement(ctx, userID)
+	}
+	if errors.Is(err, client.ErrNotFound) {
+		l.Info("User to delete not found; treating as successful because the end state is achieved")
+		return nil, nil
+	}
+	if err != nil {
+		l.Debug("Error deleting user", zap.Error(err))
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// disableUser disables the account of a user, by subject descriptor, and downgrades it to the Stakeholder access
+// level so that it no longer holds a paid license. The user keeps its memberships, so that enableUser restores its
+// access.
+func (o *userBuilder) disableUser(ctx context.Context, userDescriptor string) error {
+	l := ctxzap.Extract(ctx)
+
+	userID, userEntitlement, err := o.userEntitlement(ctx, userDescriptor)
+	if err != nil {
+		return err
+	}
+	if userEntitlement.AccessLevel != nil && userEntitlement.AccessLevel.Status != nil &&
+		*userEntitlement.AccessLevel.Status == accounts.AccountUserStatusValues.Disabled {
+		l.Info("User is already disabled; treating as successful because the end state is achieved")
+		return nil
+	}
+
+	_, err = o.client.UpdateUserEntitlement(ctx, userID, accessLevelPatch(&licensing.AccessLevel{
+		LicensingSource:    &licensing.LicensingSourceValues.Account,
+		AccountLicenseType: &licensing.AccountLicenseTypeValues.Stakeholder,
+		Status:             &accounts.AccountUserStatusValues.Disabled,
+	}))
+	if err != nil {
+		l.Debug("Error disabling user", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// enableUser restores the account of a disabled user, by subject descriptor, with a license type as accepted by
+// CreateAccount.
+func (o *userBuilder) enableUser(ctx context.Context, userDescriptor, licenseType string) error {
+	l := ctxzap.Extract(ctx)
+
+	accountLicenseType, licensingSource, err := mapLicenseType(licenseType)
+	if err != nil {
+		return err
+	}
+
+	userID, userEntitlement, err := o.userEntitlement(ctx, userDescriptor)
+	if err != nil {
+		return err
+	}
+	if userEntitlement.AccessLevel == nil || userEntitlement.AccessLevel.Status == nil ||
+		*userEntitlement.AccessLevel.Status != accounts.AccountUserStatusValues.Disabled {
+		l.Info("User is not disabled; treating as successful because the end state is achieved")
+		return nil
+	}
+
+	_, err = o.client.UpdateUserEntitlement(ctx, userID, accessLevelPatch(&licensing.AccessLevel{
+		LicensingSource:    licensingSource,
+		AccountLicenseType: accountLicenseType,
+		Status:             &accounts.AccountUserStatusValues.Active,
+	}))
+	if err != nil {
+		l.Debug("Error enabling user", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// userEntitlement returns the storage key and the entitlement of a user, by subject descriptor.
+func (o *userBuilder) userEntitlement(ctx context.Context, userDescriptor string) (uuid.UUID, *userentitlement.UserEntitlement, error) {
+	err := checkNotServicePrincipal(userDescriptor)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	userID, err := o.client.GetStorageKey(ctx, userDescriptor)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	userEntitlement, err := o.client.GetUserEntitlement(ctx, userID)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return userID, userEntitlement, nil
+}
+
+// checkNotServicePrincipal rejects the descriptors of service principals, which are synced as users but have no user
+// entitlement to delete, disable or enable.
+func checkNotServicePrincipal(userDescriptor string) error {
+	if strings.HasPrefix(userDescriptor, servicePrincipalDescriptorPrefix) {
+		return fmt.Errorf("%s is a service principal; service principals are not user accounts and cannot be deleted, disabled or enabled", userDescriptor)
+	}
+
+	return nil
+}
+
 // Function to validate and parse the license type.
 // Valid license types are:
 // - express
@@ -131,6 +450,55 @@ func mapLicenseType(input string) (*licensing.AccountLicenseType, *licensing.Lic
 	}
 }
 
+// Function to validate and parse the project group of the projects a user is added to at account creation.
+// Valid project groups are:
+// - contributors (the default)
+// - readers
+// - project administrators
+// If the project group is not valid, it returns an error.
+func mapProjectGroupType(input string) (*userentitlement.GroupType, error) {
+	switch strings.ToLower(input) {
+	case "", "contributors":
+		return &userentitlement.GroupTypeValues.ProjectContributor, nil
+	case "readers":
+		return &userentitlement.GroupTypeValues.ProjectReader, nil
+	case "project administrators":
+		return &userentitlement.GroupTypeValues.ProjectAdministrator, nil
+	default:
+		return nil, fmt.Errorf("invalid project_group '%s'; must be one of: contributors, readers, project administrators", input)
+	}
+}
+
+// profileStrings returns the values of a multi-value field of an account profile or of action arguments. A single
+// value, or values separated by commas, are accepted as well.
+func profileStrings(profile map[string]interface{}, key string) ([]string, error) {
+	var values []string
+	switch raw := profile[key].(type) {
+	case nil:
+	case string:
+		values = strings.Split(raw, ",")
+	case []interface{}:
+		for _, value := range raw {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid '%s': %v is not a string", key, value)
+			}
+			values = append(values, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid '%s': %v is not a list of strings", key, raw)
+	}
+
+	var ret []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			ret = append(ret, value)
+		}
+	}
+
+	return ret, nil
+}
+
 // Entitlements always returns an empty slice for users.
 func (o *userBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	return nil, "", nil, nil
@@ -143,7 +511,7 @@ func (o *userBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.To
 
 func parseIntoUserResource(userEntitlement *userentitlement.UserEntitlement) (*v2.Resource, error) {
 	var userStatus = v2.UserTrait_Status_STATUS_ENABLED
-	if userEntitlement.AccessLevel.Status != nil {
+	if userEntitlement.AccessLevel != nil && userEntitlement.AccessLevel.Status != nil {
 		// status valid options: none, active, disabled, deleted, pending, expired, pendingDisabled
 		switch *userEntitlement.AccessLevel.Status {
 		case accounts.AccountUserStatusValues.Disabled:
@@ -158,19 +526,29 @@ func parseIntoUserResource(userEntitlement *userentitlement.UserEntitlement) (*v
 		accountType = v2.UserTrait_ACCOUNT_TYPE_SERVICE
 	}
 
+	// Users of an Azure DevOps Server collection may have no mail address and have no last access date.
+	email := ""
+	if userEntitlement.User.MailAddress != nil {
+		email = *userEntitlement.User.MailAddress
+	}
+
 	profile := map[string]interface{}{
 		"user_descriptor": *userEntitlement.User.Descriptor,
 		"username":        *userEntitlement.User.DisplayName,
-		"email":           *userEntitlement.User.MailAddress,
+		"email":           email,
 	}
 	userTraits := []resource.UserTraitOption{
 		resource.WithUserProfile(profile),
 		resource.WithStatus(userStatus),
-		resource.WithEmail(*userEntitlement.User.MailAddress, true),
 		resource.WithUserLogin(*userEntitlement.User.DisplayName),
-		resource.WithLastLogin(userEntitlement.LastAccessedDate.Time),
 		resource.WithAccountType(accountType),
 	}
+	if email != "" {
+		userTraits = append(userTraits, resource.WithEmail(email, true))
+	}
+	if userEntitlement.LastAccessedDate != nil {
+		userTraits = append(userTraits, resource.WithLastLogin(userEntitlement.LastAccessedDate.Time))
+	}
 
 	userResource, err := resource.NewUserResource(
 		*userEntitlement.User.DisplayName,
@@ -185,9 +563,81 @@ func parseIntoUserResource(userEntitlement *userentitlement.UserEntitlement) (*v
 	return userResource, nil
 }
 
+// parseIntoServicePrincipalResource builds a service account user resource identified, like users, by its subject
+// descriptor, which is the aadsp. descriptor that group memberships and access control entries resolve to.
+func parseIntoServicePrincipalResource(servicePrincipal *graph.GraphServicePrincipal) (*v2.Resource, error) {
+	displayName := *servicePrincipal.Descriptor
+	if servicePrincipal.DisplayName != nil && *servicePrincipal.DisplayName != "" {
+		displayName = *servicePrincipal.DisplayName
+	}
+
+	profile := map[string]interface{}{
+		"user_descriptor": *servicePrincipal.Descriptor,
+		"username":        displayName,
+	}
+	if servicePrincipal.ApplicationId != nil {
+		profile["application_id"] = *servicePrincipal.ApplicationId
+	}
+	if servicePrincipal.OriginId != nil {
+		profile["origin_id"] = *servicePrincipal.OriginId
+	}
+
+	userStatus := v2.UserTrait_Status_STATUS_ENABLED
+	if servicePrincipal.IsDeletedInOrigin != nil && *servicePrincipal.IsDeletedInOrigin {
+		userStatus = v2.UserTrait_Status_STATUS_DELETED
+	}
+
+	userTraits := []resource.UserTraitOption{
+		resource.WithUserProfile(profile),
+		resource.WithStatus(userStatus),
+		resource.WithUserLogin(displayName),
+		resource.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_SERVICE),
+	}
+
+	servicePrincipalResource, err := resource.NewUserResource(
+		displayName,
+		userResourceType,
+		*servicePrincipal.Descriptor,
+		userTraits,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return servicePrincipalResource, nil
+}
+
 func newUserBuilder(c *client.AzureDevOpsClient) *userBuilder {
 	return &userBuilder{
 		resourceType: userResourceType,
 		client:       c,
 	}
 }
+
+// serverUserBuilder syncs the users of an Azure DevOps Server collection. It does not provision accounts, since the
+// users of a collection come from Active Directory.
+type serverUserBuilder struct {
+	users *userBuilder
+}
+
+func (o *serverUserBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return o.users.ResourceType(ctx)
+}
+
+func (o *serverUserBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	return o.users.List(ctx, parentResourceID, pToken)
+}
+
+func (o *serverUserBuilder) Entitlements(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return o.users.Entitlements(ctx, resource, pToken)
+}
+
+func (o *serverUserBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return o.users.Grants(ctx, resource, pToken)
+}
+
+func newServerUserBuilder(c *client.AzureDevOpsClient) *serverUserBuilder {
+	return &serverUserBuilder{
+		users: newUserBuilder(c),
+	}
+}
@@ -0,0 +1,192 @@
+package connector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client/userentitlement"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/google/uuid"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/graph"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/identity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProjectEntitlements(t *testing.T) {
+	ctx := context.Background()
+	websiteID := uuid.MustParse("2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b")
+	mobileID := uuid.MustParse("8f9e0d1c-2b3a-4c5d-8e7f-6a5b4c3d2e1f")
+
+	t.Run("projects are given as a list", func(t *testing.T) {
+		profile, err := structpb.NewStruct(map[string]interface{}{
+			"projects":      []interface{}{websiteID.String(), "contoso/" + mobileID.String()},
+			"project_group": "Readers",
+		})
+		require.NoError(t, err)
+
+		entitlements, err := projectEntitlements(ctx, nil, profile.AsMap())
+		require.NoError(t, err)
+		require.Len(t, entitlements, 2)
+		assert.Equal(t, websiteID, *entitlements[0].ProjectRef.Id)
+		assert.Equal(t, mobileID, *entitlements[1].ProjectRef.Id)
+		assert.Equal(t, userentitlement.GroupTypeValues.ProjectReader, *entitlements[1].Group.GroupType)
+	})
+
+	t.Run("projects are given as a comma separated string", func(t *testing.T) {
+		profile := map[string]interface{}{"projects": websiteID.String() + ", " + mobileID.String() + ","}
+
+		entitlements, err := projectEntitlements(ctx, nil, profile)
+		require.NoError(t, err)
+		require.Len(t, entitlements, 2)
+		assert.Equal(t, userentitlement.GroupTypeValues.ProjectContributor, *entitlements[0].Group.GroupType)
+	})
+
+	t.Run("no projects", func(t *testing.T) {
+		entitlements, err := projectEntitlements(ctx, nil, map[string]interface{}{"project_group": "readers"})
+		require.NoError(t, err)
+		assert.Empty(t, entitlements)
+	})
+
+	t.Run("invalid project group", func(t *testing.T) {
+		profile := map[string]interface{}{"projects": websiteID.String(), "project_group": "owners"}
+
+		_, err := projectEntitlements(ctx, nil, profile)
+		assert.ErrorContains(t, err, "invalid project_group 'owners'")
+	})
+
+	t.Run("invalid projects", func(t *testing.T) {
+		profile := map[string]interface{}{"projects": []interface{}{websiteID.String(), 1.0}}
+
+		_, err := projectEntitlements(ctx, nil, profile)
+		assert.ErrorContains(t, err, "invalid 'projects'")
+	})
+}
+
+func TestFailedProjectEntitlements(t *testing.T) {
+	websiteID := uuid.MustParse("2b3a6f0e-7c4d-4e8f-9a1b-0c2d3e4f5a6b")
+	mobileID := uuid.MustParse("8f9e0d1c-2b3a-4c5d-8e7f-6a5b4c3d2e1f")
+	apiID := uuid.MustParse("4c5d6e7f-8a9b-4c0d-9e1f-2a3b4c5d6e7f")
+	projectEntitlement := func(id uuid.UUID) userentitlement.ProjectEntitlement {
+		return userentitlement.ProjectEntitlement{
+			Group:      &userentitlement.Group{GroupType: &userentitlement.GroupTypeValues.ProjectContributor},
+			ProjectRef: &userentitlement.ProjectRef{Id: &id},
+		}
+	}
+	operationErrors := func(messages ...string) *[]azuredevops.KeyValuePair {
+		var kvs []azuredevops.KeyValuePair
+		for i, message := range messages {
+			var key interface{} = 5000 + i
+			var value interface{} = message
+			kvs = append(kvs, azuredevops.KeyValuePair{Key: &key, Value: &value})
+		}
+		return &kvs
+	}
+	granted := []userentitlement.ProjectEntitlement{projectEntitlement(websiteID)}
+	success, failure := true, false
+
+	t.Run("each project the user did not get is reported with its own errors", func(t *testing.T) {
+		requested := []userentitlement.ProjectEntitlement{projectEntitlement(websiteID), projectEntitlement(mobileID), projectEntitlement(apiID)}
+		resp := &userentitlement.UserEntitlementsPostResponse{
+			UserEntitlement: &userentitlement.UserEntitlement{ProjectEntitlements: &granted},
+			OperationResult: &userentitlement.UserEntitlementOperationResult{
+				IsSuccess: &failure,
+				Errors: operationErrors(
+					"Project "+strings.ToUpper(mobileID.String())+" was not found.",
+					"Access denied to project "+apiID.String()+".",
+					"The operation was throttled.",
+				),
+			},
+		}
+
+		failed, unattributedErrors := failedProjectEntitlements(requested, resp)
+		require.Len(t, failed, 2)
+		assert.Equal(t, mobileID, *failed[0].ProjectRef.Id)
+		assert.Equal(t, []string{"Project " + strings.ToUpper(mobileID.String()) + " was not found."}, failed[0].errors)
+		assert.Equal(t, apiID, *failed[1].ProjectRef.Id)
+		assert.Equal(t, []string{"Access denied to project " + apiID.String() + "."}, failed[1].errors)
+		assert.Equal(t, []string{"The operation was throttled."}, unattributedErrors)
+
+		message := failedProjectEntitlementsMessage(failed, unattributedErrors)
+		assert.Contains(t, message, mobileID.String()+" (projectContributor): Project "+strings.ToUpper(mobileID.String())+" was not found.")
+		assert.Contains(t, message, apiID.String()+" (projectContributor): Access denied to project "+apiID.String()+".")
+		assert.Contains(t, message, "Errors: The operation was throttled.")
+	})
+
+	t.Run("a single failed project gets every error", func(t *testing.T) {
+		requested := []userentitlement.ProjectEntitlement{projectEntitlement(websiteID), projectEntitlement(mobileID)}
+		resp := &userentitlement.UserEntitlementsPostResponse{
+			UserEntitlement: &userentitlement.UserEntitlement{ProjectEntitlements: &granted},
+			OperationResult: &userentitlement.UserEntitlementOperationResult{
+				IsSuccess: &failure,
+				Errors:    operationErrors("The operation was throttled."),
+			},
+		}
+
+		failed, unattributedErrors := failedProjectEntitlements(requested, resp)
+		require.Len(t, failed, 1)
+		assert.Equal(t, mobileID, *failed[0].ProjectRef.Id)
+		assert.Equal(t, []string{"The operation was throttled."}, failed[0].errors)
+		assert.Empty(t, unattributedErrors)
+	})
+
+	t.Run("nothing is reported when the operation succeeded", func(t *testing.T) {
+		requested := []userentitlement.ProjectEntitlement{projectEntitlement(websiteID), projectEntitlement(mobileID)}
+		resp := &userentitlement.UserEntitlementsPostResponse{
+			UserEntitlement: &userentitlement.UserEntitlement{},
+			OperationResult: &userentitlement.UserEntitlementOperationResult{IsSuccess: &success},
+		}
+
+		failed, unattributedErrors := failedProjectEntitlements(requested, resp)
+		assert.Empty(t, failed)
+		assert.Empty(t, unattributedErrors)
+	})
+}
+
+func TestParseIntoServicePrincipalResource(t *testing.T) {
+	descriptor := "aadsp.ZjIxN2FjM2ItNzA4YS00ZGI1LWI2YzMtODA0MDhhZmVkOWM3"
+	displayName := "deploy-pipeline"
+	applicationID := "f217ac3b-708a-4db5-b6c3-80408afed9c7"
+	servicePrincipal := &graph.GraphServicePrincipal{
+		Descriptor:    &descriptor,
+		DisplayName:   &displayName,
+		ApplicationId: &applicationID,
+	}
+
+	servicePrincipalResource, err := parseIntoServicePrincipalResource(servicePrincipal)
+	require.NoError(t, err)
+	assert.Equal(t, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: descriptor}, servicePrincipalResource.Id)
+	assert.Equal(t, displayName, servicePrincipalResource.DisplayName)
+
+	userTrait, err := resource.GetUserTrait(servicePrincipalResource)
+	require.NoError(t, err)
+	assert.Equal(t, v2.UserTrait_ACCOUNT_TYPE_SERVICE, userTrait.AccountType)
+	assert.Equal(t, applicationID, userTrait.Profile.AsMap()["application_id"])
+
+	t.Run("group membership grants refer to the listed service principal", func(t *testing.T) {
+		id := uuid.New()
+		isContainer := false
+		member := &identity.Identity{Id: &id, IsContainer: &isContainer, SubjectDescriptor: &descriptor}
+		groupResource := &v2.Resource{Id: &v2.ResourceId{ResourceType: groupResourceType.Id, Resource: uuid.NewString()}}
+
+		membership := grant.NewGrant(groupResource, memberPermission, getMemberResourceID(member, nil))
+		assert.Equal(t, servicePrincipalResource.Id, membership.Principal.Id)
+	})
+
+	t.Run("service principals are not deleted, disabled or enabled as users", func(t *testing.T) {
+		ctx := context.Background()
+		builder := &userBuilder{}
+
+		_, err := builder.Delete(ctx, servicePrincipalResource.Id)
+		require.ErrorContains(t, err, "is a service principal")
+		err = builder.disableUser(ctx, descriptor)
+		require.ErrorContains(t, err, "is a service principal")
+		err = builder.enableUser(ctx, descriptor, "express")
+		require.ErrorContains(t, err, "is a service principal")
+	})
+}
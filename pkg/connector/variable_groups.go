@@ -0,0 +1,132 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-azure-devops/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/microsoft/azure-devops-go-api/azuredevops/v7/taskagent"
+)
+
+var libraryRoles = []string{administratorRole, userRole, readerRole}
+
+type variableGroupBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.AzureDevOpsClient
+	organization *organization
+}
+
+func (o *variableGroupBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return variableGroupResourceType
+}
+
+func (o *variableGroupBuilder) List(ctx context.Context, parent *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var resources []*v2.Resource
+
+	if parent == nil {
+		return resources, "", nil, nil
+	}
+
+	variableGroups, err := o.client.ListVariableGroups(ctx, parent.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, variableGroup := range variableGroups {
+		profile, err := getPipelinePermissionsProfile(ctx, o.client, parent.Resource, "variablegroup", strconv.Itoa(*variableGroup.Id))
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		variableGroupCopy := &variableGroup
+		variableGroupResource, err := parseIntoVariableGroupResource(parent.Resource, variableGroupCopy, profile)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		resources = append(resources, variableGroupResource)
+	}
+
+	return resources, "", nil, nil
+}
+
+func (o *variableGroupBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	entitlements := getRoleEntitlements(resource, libraryRoles)
+	entitlements = append(entitlements, getPipelineAuthorizedEntitlement(resource))
+
+	return entitlements, "", nil, nil
+}
+
+func (o *variableGroupBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	grants, err := getRoleGrants(ctx, o.organization, variableGroupRoleScope, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	// Resources are identified by projectId$id, the id of the resource in its security role scope.
+	projectID, resourceID, _ := strings.Cut(resource.Id.Resource, "$")
+	pipelineGrants, err := getPipelineAuthorizedGrants(ctx, o.client, projectID, "variablegroup", resourceID, resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	grants = append(grants, pipelineGrants...)
+
+	return grants, "", nil, nil
+}
+
+func (o *variableGroupBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlementResource *v2.Entitlement) (annotations.Annotations, error) {
+	if entitlementResource.Slug == pipelineAuthorizedPermission {
+		return grantPipelineAuthorization(ctx, o.client, "variablegroup", principal, entitlementResource)
+	}
+
+	return grantRole(ctx, o.client, variableGroupRoleScope, libraryRoles, principal, entitlementResource)
+}
+
+func (o *variableGroupBuilder) Revoke(ctx context.Context, grantResource *v2.Grant) (annotations.Annotations, error) {
+	if grantResource.Entitlement.Slug == pipelineAuthorizedPermission {
+		return revokePipelineAuthorization(ctx, o.client, "variablegroup", grantResource)
+	}
+
+	return revokeRole(ctx, o.client, variableGroupRoleScope, libraryRoles, grantResource)
+}
+
+// parseIntoVariableGroupResource builds a variable group resource identified by projectId$variableGroupId, the id of
+// the variable group in its security role scope.
+func parseIntoVariableGroupResource(projectID string, variableGroup *taskagent.VariableGroup, profile map[string]interface{}) (*v2.Resource, error) {
+	options := []resource.ResourceOption{
+		resource.WithAppTrait(resource.WithAppProfile(profile)),
+		resource.WithParentResourceID(
+			&v2.ResourceId{
+				ResourceType: projectResourceType.Id,
+				Resource:     projectID,
+			}),
+	}
+	if variableGroup.Description != nil && *variableGroup.Description != "" {
+		options = append(options, resource.WithDescription(*variableGroup.Description))
+	}
+
+	variableGroupResource, err := resource.NewResource(
+		*variableGroup.Name,
+		variableGroupResourceType,
+		fmt.Sprintf("%s$%d", projectID, *variableGroup.Id),
+		options...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return variableGroupResource, nil
+}
+
+func newVariableGroupBuilder(c *client.AzureDevOpsClient, org *organization) *variableGroupBuilder {
+	return &variableGroupBuilder{
+		resourceType: variableGroupResourceType,
+		client:       c,
+		organization: org,
+	}
+}